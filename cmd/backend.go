@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/mantton/anthe/internal/ast"
+	"github.com/mantton/anthe/internal/evaluator"
+	"github.com/mantton/anthe/internal/module"
+	"github.com/mantton/anthe/internal/object"
+	"github.com/mantton/anthe/internal/vm"
+)
+
+/*
+Runs programs for the repl and `anthe run`, the evaluator unless --backend=vm is passed.
+`anthe script.an` compiles the script to llvm ir, unless a backend is passed to run it with.
+*/
+type backend interface {
+	RunProgram(program *ast.Program) (object.Object, error)
+	RunMain(modules []*module.Module) (object.Object, error)
+	SetMaxCallDepth(max int)
+}
+
+// set by --backend and --max-depth
+type options struct {
+	backend  string
+	explicit bool // whether --backend was passed
+	maxDepth int
+}
+
+func newBackend(opts options) (backend, error) {
+	var b backend
+
+	switch opts.backend {
+	case "evaluator":
+		b = evaluator.New()
+	case "vm":
+		b = vm.New()
+	default:
+		return nil, fmt.Errorf("unknown backend `%s`, expected evaluator or vm", opts.backend)
+	}
+
+	b.SetMaxCallDepth(opts.maxDepth)
+	return b, nil
+}
+
+// removes the flags from args, returning the options they set
+func parseFlags(args []string) (options, []string, error) {
+	opts := options{backend: "evaluator", maxDepth: object.MAX_CALL_DEPTH}
+	rest := make([]string, 0, len(args))
+
+	for _, arg := range args {
+		if value, ok := strings.CutPrefix(arg, "--backend="); ok {
+			opts.backend, opts.explicit = value, true
+			continue
+		}
+
+		if value, ok := strings.CutPrefix(arg, "--max-depth="); ok {
+			depth, err := strconv.Atoi(value)
+
+			if err != nil || depth < 1 {
+				return opts, nil, fmt.Errorf("--max-depth expects a positive number, got `%s`", value)
+			}
+
+			opts.maxDepth = depth
+			continue
+		}
+
+		rest = append(rest, arg)
+	}
+
+	return opts, rest, nil
+}
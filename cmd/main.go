@@ -5,49 +5,112 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
+	"strings"
 
+	"github.com/mantton/anthe/internal/bytecode"
 	"github.com/mantton/anthe/internal/compiler"
-	"github.com/mantton/anthe/internal/evaluator"
 	"github.com/mantton/anthe/internal/lexer"
+	"github.com/mantton/anthe/internal/module"
+	"github.com/mantton/anthe/internal/object"
 	"github.com/mantton/anthe/internal/parser"
+	"github.com/mantton/anthe/internal/typing"
 )
 
 const PROMPT = ">> "
 
 func main() {
 
-	allArgs := os.Args[1:]
+	opts, allArgs, err := parseFlags(os.Args[1:])
+
+	if err != nil {
+		fmt.Println(err.Error())
+		os.Exit(64)
+	}
+
 	argCount := len(allArgs)
 
-	e := evaluator.New()
+	e, err := newBackend(opts)
+
+	if err != nil {
+		fmt.Println(err.Error())
+		os.Exit(64)
+	}
+
+	checker := typing.New()
+	types := parser.NewTypeScope(nil) // type names declared on earlier REPL lines
+
+	// anthe build [dir], anthe run [dir]
+	if argCount >= 1 && argCount <= 2 && (allArgs[0] == "build" || allArgs[0] == "run") {
+		dir := "."
+		if argCount == 2 {
+			dir = allArgs[1]
+		}
+
+		if err := runProject(allArgs[0], dir, opts); err != nil {
+			fmt.Println(err.Error())
+			os.Exit(1)
+		}
+		return
+	}
+
+	// anthe compile script.an, anthe script.anbc
+	if argCount == 2 && allArgs[0] == "compile" {
+		if err := compileScript(allArgs[1]); err != nil {
+			fmt.Println(err.Error())
+			os.Exit(1)
+		}
+		return
+	}
+
+	if argCount == 1 && strings.HasSuffix(allArgs[0], bytecode.FILE_EXTENSION) {
+		if err := runPrecompiled(allArgs[0], opts.maxDepth); err != nil {
+			fmt.Println(err.Error())
+			os.Exit(1)
+		}
+		return
+	}
 
 	if argCount > 1 { // not enough args provided
-		fmt.Println("Usage: anthe [script]")
+		fmt.Println("Usage: anthe [--backend=evaluator|vm] [--max-depth=n] [script] | anthe compile [script] | anthe build [dir] | anthe run [dir]")
 		os.Exit(64)
 	} else if argCount == 1 {
 
 		path := allArgs[0]
 
-		data, err := os.ReadFile(path)
+		// imports not relative to the importing file are looked up next to the entry file, then in ANTHE_PATH
+		searchPath := filepath.SplitList(os.Getenv("ANTHE_PATH"))
+		modules, err := module.NewResolver(searchPath).Load(path)
 
 		if err != nil {
 			fmt.Println(err.Error())
 			return
 		}
-		l := lexer.New(string(data), "repl.an")
-		p := parser.New(l)
 
-		prog := p.ParseProgram()
+		if ok, errs := checker.CheckModules(modules); !ok {
+			fmt.Println("Type Checker : Errors")
+			for _, msg := range errs {
+				fmt.Println(msg)
+			}
+			return
+		}
 
-		c := compiler.New()
-		result, err := c.Compile(prog)
+		if opts.explicit {
+			if err := runScript(e, modules); err != nil {
+				fmt.Println(err.Error())
+				os.Exit(1)
+			}
+			return
+		}
+
+		result, err := compiler.CompileModules(modules, checker, opts.maxDepth)
 		if err != nil {
 			fmt.Println(err.Error())
 			return
 
 		}
 
-		fmt.Println("\n\n\n")
+		fmt.Print("\n\n\n\n")
 		fmt.Println(result)
 		fmt.Println("done")
 
@@ -80,6 +143,7 @@ func main() {
 
 			l := lexer.New(line, "repl.an")
 			p := parser.New(l)
+			p.UseTypeScope(types)
 
 			prog := p.ParseProgram()
 
@@ -95,22 +159,22 @@ func main() {
 				continue
 			}
 
-			// checker := typing.New(prog.Statements)
-
-			// ok, t_err := checker.CheckAll()
-
-			// if !ok {
-			// 	fmt.Println("Type Checker : Errors")
-			// 	for _, msg := range t_err {
-			// 		fmt.Println(msg)
-			// 	}
-			// }
+			if ok, errs := checker.CheckProgram(prog); !ok {
+				fmt.Println("Type Checker : Errors")
+				for _, msg := range errs {
+					fmt.Println(msg)
+				}
+				continue
+			}
 
 			result, err := e.RunProgram(prog)
 
 			if err != nil {
-				fmt.Println(err.Error())
-
+				if rErr, ok := err.(*object.Error); ok {
+					fmt.Println(rErr.Traceback())
+				} else {
+					fmt.Println(err.Error())
+				}
 			}
 
 			if result != nil && result.Type() != "void" {
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mantton/anthe/internal/bytecode"
+	"github.com/mantton/anthe/internal/module"
+	"github.com/mantton/anthe/internal/object"
+	"github.com/mantton/anthe/internal/typing"
+	"github.com/mantton/anthe/internal/vm"
+)
+
+// checks and compiles the script with its imports, writing the bytecode next to it as <script>.anbc
+func compileScript(path string) error {
+	searchPath := filepath.SplitList(os.Getenv("ANTHE_PATH"))
+	modules, err := module.NewResolver(searchPath).Load(path)
+
+	if err != nil {
+		return err
+	}
+
+	if ok, errs := typing.New().CheckModules(modules); !ok {
+		return errors.New("Type Checker : Errors\n" + strings.Join(errs, "\n"))
+	}
+
+	compiled, err := bytecode.CompileModules(modules)
+
+	if err != nil {
+		return err
+	}
+
+	data, err := bytecode.Encode(compiled)
+
+	if err != nil {
+		return err
+	}
+
+	out := strings.TrimSuffix(path, module.EXTENSION) + bytecode.FILE_EXTENSION
+
+	if err := os.WriteFile(out, data, 0o644); err != nil {
+		return err
+	}
+
+	fmt.Println("compiled " + out)
+	return nil
+}
+
+// runs a precompiled script on the vm, without parsing or checking it again
+func runPrecompiled(path string, maxDepth int) error {
+	data, err := os.ReadFile(path)
+
+	if err != nil {
+		return err
+	}
+
+	modules, err := bytecode.Decode(data)
+
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	machine := vm.New()
+	machine.SetMaxCallDepth(maxDepth)
+
+	result, err := machine.RunCompiled(modules)
+
+	if err != nil {
+		if rErr, ok := err.(*object.Error); ok {
+			return errors.New(rErr.Traceback())
+		}
+		return err
+	}
+
+	if result != nil && result.Type() != object.VOID {
+		fmt.Println(result.Inspect())
+	}
+
+	return nil
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mantton/anthe/internal/compiler"
+	"github.com/mantton/anthe/internal/module"
+	"github.com/mantton/anthe/internal/object"
+	"github.com/mantton/anthe/internal/project"
+	"github.com/mantton/anthe/internal/typing"
+)
+
+/*
+Builds or runs the project whose anthe.toml is in dir.
+build writes the linked llvm ir to build/<name>.ll, run evaluates the project with the backend of opts and calls main.
+*/
+func runProject(command, dir string, opts options) error {
+	p, err := project.Load(dir)
+
+	if err != nil {
+		return err
+	}
+
+	modules, err := p.Modules()
+
+	if err != nil {
+		return err
+	}
+
+	checker := typing.New()
+
+	if ok, errs := checker.CheckModules(modules); !ok {
+		return errors.New("Type Checker : Errors\n" + strings.Join(errs, "\n"))
+	}
+
+	switch command {
+	case "build":
+		ir, err := compiler.CompileModules(modules, checker, opts.maxDepth)
+
+		if err != nil {
+			return err
+		}
+
+		out := filepath.Join(p.Root, "build", p.Manifest.Name+".ll")
+
+		if err := os.MkdirAll(filepath.Dir(out), 0o755); err != nil {
+			return err
+		}
+
+		if err := os.WriteFile(out, []byte(ir), 0o644); err != nil {
+			return err
+		}
+
+		fmt.Println("built " + out)
+	case "run":
+		e, err := newBackend(opts)
+
+		if err != nil {
+			return err
+		}
+
+		return runScript(e, modules)
+	}
+
+	return nil
+}
+
+// runs the modules and main with e, printing its result
+func runScript(e backend, modules []*module.Module) error {
+	result, err := e.RunMain(modules)
+
+	if err != nil {
+		if rErr, ok := err.(*object.Error); ok {
+			return errors.New(rErr.Traceback())
+		}
+		return err
+	}
+
+	if result != nil && result.Type() != object.VOID {
+		fmt.Println(result.Inspect())
+	}
+
+	return nil
+}
@@ -1,7 +1,10 @@
 package ast
 
+import "github.com/mantton/anthe/internal/token"
+
 type Node interface {
 	TokenLiteral() string
+	Pos() token.Position
 }
 
 type Program struct {
@@ -16,3 +19,10 @@ func (p *Program) TokenLiteral() string {
 		return ""
 	}
 }
+
+func (p *Program) Pos() token.Position {
+	if len(p.Statements) > 0 {
+		return p.Statements[0].Pos()
+	}
+	return token.Position{}
+}
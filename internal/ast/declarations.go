@@ -1,6 +1,41 @@
 package ast
 
+import "github.com/mantton/anthe/internal/token"
+
 type Declaration interface {
 	Node
 	declarationNode()
 }
+
+type StructField struct {
+	Name string
+	Type TypeExpression
+}
+
+// struct Box<T> { value: T }
+type StructDeclaration struct {
+	Token          token.Token
+	Name           string
+	TypeParameters []string
+	Fields         []StructField
+}
+
+// conform
+func (s *StructDeclaration) statementNode()       {}
+func (s *StructDeclaration) declarationNode()     {}
+func (s *StructDeclaration) TokenLiteral() string { return "struct " + s.Name }
+func (s *StructDeclaration) Pos() token.Position  { return s.Token.Pos }
+
+// type UserID = int, or with Distinct, type Meters distinct float
+type TypeDeclaration struct {
+	Token    token.Token
+	Name     string
+	Value    TypeExpression
+	Distinct bool // a new type not interchangeable with Value
+}
+
+// conform
+func (s *TypeDeclaration) statementNode()       {}
+func (s *TypeDeclaration) declarationNode()     {}
+func (s *TypeDeclaration) TokenLiteral() string { return "type " + s.Name }
+func (s *TypeDeclaration) Pos() token.Position  { return s.Token.Pos }
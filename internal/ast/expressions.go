@@ -8,8 +8,16 @@ type Expression interface {
 }
 
 type IdentifierExpression struct {
-	Token token.Token
-	Value string
+	Token    token.Token
+	Value    string
+	Location Location // set by the resolver
+}
+
+// where the resolver placed a binding, names that are not Local are globals or builtins looked up by name
+type Location struct {
+	Local bool
+	Depth int // frames between the use and the frame declaring the binding
+	Slot  int
 }
 
 type PrefixExpression struct {
@@ -36,12 +44,22 @@ type CallExpression struct {
 	Token     token.Token
 	Function  Expression
 	Arguments []Expression
+	Tail      bool // the result of the call is returned as is by the enclosing function, set by MarkTailCalls
 }
 
 type IndexExpression struct {
-	Token token.Token
-	Left  Expression
-	Index Expression
+	Token    token.Token
+	Left     Expression
+	Index    Expression
+	Optional bool // `?[`, evaluates to null when Left is null
+}
+
+// a.b
+type MemberExpression struct {
+	Token    token.Token
+	Object   Expression
+	Property *IdentifierExpression
+	Optional bool // `?.`, evaluates to null when Object is null
 }
 
 type AssignmentExpression struct {
@@ -50,24 +68,56 @@ type AssignmentExpression struct {
 	Value  Expression
 }
 
+// assignment to an element or member of a container, e.g arr[0] = 1, p.name = "a"
+type ElementAssignmentExpression struct {
+	Token  token.Token
+	Target Expression // *IndexExpression or *MemberExpression
+	Value  Expression
+}
+
+// postfix `?`, unwraps an ok result or returns the err result from the enclosing function
+type PropagateExpression struct {
+	Token token.Token
+	Value Expression
+}
+
 // conform
 func (pe *PrefixExpression) expressionNode()      {}
 func (pe *PrefixExpression) TokenLiteral() string { return "Prefix " + pe.Token.Literal }
+func (pe *PrefixExpression) Pos() token.Position  { return pe.Token.Pos }
 
 func (i *IdentifierExpression) expressionNode()      {}
 func (i *IdentifierExpression) TokenLiteral() string { return "Ident " + i.Token.Literal }
+func (i *IdentifierExpression) Pos() token.Position  { return i.Token.Pos }
 
 func (i *InfixExpression) expressionNode()      {}
 func (i *InfixExpression) TokenLiteral() string { return "Infix " + i.Token.Literal }
+func (i *InfixExpression) Pos() token.Position  { return i.Token.Pos }
 
 func (i *IfExpression) expressionNode()      {}
 func (i *IfExpression) TokenLiteral() string { return "If " + i.Token.Literal }
+func (i *IfExpression) Pos() token.Position  { return i.Token.Pos }
 
 func (i *CallExpression) expressionNode()      {}
 func (i *CallExpression) TokenLiteral() string { return "Call " + i.Token.Literal }
+func (i *CallExpression) Pos() token.Position  { return i.Token.Pos }
 
 func (i *IndexExpression) expressionNode()      {}
 func (i *IndexExpression) TokenLiteral() string { return "Idx " + i.Token.Literal }
+func (i *IndexExpression) Pos() token.Position  { return i.Token.Pos }
 
 func (i *AssignmentExpression) expressionNode()      {}
 func (i *AssignmentExpression) TokenLiteral() string { return "assign " + i.Token.Literal }
+func (i *AssignmentExpression) Pos() token.Position  { return i.Token.Pos }
+
+func (i *ElementAssignmentExpression) expressionNode()      {}
+func (i *ElementAssignmentExpression) TokenLiteral() string { return "assign " + i.Token.Literal }
+func (i *ElementAssignmentExpression) Pos() token.Position  { return i.Token.Pos }
+
+func (i *PropagateExpression) expressionNode()      {}
+func (i *PropagateExpression) TokenLiteral() string { return "propagate " + i.Token.Literal }
+func (i *PropagateExpression) Pos() token.Position  { return i.Token.Pos }
+
+func (i *MemberExpression) expressionNode()      {}
+func (i *MemberExpression) TokenLiteral() string { return "member " + i.Token.Literal }
+func (i *MemberExpression) Pos() token.Position  { return i.Token.Pos }
@@ -25,9 +25,12 @@ type BooleanLiteral struct {
 }
 
 type FunctionLiteral struct {
-	Token      token.Token
-	Parameters []*IdentifierExpression
-	Body       *BlockStatement
+	Token          token.Token
+	Parameters     []*IdentifierExpression
+	ParameterTypes []TypeExpression // annotation of each parameter, nil when not annotated
+	ReturnType     TypeExpression   // nil when not annotated
+	Body           *BlockStatement
+	FrameSize      int // slots of the call frame, set by the resolver
 }
 
 type ArrayLiteral struct {
@@ -35,9 +38,27 @@ type ArrayLiteral struct {
 	Elements []Expression
 }
 
+// a single `key: value` entry of a hash literal
+type HashLiteralPair struct {
+	Key   Expression
+	Value Expression
+}
+
 type HashLiteral struct {
 	Token token.Token
-	Pairs map[Expression]Expression
+	Pairs []HashLiteralPair // in source order
+}
+
+// `{1, 2, 3}`, a brace literal without keys
+type SetLiteral struct {
+	Token    token.Token
+	Elements []Expression
+}
+
+// `(1, "a")`, a parenthesized list with at least one comma
+type TupleLiteral struct {
+	Token    token.Token
+	Elements []Expression
 }
 
 type StringLiteral struct {
@@ -53,31 +74,49 @@ type NullLiteral struct {
 func (il *IntegerLiteral) expressionNode()      {}
 func (n *IntegerLiteral) literalNode()          {}
 func (il *IntegerLiteral) TokenLiteral() string { return "IntLit " + il.Token.Literal }
+func (il *IntegerLiteral) Pos() token.Position  { return il.Token.Pos }
 
 func (b *BooleanLiteral) expressionNode()      {}
 func (n *BooleanLiteral) literalNode()         {}
 func (b *BooleanLiteral) TokenLiteral() string { return "BoolLit " + b.Token.Literal }
+func (b *BooleanLiteral) Pos() token.Position  { return b.Token.Pos }
 
 func (b *FunctionLiteral) expressionNode()      {}
 func (n *FunctionLiteral) literalNode()         {}
 func (b *FunctionLiteral) TokenLiteral() string { return "FuncLit " + b.Token.Literal }
+func (b *FunctionLiteral) Pos() token.Position  { return b.Token.Pos }
 
 func (b *ArrayLiteral) expressionNode()      {}
 func (n *ArrayLiteral) literalNode()         {}
 func (b *ArrayLiteral) TokenLiteral() string { return "ArrLit " + b.Token.Literal }
+func (b *ArrayLiteral) Pos() token.Position  { return b.Token.Pos }
 
 func (b *HashLiteral) expressionNode()      {}
 func (n *HashLiteral) literalNode()         {}
 func (b *HashLiteral) TokenLiteral() string { return "HashLit " + b.Token.Literal }
+func (b *HashLiteral) Pos() token.Position  { return b.Token.Pos }
+
+func (b *SetLiteral) expressionNode()      {}
+func (n *SetLiteral) literalNode()         {}
+func (b *SetLiteral) TokenLiteral() string { return "SetLit " + b.Token.Literal }
+func (b *SetLiteral) Pos() token.Position  { return b.Token.Pos }
+
+func (b *TupleLiteral) expressionNode()      {}
+func (n *TupleLiteral) literalNode()         {}
+func (b *TupleLiteral) TokenLiteral() string { return "TupleLit " + b.Token.Literal }
+func (b *TupleLiteral) Pos() token.Position  { return b.Token.Pos }
 
 func (b *StringLiteral) expressionNode()      {}
 func (n *StringLiteral) literalNode()         {}
 func (b *StringLiteral) TokenLiteral() string { return "StringLit " + b.Token.Literal }
+func (b *StringLiteral) Pos() token.Position  { return b.Token.Pos }
 
 func (b *FloatLiteral) expressionNode()      {}
 func (n *FloatLiteral) literalNode()         {}
 func (b *FloatLiteral) TokenLiteral() string { return "Float Lit " + b.Token.Literal }
+func (b *FloatLiteral) Pos() token.Position  { return b.Token.Pos }
 
 func (b *NullLiteral) expressionNode()      {}
 func (n *NullLiteral) literalNode()         {}
 func (b *NullLiteral) TokenLiteral() string { return "null Lit " + b.Token.Literal }
+func (b *NullLiteral) Pos() token.Position  { return b.Token.Pos }
@@ -0,0 +1,54 @@
+package ast
+
+import "github.com/mantton/anthe/internal/token"
+
+// import "lib/geometry" binds the module to `geometry`, import { area } from "./shapes" binds its exports
+type ImportStatement struct {
+	Token    token.Token
+	Path     string                  // as written in the source
+	Name     string                  // the module's binding, the last element of Path
+	Names    []*IdentifierExpression // nil when the whole module is imported
+	Resolved string                  // the file Path refers to, set by the module resolver
+}
+
+// export func area(...) {...}
+type ExportStatement struct {
+	Token     token.Token
+	Statement Statement
+}
+
+// the names the exported declaration binds
+func (s *ExportStatement) Names() []string {
+	switch stmt := s.Statement.(type) {
+	case *LetStatement:
+		return []string{stmt.Name.Value}
+	case *ConstStatement:
+		return []string{stmt.Name.Value}
+	case *DestructuringStatement:
+		names := []string{}
+		for _, name := range stmt.Names {
+			names = append(names, name.Value)
+		}
+		if stmt.Rest != nil {
+			names = append(names, stmt.Rest.Value)
+		}
+		return names
+	case *NamedFunctionDeclaration:
+		return []string{stmt.Name}
+	case *StructDeclaration:
+		return []string{stmt.Name}
+	case *TypeDeclaration:
+		return []string{stmt.Name}
+	}
+
+	return nil
+}
+
+// conform
+func (s *ImportStatement) statementNode()       {}
+func (s *ImportStatement) TokenLiteral() string { return "import " + s.Path }
+func (s *ImportStatement) Pos() token.Position  { return s.Token.Pos }
+
+func (s *ExportStatement) statementNode()       {}
+func (s *ExportStatement) TokenLiteral() string { return "export " + s.Statement.TokenLiteral() }
+func (s *ExportStatement) Pos() token.Position  { return s.Token.Pos }
@@ -25,6 +25,24 @@ type ConstStatement struct {
 	Type  TypeExpression
 }
 
+type PatternKind int
+
+const (
+	TuplePattern  PatternKind = iota // let (a, b) = t
+	ArrayPattern                     // let [x, y, ...rest] = arr
+	ObjectPattern                    // let {name, age} = person
+)
+
+// let or const binding several names from the parts of a value
+type DestructuringStatement struct {
+	Token    token.Token // The token.LET or token.CONST token
+	Kind     PatternKind
+	Names    []*IdentifierExpression
+	Rest     *IdentifierExpression // array patterns only, nil without `...rest`
+	Value    Expression
+	Constant bool
+}
+
 // RETURN
 type ReturnStatement struct {
 	Token       token.Token
@@ -44,27 +62,63 @@ type BlockStatement struct {
 
 type NamedFunctionDeclaration struct {
 	token.Token
-	Name string
-	Fn   *FunctionLiteral
+	Name           string
+	TypeParameters []string // func first<T>(...)
+	Fn             *FunctionLiteral
+	Location       Location // set by the resolver
+}
+
+// THROW
+type ThrowStatement struct {
+	Token token.Token
+	Value Expression
+}
+
+// TRY
+type TryStatement struct {
+	Token          token.Token
+	Body           *BlockStatement
+	CatchParam     *IdentifierExpression // nil when the catch clause does not bind the error
+	Catch          *BlockStatement       // nil when there is no catch clause
+	Finally        *BlockStatement       // nil when there is no finally clause
+	CatchFrameSize int                   // slots of the catch clause's frame, set by the resolver
 }
 
 // conform
 func (s *LetStatement) statementNode()       {}
 func (s *LetStatement) TokenLiteral() string { return "Let " + s.Token.Literal }
+func (s *LetStatement) Pos() token.Position  { return s.Token.Pos }
+
+func (s *DestructuringStatement) statementNode()       {}
+func (s *DestructuringStatement) TokenLiteral() string { return "Destructure " + s.Token.Literal }
+func (s *DestructuringStatement) Pos() token.Position  { return s.Token.Pos }
 
 func (s *ReturnStatement) statementNode()       {}
 func (s *ReturnStatement) TokenLiteral() string { return "Return  " + s.Token.Literal }
+func (s *ReturnStatement) Pos() token.Position  { return s.Token.Pos }
 
 func (s *ExpressionStatement) statementNode() {}
 func (s *ExpressionStatement) TokenLiteral() string {
 	return "Expression " + s.Token.Literal + s.Expression.TokenLiteral()
 }
+func (s *ExpressionStatement) Pos() token.Position { return s.Token.Pos }
 
 func (s *BlockStatement) statementNode()       {}
 func (s *BlockStatement) TokenLiteral() string { return s.Token.Literal }
+func (s *BlockStatement) Pos() token.Position  { return s.Token.Pos }
 
 func (s *NamedFunctionDeclaration) statementNode()       {}
 func (s *NamedFunctionDeclaration) TokenLiteral() string { return s.Token.Literal + s.Name }
+func (s *NamedFunctionDeclaration) Pos() token.Position  { return s.Token.Pos }
 
 func (s *ConstStatement) statementNode()       {}
 func (s *ConstStatement) TokenLiteral() string { return "const_" + s.Token.Literal }
+func (s *ConstStatement) Pos() token.Position  { return s.Token.Pos }
+
+func (s *ThrowStatement) statementNode()       {}
+func (s *ThrowStatement) TokenLiteral() string { return "throw " + s.Token.Literal }
+func (s *ThrowStatement) Pos() token.Position  { return s.Token.Pos }
+
+func (s *TryStatement) statementNode()       {}
+func (s *TryStatement) TokenLiteral() string { return "try " + s.Token.Literal }
+func (s *TryStatement) Pos() token.Position  { return s.Token.Pos }
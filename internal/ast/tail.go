@@ -0,0 +1,48 @@
+package ast
+
+/*
+Marks the calls in tail position of a function body, those whose result the function returns as is.
+The values of return statements and the last expression of the body are in tail position, as are
+the last expressions of the branches of an if in tail position. Calls within try statements are not,
+their result is still needed to run catch and finally.
+*/
+func MarkTailCalls(body *BlockStatement) {
+	markTailBlock(body, true)
+}
+
+func markTailBlock(block *BlockStatement, tail bool) {
+	if block == nil {
+		return
+	}
+
+	for i, stmt := range block.Statements {
+		markTailStatement(stmt, tail && i == len(block.Statements)-1)
+	}
+}
+
+// returns are found in the branches of ifs anywhere in the body, other expressions only at its end
+func markTailStatement(stmt Statement, tail bool) {
+	switch stmt := stmt.(type) {
+	case *ReturnStatement:
+		markTailExpression(stmt.ReturnValue)
+	case *BlockStatement:
+		markTailBlock(stmt, tail)
+	case *ExpressionStatement:
+		if tail {
+			markTailExpression(stmt.Expression)
+		} else if ie, ok := stmt.Expression.(*IfExpression); ok {
+			markTailBlock(ie.Action, false)
+			markTailBlock(ie.Alternative, false)
+		}
+	}
+}
+
+func markTailExpression(expr Expression) {
+	switch expr := expr.(type) {
+	case *CallExpression:
+		expr.Tail = true
+	case *IfExpression:
+		markTailBlock(expr.Action, true)
+		markTailBlock(expr.Alternative, true)
+	}
+}
@@ -1,6 +1,9 @@
 package ast
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
 
 type TypeExpression interface {
 	typeNode()
@@ -12,6 +15,7 @@ type LiteralIntegerType struct{}
 type LiteralStringType struct{}
 type LiteralFloatType struct{}
 type LiteralBooleanType struct{}
+type LiteralNullType struct{}
 
 func (t *LiteralIntegerType) typeNode() {}
 func (t *LiteralIntegerType) Type() string {
@@ -33,13 +37,64 @@ func (t *LiteralBooleanType) Type() string {
 	return "boolean"
 }
 
+func (t *LiteralNullType) typeNode() {}
+func (t *LiteralNullType) Type() string {
+	return "null"
+}
+
 // More Advanced
 type OptionalType struct {
 	Value TypeExpression
 }
 
+type ResultType struct {
+	Value TypeExpression
+	Error TypeExpression
+}
+
+// array<T>
+type ArrayType struct {
+	Element TypeExpression
+}
+
+// map<K, V>
+type MapType struct {
+	Key   TypeExpression
+	Value TypeExpression
+}
+
+// set<T>
+type SetType struct {
+	Element TypeExpression
+}
+
+// (int, string)
+type TupleType struct {
+	Elements []TypeExpression
+}
+
+// the type of a function value e.g (int, int) -> int
+type FunctionType struct {
+	Parameters []TypeExpression
+	Return     TypeExpression
+}
+
+// placeholder for a type still being inferred, numeric variables may only become int or float
+type TypeVariable struct {
+	ID      int
+	Numeric bool
+	Name    string // display name, assigned when the type is reported
+}
+
+// the type of a module bound by `import "path"`, its members are the module's exports
+type ModuleType struct {
+	Name string
+	Path string // resolved path of the module
+}
+
 type ScopeDefinedType struct {
 	Name   string
+	Module string // resolved path of the module declaring the struct or distinct type, empty outside modules
 	Values []TypeExpression
 }
 
@@ -48,6 +103,69 @@ func (t *OptionalType) Type() string {
 	return fmt.Sprintf("optional<%s>", t.Value.Type())
 }
 
+func (t *ResultType) typeNode() {}
+func (t *ResultType) Type() string {
+	return fmt.Sprintf("result<%s, %s>", t.Value.Type(), t.Error.Type())
+}
+
+func (t *ArrayType) typeNode() {}
+func (t *ArrayType) Type() string {
+	return fmt.Sprintf("array<%s>", t.Element.Type())
+}
+
+func (t *MapType) typeNode() {}
+func (t *MapType) Type() string {
+	return fmt.Sprintf("map<%s, %s>", t.Key.Type(), t.Value.Type())
+}
+
+func (t *SetType) typeNode() {}
+func (t *SetType) Type() string {
+	return fmt.Sprintf("set<%s>", t.Element.Type())
+}
+
+func (t *TupleType) typeNode() {}
+func (t *TupleType) Type() string {
+	elements := []string{}
+	for _, el := range t.Elements {
+		elements = append(elements, el.Type())
+	}
+
+	return "(" + strings.Join(elements, ", ") + ")"
+}
+
+func (t *FunctionType) typeNode() {}
+func (t *FunctionType) Type() string {
+	val := "("
+
+	for i, param := range t.Parameters {
+		val += param.Type()
+
+		if i != len(t.Parameters)-1 {
+			val += ", "
+		}
+	}
+
+	return val + ") -> " + t.Return.Type()
+}
+
+func (t *TypeVariable) typeNode() {}
+func (t *TypeVariable) Type() string {
+	if t.Name != "" {
+		return t.Name
+	}
+
+	if t.Numeric {
+		return fmt.Sprintf("N%d", t.ID)
+	}
+
+	return fmt.Sprintf("T%d", t.ID)
+}
+
+func (t *ModuleType) typeNode() {}
+func (t *ModuleType) Type() string {
+	return "module " + t.Name
+}
+
 func (t *ScopeDefinedType) typeNode() {}
 func (t *ScopeDefinedType) Type() string {
 	if t.Values == nil {
@@ -36,4 +36,116 @@ var BuiltInFunctions = map[string]*object.Builtin{
 			return VOID
 		},
 	},
+
+	"error": {
+		Name: "error",
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) == 0 {
+				return &object.Error{}
+			}
+
+			return &object.Error{Message: args[0].Inspect()}
+		},
+	},
+
+	"message": {
+		Name: "message",
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) == 0 {
+				return NULL
+			}
+
+			if err, ok := args[0].(*object.Error); ok {
+				return &object.String{Value: err.Message}
+			}
+
+			// values thrown as is read as they would have been wrapped in an error
+			return &object.String{Value: args[0].Inspect()}
+		},
+	},
+
+	"traceback": {
+		Name: "traceback",
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) == 0 {
+				return NULL
+			}
+
+			if err, ok := args[0].(*object.Error); ok {
+				return &object.String{Value: err.Traceback()}
+			}
+
+			return NULL
+		},
+	},
+
+	"ok": {
+		Name: "ok",
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) == 0 {
+				return &object.Result{Ok: true, Value: VOID}
+			}
+
+			return &object.Result{Ok: true, Value: args[0]}
+		},
+	},
+
+	"err": {
+		Name: "err",
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) == 0 {
+				return &object.Result{Ok: false, Value: VOID}
+			}
+
+			return &object.Result{Ok: false, Value: args[0]}
+		},
+	},
+
+	"freeze": {
+		Name: "freeze",
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) == 0 {
+				return VOID
+			}
+
+			return object.Freeze(args[0])
+		},
+	},
+
+	"union": {
+		Name: "union",
+		Fn: func(args ...object.Object) object.Object {
+			return setOperation(args, (*object.Set).Union)
+		},
+	},
+
+	"intersection": {
+		Name: "intersection",
+		Fn: func(args ...object.Object) object.Object {
+			return setOperation(args, (*object.Set).Intersection)
+		},
+	},
+
+	"difference": {
+		Name: "difference",
+		Fn: func(args ...object.Object) object.Object {
+			return setOperation(args, (*object.Set).Difference)
+		},
+	},
+}
+
+// applies op to two sets, null if the arguments are not two sets
+func setOperation(args []object.Object, op func(a, b *object.Set) *object.Set) object.Object {
+	if len(args) != 2 {
+		return NULL
+	}
+
+	a, ok := args[0].(*object.Set)
+	b, okB := args[1].(*object.Set)
+
+	if !ok || !okB {
+		return NULL
+	}
+
+	return op(a, b)
 }
@@ -0,0 +1,245 @@
+package bytecode
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+type Opcode byte
+
+type Instructions []byte
+
+const (
+	OpConstant Opcode = iota // push constants[a]
+	OpTrue
+	OpFalse
+	OpNull
+	OpVoid
+	OpPop
+	OpDup
+
+	// operators, on the two values on top of the stack
+	OpAdd
+	OpSub
+	OpMul
+	OpDiv
+	OpEqual
+	OpNotEqual
+	OpLess
+	OpGreater
+	OpLessEqual
+	OpGreaterEqual
+	OpNot
+	OpNegate
+
+	OpJump          // jump to a
+	OpJumpNotTruthy // pop the condition, jump to a when it is not truthy
+	OpJumpNull      // jump to a keeping the value when it is null
+	OpCoalesce      // jump to a keeping the value when it is not null, pop it otherwise
+
+	OpGetGlobal    // push globals[a]
+	OpDefineGlobal // pop into globals[a]
+	OpSetGlobal    // pop into globals[a], which must already be defined
+	OpGetLocal     // push slot b of the frame a frames up
+	OpSetLocal     // pop into slot b of the frame a frames up
+	OpGetBuiltin   // push the builtin named constants[a]
+
+	OpArray // build an array of the a values on top of the stack
+	OpTuple
+	OpHash // build a hash of the a key value pairs on top of the stack
+	OpSet
+
+	OpIndex     // container, index
+	OpSetIndex  // value, container, index
+	OpMember    // object, property named constants[a]
+	OpSetMember // value, object, property named constants[a]
+
+	OpCall        // call the function below the a arguments on top of the stack
+	OpTailCall    // call in tail position, a function replaces the frame of the caller
+	OpReturnValue // return the value on top of the stack
+	OpReturn      // return nothing, only ends the top level of a program
+	OpClosure     // push constants[a] closed over the current frame
+	OpPropagate   // unwrap an ok result or return an err one
+
+	OpThrow
+	OpTry        // handle errors raised until the matching OpEndTry, catch at a, finally at b
+	OpEndTry     // the body or catch clause completed, run the finally clause or jump to a
+	OpEndFinally // resume what the finally clause interrupted
+	OpEnterFrame // push a frame of a slots for a catch clause
+	OpLeaveFrame
+
+	OpFreeze
+	OpNotNull           // raise constants[a] when the value on top of the stack is null
+	OpDestructure       // pattern kind a, b names, c is 1 when the pattern takes the rest
+	OpDestructureMember // replace the value with its member named constants[a], null for missing hash keys
+
+	OpImport // push the module at path constants[a]
+	OpExport // pop the export named constants[a]
+)
+
+// no address, for try statements without a catch or finally clause
+const NoAddress = 0xFFFF
+
+type Definition struct {
+	Name          string
+	OperandWidths []int // in bytes
+}
+
+var definitions = map[Opcode]*Definition{
+	OpConstant: {"OpConstant", []int{2}},
+	OpTrue:     {"OpTrue", nil},
+	OpFalse:    {"OpFalse", nil},
+	OpNull:     {"OpNull", nil},
+	OpVoid:     {"OpVoid", nil},
+	OpPop:      {"OpPop", nil},
+	OpDup:      {"OpDup", nil},
+
+	OpAdd:          {"OpAdd", nil},
+	OpSub:          {"OpSub", nil},
+	OpMul:          {"OpMul", nil},
+	OpDiv:          {"OpDiv", nil},
+	OpEqual:        {"OpEqual", nil},
+	OpNotEqual:     {"OpNotEqual", nil},
+	OpLess:         {"OpLess", nil},
+	OpGreater:      {"OpGreater", nil},
+	OpLessEqual:    {"OpLessEqual", nil},
+	OpGreaterEqual: {"OpGreaterEqual", nil},
+	OpNot:          {"OpNot", nil},
+	OpNegate:       {"OpNegate", nil},
+
+	OpJump:          {"OpJump", []int{2}},
+	OpJumpNotTruthy: {"OpJumpNotTruthy", []int{2}},
+	OpJumpNull:      {"OpJumpNull", []int{2}},
+	OpCoalesce:      {"OpCoalesce", []int{2}},
+
+	OpGetGlobal:    {"OpGetGlobal", []int{2}},
+	OpDefineGlobal: {"OpDefineGlobal", []int{2}},
+	OpSetGlobal:    {"OpSetGlobal", []int{2}},
+	OpGetLocal:     {"OpGetLocal", []int{1, 2}},
+	OpSetLocal:     {"OpSetLocal", []int{1, 2}},
+	OpGetBuiltin:   {"OpGetBuiltin", []int{2}},
+
+	OpArray: {"OpArray", []int{2}},
+	OpTuple: {"OpTuple", []int{2}},
+	OpHash:  {"OpHash", []int{2}},
+	OpSet:   {"OpSet", []int{2}},
+
+	OpIndex:     {"OpIndex", nil},
+	OpSetIndex:  {"OpSetIndex", nil},
+	OpMember:    {"OpMember", []int{2}},
+	OpSetMember: {"OpSetMember", []int{2}},
+
+	OpCall:        {"OpCall", []int{1}},
+	OpTailCall:    {"OpTailCall", []int{1}},
+	OpReturnValue: {"OpReturnValue", nil},
+	OpReturn:      {"OpReturn", nil},
+	OpClosure:     {"OpClosure", []int{2}},
+	OpPropagate:   {"OpPropagate", nil},
+
+	OpThrow:      {"OpThrow", nil},
+	OpTry:        {"OpTry", []int{2, 2}},
+	OpEndTry:     {"OpEndTry", []int{2}},
+	OpEndFinally: {"OpEndFinally", nil},
+	OpEnterFrame: {"OpEnterFrame", []int{2}},
+	OpLeaveFrame: {"OpLeaveFrame", nil},
+
+	OpFreeze:            {"OpFreeze", nil},
+	OpNotNull:           {"OpNotNull", []int{2}},
+	OpDestructure:       {"OpDestructure", []int{1, 1, 1}},
+	OpDestructureMember: {"OpDestructureMember", []int{2}},
+
+	OpImport: {"OpImport", []int{2}},
+	OpExport: {"OpExport", []int{2}},
+}
+
+func Lookup(op Opcode) (*Definition, error) {
+	def, ok := definitions[op]
+
+	if !ok {
+		return nil, fmt.Errorf("opcode %d undefined", op)
+	}
+
+	return def, nil
+}
+
+// encodes an instruction, operands are big endian
+func Make(op Opcode, operands ...int) []byte {
+	def, ok := definitions[op]
+
+	if !ok {
+		return []byte{}
+	}
+
+	length := 1
+	for _, w := range def.OperandWidths {
+		length += w
+	}
+
+	instruction := make([]byte, length)
+	instruction[0] = byte(op)
+
+	offset := 1
+	for i, o := range operands {
+		switch def.OperandWidths[i] {
+		case 1:
+			instruction[offset] = byte(o)
+		case 2:
+			binary.BigEndian.PutUint16(instruction[offset:], uint16(o))
+		}
+
+		offset += def.OperandWidths[i]
+	}
+
+	return instruction
+}
+
+// decodes the operands of an instruction, returning them and the bytes read
+func ReadOperands(def *Definition, ins Instructions) ([]int, int) {
+	operands := make([]int, len(def.OperandWidths))
+	offset := 0
+
+	for i, width := range def.OperandWidths {
+		switch width {
+		case 1:
+			operands[i] = int(ins[offset])
+		case 2:
+			operands[i] = int(ReadUint16(ins[offset:]))
+		}
+
+		offset += width
+	}
+
+	return operands, offset
+}
+
+func ReadUint16(ins Instructions) uint16 {
+	return binary.BigEndian.Uint16(ins)
+}
+
+// one instruction per line, prefixed with its offset
+func (ins Instructions) String() string {
+	var out strings.Builder
+
+	for i := 0; i < len(ins); {
+		def, err := Lookup(Opcode(ins[i]))
+
+		if err != nil {
+			fmt.Fprintf(&out, "ERROR: %s\n", err)
+			i++
+			continue
+		}
+
+		operands, read := ReadOperands(def, ins[i+1:])
+
+		parts := []string{def.Name}
+		for _, o := range operands {
+			parts = append(parts, fmt.Sprint(o))
+		}
+
+		fmt.Fprintf(&out, "%04d %s\n", i, strings.Join(parts, " "))
+		i += 1 + read
+	}
+
+	return out.String()
+}
@@ -0,0 +1,23 @@
+package bytecode
+
+import "testing"
+
+func TestInstructions(t *testing.T) {
+	ins := Instructions{}
+	ins = append(ins, Make(OpConstant, 65534)...)
+	ins = append(ins, Make(OpGetLocal, 1, 2)...)
+	ins = append(ins, Make(OpAdd)...)
+
+	expected := "0000 OpConstant 65534\n0003 OpGetLocal 1 2\n0007 OpAdd\n"
+
+	if ins.String() != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, ins.String())
+	}
+
+	def, _ := Lookup(OpGetLocal)
+	operands, n := ReadOperands(def, ins[4:])
+
+	if n != 3 || operands[0] != 1 || operands[1] != 2 {
+		t.Errorf("expected operands [1 2] read from 3 bytes, got %v from %d", operands, n)
+	}
+}
@@ -0,0 +1,200 @@
+package bytecode
+
+import (
+	"fmt"
+
+	"github.com/mantton/anthe/internal/ast"
+	"github.com/mantton/anthe/internal/object"
+	"github.com/mantton/anthe/internal/resolver"
+	"github.com/mantton/anthe/internal/token"
+)
+
+type symbol struct {
+	index    int
+	constant bool
+}
+
+// names looked up by name at runtime, those of the program and of the blocks at its top level
+type globalScope struct {
+	parent *globalScope
+	names  map[string]*symbol
+}
+
+func newGlobalScope(parent *globalScope) *globalScope {
+	return &globalScope{parent: parent, names: make(map[string]*symbol)}
+}
+
+/*
+Compiles resolved programs into bytecode for the vm.
+Locals use the frame slots the resolver gave them. Every other name of a program, and of the blocks
+at its top level, is given a global slot of its own when its scope is entered, so later statements
+and functions can refer to it before it is defined. The constants and globals are kept across
+programs, so REPL lines see the declarations of earlier ones.
+*/
+type Compiler struct {
+	constants []object.Object
+	names     map[string]int // constant index of each name, deduplicated
+	globals   []string
+
+	root  *globalScope
+	scope *globalScope
+
+	function *Function // being compiled
+	frames   int       // enclosing functions and catch clauses, names are locals within them
+	pos      token.Position
+}
+
+func NewCompiler() *Compiler {
+	root := newGlobalScope(nil)
+
+	return &Compiler{
+		names: make(map[string]int),
+		root:  root,
+		scope: root,
+	}
+}
+
+// compiles a program whose names have been resolved
+func (c *Compiler) Compile(program *ast.Program) (*Program, error) {
+	c.function = &Function{Name: "<main>"}
+	c.scope = c.root
+	c.frames = 0
+	c.declare(program.Statements)
+
+	for i, s := range program.Statements {
+		pushed, err := c.compileStatement(s)
+
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case i == len(program.Statements)-1 && pushed:
+			c.emit(OpReturnValue)
+		case pushed:
+			c.emit(OpPop)
+		}
+	}
+
+	c.emit(OpReturn)
+
+	return &Program{Main: c.function, Constants: c.constants, Globals: c.globals}, nil
+}
+
+// gives the names declared by statements a global slot in the current scope
+func (c *Compiler) declare(statements []ast.Statement) {
+	for _, s := range statements {
+		for _, name := range resolver.DeclaredNames(s) {
+			c.scope.names[name] = &symbol{index: len(c.globals)}
+			c.globals = append(c.globals, name)
+		}
+	}
+}
+
+func (c *Compiler) lookup(name string) (*symbol, bool) {
+	for s := c.scope; s != nil; s = s.parent {
+		if sym, ok := s.names[name]; ok {
+			return sym, true
+		}
+	}
+
+	return nil, false
+}
+
+func (c *Compiler) addConstant(obj object.Object) int {
+	c.constants = append(c.constants, obj)
+	return len(c.constants) - 1
+}
+
+// the constant holding name
+func (c *Compiler) name(name string) int {
+	if idx, ok := c.names[name]; ok {
+		return idx
+	}
+
+	idx := c.addConstant(&object.String{Value: name})
+	c.names[name] = idx
+	return idx
+}
+
+// appends an instruction to the function being compiled, returning its offset
+func (c *Compiler) emit(op Opcode, operands ...int) int {
+	fn := c.function
+	offset := len(fn.Instructions)
+
+	if n := len(fn.Lines); n == 0 || fn.Lines[n-1].Position != c.pos {
+		fn.Lines = append(fn.Lines, Line{Offset: offset, Position: c.pos})
+	}
+
+	fn.Instructions = append(fn.Instructions, Make(op, operands...)...)
+	return offset
+}
+
+// rewrites the operands of the instruction at offset, once the addresses it jumps to are known
+func (c *Compiler) patch(offset int, operands ...int) {
+	op := Opcode(c.function.Instructions[offset])
+	copy(c.function.Instructions[offset:], Make(op, operands...))
+}
+
+// the offset the next instruction is emitted at
+func (c *Compiler) here() int {
+	return len(c.function.Instructions)
+}
+
+// positions instructions emitted until the returned function is called at node
+func (c *Compiler) at(node ast.Node) func() {
+	pos := c.pos
+	c.pos = node.Pos()
+	return func() { c.pos = pos }
+}
+
+// compiles the body of a function literal, returning the constant holding it
+func (c *Compiler) compileFunction(name string, lit *ast.FunctionLiteral) (int, error) {
+	for i, param := range lit.Parameters {
+		if !param.Location.Local || param.Location.Slot != i {
+			return 0, fmt.Errorf("parameter `%s` of `%s` was not resolved", param.Value, name)
+		}
+	}
+
+	enclosing := c.function
+	c.function = &Function{Name: name, NumParams: len(lit.Parameters), FrameSize: lit.FrameSize}
+	c.frames++
+
+	defer func() {
+		c.function = enclosing
+		c.frames--
+	}()
+
+	if err := c.compileBlock(lit.Body); err != nil {
+		return 0, err
+	}
+
+	c.emit(OpReturnValue)
+	return c.addConstant(c.function), nil
+}
+
+// pops the value on top of the stack into the binding of name, a frozen copy of it when constant
+func (c *Compiler) bind(name string, loc ast.Location, constant bool) error {
+	if constant {
+		c.emit(OpFreeze)
+	}
+
+	if loc.Local {
+		c.emit(OpSetLocal, 0, loc.Slot)
+		return nil
+	}
+
+	if c.frames > 0 {
+		return fmt.Errorf("`%s` must be declared at the top level", name)
+	}
+
+	sym, ok := c.scope.names[name]
+
+	if !ok {
+		return fmt.Errorf("`%s` was not declared", name)
+	}
+
+	sym.constant = constant
+	c.emit(OpDefineGlobal, sym.index)
+	return nil
+}
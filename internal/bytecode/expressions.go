@@ -0,0 +1,303 @@
+package bytecode
+
+import (
+	"fmt"
+
+	"github.com/mantton/anthe/internal/ast"
+	"github.com/mantton/anthe/internal/builtins"
+	"github.com/mantton/anthe/internal/object"
+)
+
+var infixOperators = map[string]Opcode{
+	"+":  OpAdd,
+	"-":  OpSub,
+	"*":  OpMul,
+	"/":  OpDiv,
+	"==": OpEqual,
+	"!=": OpNotEqual,
+	"<":  OpLess,
+	">":  OpGreater,
+	"<=": OpLessEqual,
+	">=": OpGreaterEqual,
+}
+
+// compiles an expression, leaving its value on the stack
+func (c *Compiler) compileExpression(node ast.Expression) error {
+	defer c.at(node)()
+
+	switch node := node.(type) {
+	case *ast.IntegerLiteral:
+		c.emit(OpConstant, c.addConstant(&object.Integer{Value: node.Value}))
+	case *ast.FloatLiteral:
+		c.emit(OpConstant, c.addConstant(&object.Float{Value: node.Value}))
+	case *ast.StringLiteral:
+		c.emit(OpConstant, c.name(node.Value))
+	case *ast.BooleanLiteral:
+		if node.Value {
+			c.emit(OpTrue)
+		} else {
+			c.emit(OpFalse)
+		}
+	case *ast.NullLiteral:
+		c.emit(OpNull)
+	case *ast.ArrayLiteral:
+		return c.compileList(OpArray, node.Elements)
+	case *ast.TupleLiteral:
+		return c.compileList(OpTuple, node.Elements)
+	case *ast.SetLiteral:
+		return c.compileList(OpSet, node.Elements)
+	case *ast.HashLiteral:
+		// pairs are evaluated left to right, key before value
+		for _, pair := range node.Pairs {
+			if err := c.compileExpression(pair.Key); err != nil {
+				return err
+			}
+
+			if err := c.compileExpression(pair.Value); err != nil {
+				return err
+			}
+		}
+
+		c.emit(OpHash, len(node.Pairs))
+	case *ast.FunctionLiteral:
+		fn, err := c.compileFunction("", node)
+
+		if err != nil {
+			return err
+		}
+
+		c.emit(OpClosure, fn)
+	case *ast.IdentifierExpression:
+		return c.compileIdentifier(node)
+	case *ast.IfExpression:
+		return c.compileIfExpression(node)
+	case *ast.CallExpression:
+		if err := c.compileExpression(node.Function); err != nil {
+			return err
+		}
+
+		for _, arg := range node.Arguments {
+			if err := c.compileExpression(arg); err != nil {
+				return err
+			}
+		}
+
+		// the call site of a trace is the function being called
+		c.pos = node.Function.Pos()
+
+		if node.Tail {
+			c.emit(OpTailCall, len(node.Arguments))
+		} else {
+			c.emit(OpCall, len(node.Arguments))
+		}
+	case *ast.IndexExpression:
+		if err := c.compileExpression(node.Left); err != nil {
+			return err
+		}
+
+		skip := c.optional(node.Optional)
+
+		if err := c.compileExpression(node.Index); err != nil {
+			return err
+		}
+
+		c.emit(OpIndex)
+		c.land(skip)
+	case *ast.MemberExpression:
+		if err := c.compileExpression(node.Object); err != nil {
+			return err
+		}
+
+		skip := c.optional(node.Optional)
+		c.emit(OpMember, c.name(node.Property.Value))
+		c.land(skip)
+	case *ast.PrefixExpression:
+		if err := c.compileExpression(node.Right); err != nil {
+			return err
+		}
+
+		switch node.Operator {
+		case "!":
+			c.emit(OpNot)
+		case "-":
+			c.emit(OpNegate)
+		default:
+			return fmt.Errorf("unknown operator: %s", node.Operator)
+		}
+	case *ast.InfixExpression:
+		return c.compileInfixExpression(node)
+	case *ast.AssignmentExpression:
+		return c.compileAssignmentExpression(node)
+	case *ast.ElementAssignmentExpression:
+		return c.compileElementAssignmentExpression(node)
+	case *ast.PropagateExpression:
+		if err := c.compileExpression(node.Value); err != nil {
+			return err
+		}
+
+		c.emit(OpPropagate)
+	default:
+		return fmt.Errorf("unknown expression %T", node)
+	}
+
+	return nil
+}
+
+func (c *Compiler) compileList(op Opcode, elements []ast.Expression) error {
+	for _, el := range elements {
+		if err := c.compileExpression(el); err != nil {
+			return err
+		}
+	}
+
+	c.emit(op, len(elements))
+	return nil
+}
+
+// optional access skips the rest of the expression on null, leaving the null as its value
+func (c *Compiler) optional(optional bool) int {
+	if !optional {
+		return -1
+	}
+
+	return c.emit(OpJumpNull, NoAddress)
+}
+
+func (c *Compiler) land(jump int) {
+	if jump >= 0 {
+		c.patch(jump, c.here())
+	}
+}
+
+func (c *Compiler) compileIdentifier(node *ast.IdentifierExpression) error {
+	if loc := node.Location; loc.Local {
+		c.emit(OpGetLocal, loc.Depth, loc.Slot)
+		return nil
+	}
+
+	if sym, ok := c.lookup(node.Value); ok {
+		c.emit(OpGetGlobal, sym.index)
+		return nil
+	}
+
+	if _, ok := builtins.BuiltInFunctions[node.Value]; ok {
+		c.emit(OpGetBuiltin, c.name(node.Value))
+		return nil
+	}
+
+	return fmt.Errorf("undefined variable `%s`", node.Value)
+}
+
+// the branch taken leaves its value, void when there is no else
+func (c *Compiler) compileIfExpression(node *ast.IfExpression) error {
+	if err := c.compileExpression(node.Condition); err != nil {
+		return err
+	}
+
+	alternative := c.emit(OpJumpNotTruthy, NoAddress)
+
+	if err := c.compileBlock(node.Action); err != nil {
+		return err
+	}
+
+	end := c.emit(OpJump, NoAddress)
+	c.patch(alternative, c.here())
+
+	if node.Alternative != nil {
+		if err := c.compileBlock(node.Alternative); err != nil {
+			return err
+		}
+	} else {
+		c.emit(OpVoid)
+	}
+
+	c.patch(end, c.here())
+	return nil
+}
+
+func (c *Compiler) compileInfixExpression(node *ast.InfixExpression) error {
+	if err := c.compileExpression(node.Left); err != nil {
+		return err
+	}
+
+	// coalescing only evaluates the right hand side when needed
+	if node.Operator == "??" {
+		end := c.emit(OpCoalesce, NoAddress)
+
+		if err := c.compileExpression(node.Right); err != nil {
+			return err
+		}
+
+		c.patch(end, c.here())
+		return nil
+	}
+
+	if err := c.compileExpression(node.Right); err != nil {
+		return err
+	}
+
+	op, ok := infixOperators[node.Operator]
+
+	if !ok {
+		return fmt.Errorf("unknown operator: %s", node.Operator)
+	}
+
+	c.emit(op)
+	return nil
+}
+
+func (c *Compiler) compileAssignmentExpression(node *ast.AssignmentExpression) error {
+	if err := c.compileExpression(node.Value); err != nil {
+		return err
+	}
+
+	if loc := node.Target.Location; loc.Local {
+		c.emit(OpSetLocal, loc.Depth, loc.Slot)
+		c.emit(OpVoid)
+		return nil
+	}
+
+	sym, ok := c.lookup(node.Target.Value)
+
+	if !ok {
+		return fmt.Errorf("undefined variable %s", node.Target.Value)
+	}
+
+	if sym.constant {
+		return fmt.Errorf("cannot reassign constant '%s'", node.Target.Value)
+	}
+
+	c.emit(OpSetGlobal, sym.index)
+	c.emit(OpVoid)
+	return nil
+}
+
+// the value is evaluated before the container it is stored in
+func (c *Compiler) compileElementAssignmentExpression(node *ast.ElementAssignmentExpression) error {
+	if err := c.compileExpression(node.Value); err != nil {
+		return err
+	}
+
+	switch target := node.Target.(type) {
+	case *ast.IndexExpression:
+		if err := c.compileExpression(target.Left); err != nil {
+			return err
+		}
+
+		if err := c.compileExpression(target.Index); err != nil {
+			return err
+		}
+
+		c.emit(OpSetIndex)
+	case *ast.MemberExpression:
+		if err := c.compileExpression(target.Object); err != nil {
+			return err
+		}
+
+		c.emit(OpSetMember, c.name(target.Property.Value))
+	default:
+		return fmt.Errorf("cannot assign to %T", target)
+	}
+
+	return nil
+}
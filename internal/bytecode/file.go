@@ -0,0 +1,324 @@
+package bytecode
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"math"
+
+	"github.com/mantton/anthe/internal/object"
+	"github.com/mantton/anthe/internal/token"
+)
+
+const FILE_EXTENSION = ".anbc"
+
+// bumped whenever the instruction set or the layout below changes
+const VERSION = 2
+
+var magic = []byte("ANBC")
+
+/*
+Precompiled modules are stored as
+
+	header       magic "ANBC", version as 2 bytes, count of modules
+	modules      in dependency order, each with
+	  name, path
+	  globals    names of the global slots
+	  constants  tagged, functions refer to the function table
+	  functions  name, parameters, frame size and instructions, main first
+	  lines      debug line table of each function, in the order of the table
+	checksum     crc32 of everything before it, 4 bytes
+
+Counts, lengths and integers are varints, strings are length prefixed, floats are their 8 IEEE 754 bytes.
+*/
+const (
+	tagInteger byte = iota + 1
+	tagFloat
+	tagString
+	tagStruct
+	tagFunction
+)
+
+// serializes compiled modules to the .anbc format
+func Encode(modules []*Module) ([]byte, error) {
+	e := &encoder{}
+	e.buf = append(e.buf, magic...)
+	e.buf = binary.BigEndian.AppendUint16(e.buf, VERSION)
+	e.uint(len(modules))
+
+	for _, m := range modules {
+		if err := e.module(m); err != nil {
+			return nil, err
+		}
+	}
+
+	return binary.BigEndian.AppendUint32(e.buf, crc32.ChecksumIEEE(e.buf)), nil
+}
+
+type encoder struct {
+	buf []byte
+}
+
+func (e *encoder) uint(v int) {
+	e.buf = binary.AppendUvarint(e.buf, uint64(v))
+}
+
+func (e *encoder) string(s string) {
+	e.uint(len(s))
+	e.buf = append(e.buf, s...)
+}
+
+func (e *encoder) module(m *Module) error {
+	e.string(m.Name)
+	e.string(m.Path)
+
+	e.uint(len(m.Program.Globals))
+	for _, name := range m.Program.Globals {
+		e.string(name)
+	}
+
+	// main is the first function of the table, those of the constant pool follow in order
+	functions := []*Function{m.Program.Main}
+
+	e.uint(len(m.Program.Constants))
+	for _, c := range m.Program.Constants {
+		switch c := c.(type) {
+		case *object.Integer:
+			e.buf = append(e.buf, tagInteger)
+			e.buf = binary.AppendVarint(e.buf, c.Value)
+		case *object.Float:
+			e.buf = append(e.buf, tagFloat)
+			e.buf = binary.BigEndian.AppendUint64(e.buf, math.Float64bits(c.Value))
+		case *object.String:
+			e.buf = append(e.buf, tagString)
+			e.string(c.Value)
+		case *object.StructDefinition:
+			e.buf = append(e.buf, tagStruct)
+			e.string(c.Name)
+			e.uint(len(c.Fields))
+			for _, field := range c.Fields {
+				e.string(field)
+			}
+		case *Function:
+			e.buf = append(e.buf, tagFunction)
+			e.uint(len(functions))
+			functions = append(functions, c)
+		default:
+			return fmt.Errorf("cannot serialize constant of type %s", c.Type())
+		}
+	}
+
+	e.uint(len(functions))
+	for _, fn := range functions {
+		e.string(fn.Name)
+		e.uint(fn.NumParams)
+		e.uint(fn.FrameSize)
+		e.uint(len(fn.Instructions))
+		e.buf = append(e.buf, fn.Instructions...)
+	}
+
+	for _, fn := range functions {
+		e.uint(len(fn.Lines))
+		for _, line := range fn.Lines {
+			e.uint(line.Offset)
+			e.string(line.Position.Filename)
+			e.uint(line.Position.Line)
+			e.uint(line.Position.Col)
+		}
+	}
+
+	return nil
+}
+
+var errTruncated = errors.New("truncated bytecode file")
+
+// loads modules serialized by Encode, refusing files of another version or that fail their checksum
+func Decode(data []byte) ([]*Module, error) {
+	if len(data) < len(magic)+2+4 || string(data[:len(magic)]) != string(magic) {
+		return nil, errors.New("not an anthe bytecode file")
+	}
+
+	if version := binary.BigEndian.Uint16(data[len(magic):]); version != VERSION {
+		return nil, fmt.Errorf("unsupported bytecode version %d, expected %d", version, VERSION)
+	}
+
+	body, sum := data[:len(data)-4], binary.BigEndian.Uint32(data[len(data)-4:])
+
+	if crc32.ChecksumIEEE(body) != sum {
+		return nil, errors.New("bytecode checksum mismatch, the file is corrupt")
+	}
+
+	d := &decoder{data: body, off: len(magic) + 2}
+	modules := make([]*Module, d.count())
+
+	for i := range modules {
+		m, err := d.module()
+
+		if err != nil {
+			return nil, err
+		}
+
+		modules[i] = m
+	}
+
+	if d.err == nil && d.off != len(d.data) {
+		return nil, errors.New("unexpected data after the last module")
+	}
+
+	return modules, d.err
+}
+
+// reads are sticky on the first error, returning zero values after it
+type decoder struct {
+	data []byte
+	off  int
+	err  error
+}
+
+func (d *decoder) uint() int {
+	v, n := binary.Uvarint(d.data[min(d.off, len(d.data)):])
+
+	if n <= 0 || v > math.MaxInt32 {
+		d.fail()
+		return 0
+	}
+
+	d.off += n
+	return int(v)
+}
+
+// a count of items, each taking at least a byte, so no more than the bytes left before allocating them
+func (d *decoder) count() int {
+	n := d.uint()
+
+	if n > len(d.data)-d.off {
+		d.fail()
+		return 0
+	}
+
+	return n
+}
+
+func (d *decoder) int() int64 {
+	v, n := binary.Varint(d.data[min(d.off, len(d.data)):])
+
+	if n <= 0 {
+		d.fail()
+		return 0
+	}
+
+	d.off += n
+	return v
+}
+
+func (d *decoder) bytes(n int) []byte {
+	if d.err != nil || d.off+n > len(d.data) {
+		d.fail()
+		return nil
+	}
+
+	b := d.data[d.off : d.off+n]
+	d.off += n
+	return b
+}
+
+func (d *decoder) byte() byte {
+	if b := d.bytes(1); b != nil {
+		return b[0]
+	}
+
+	return 0
+}
+
+func (d *decoder) string() string {
+	return string(d.bytes(d.uint()))
+}
+
+func (d *decoder) fail() {
+	if d.err == nil {
+		d.err = errTruncated
+	}
+
+	d.off = len(d.data)
+}
+
+func (d *decoder) module() (*Module, error) {
+	m := &Module{Name: d.string(), Path: d.string(), Program: &Program{}}
+	p := m.Program
+
+	p.Globals = make([]string, d.count())
+	for i := range p.Globals {
+		p.Globals[i] = d.string()
+	}
+
+	// functions are patched in once the table is read
+	refs := make(map[int]int)
+	p.Constants = make([]object.Object, d.count())
+
+	for i := range p.Constants {
+		switch tag := d.byte(); tag {
+		case tagInteger:
+			p.Constants[i] = &object.Integer{Value: d.int()}
+		case tagFloat:
+			var bits uint64
+			if b := d.bytes(8); b != nil {
+				bits = binary.BigEndian.Uint64(b)
+			}
+			p.Constants[i] = &object.Float{Value: math.Float64frombits(bits)}
+		case tagString:
+			p.Constants[i] = &object.String{Value: d.string()}
+		case tagStruct:
+			def := &object.StructDefinition{Name: d.string()}
+			def.Fields = make([]string, d.count())
+			for j := range def.Fields {
+				def.Fields[j] = d.string()
+			}
+			p.Constants[i] = def
+		case tagFunction:
+			refs[i] = d.uint()
+		default:
+			if d.err != nil {
+				return nil, d.err
+			}
+			return nil, fmt.Errorf("unknown constant tag %d", tag)
+		}
+	}
+
+	functions := make([]*Function, d.count())
+	for i := range functions {
+		functions[i] = &Function{Name: d.string(), NumParams: d.uint(), FrameSize: d.uint()}
+		functions[i].Instructions = Instructions(d.bytes(d.uint()))
+	}
+
+	for _, fn := range functions {
+		fn.Lines = make([]Line, d.count())
+		for i := range fn.Lines {
+			fn.Lines[i] = Line{Offset: d.uint(), Position: token.Position{Filename: d.string(), Line: d.uint(), Col: d.uint()}}
+		}
+	}
+
+	if d.err != nil {
+		return nil, d.err
+	}
+
+	if len(functions) == 0 {
+		return nil, fmt.Errorf("module `%s` has no main function", m.Name)
+	}
+
+	p.Main = functions[0]
+
+	for i, ref := range refs {
+		if ref <= 0 || ref >= len(functions) {
+			return nil, fmt.Errorf("constant %d refers to unknown function %d", i, ref)
+		}
+
+		p.Constants[i] = functions[ref]
+	}
+
+	if err := verify(p); err != nil {
+		return nil, fmt.Errorf("module `%s`: %w", m.Name, err)
+	}
+
+	return m, nil
+}
@@ -0,0 +1,117 @@
+package bytecode
+
+import (
+	"encoding/binary"
+	"hash/crc32"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/mantton/anthe/internal/lexer"
+	"github.com/mantton/anthe/internal/module"
+	"github.com/mantton/anthe/internal/object"
+	"github.com/mantton/anthe/internal/parser"
+)
+
+func TestEncoding(t *testing.T) {
+	input := `struct P { x: int, y: float }; func f(a) { let s = "s"; func() { a + 1 } }; f(-2)(); P(1, 2.5)`
+	prog := parser.New(lexer.New(input, "test.an")).ParseProgram()
+
+	compiled, err := CompileModules([]*module.Module{{Name: "test", Path: "/test.an", Program: prog}})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := Encode(compiled)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decoded, err := Decode(data)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected, got := compiled[0].Program, decoded[0].Program
+
+	if decoded[0].Path != "/test.an" || len(got.Globals) != len(expected.Globals) {
+		t.Fatalf("expected module /test.an with globals %v, got %s with %v", expected.Globals, decoded[0].Path, got.Globals)
+	}
+
+	if got.Main.Instructions.String() != expected.Main.Instructions.String() {
+		t.Errorf("expected main\n%s\ngot\n%s", expected.Main.Instructions, got.Main.Instructions)
+	}
+
+	for i, c := range expected.Constants {
+		if fn, ok := c.(*Function); ok {
+			decodedFn, ok := got.Constants[i].(*Function)
+
+			if !ok || decodedFn.Instructions.String() != fn.Instructions.String() || len(decodedFn.Lines) != len(fn.Lines) {
+				t.Errorf("constant %d: function %s not restored", i, fn.Name)
+			}
+		} else if got.Constants[i].Inspect() != c.Inspect() {
+			t.Errorf("constant %d: expected %s, got %s", i, c.Inspect(), got.Constants[i].Inspect())
+		}
+	}
+
+	for i, line := range expected.Main.Lines {
+		if i >= len(got.Main.Lines) || got.Main.Lines[i] != line {
+			t.Fatalf("expected debug lines %v, got %v", expected.Main.Lines, got.Main.Lines)
+		}
+	}
+
+	data[len(data)/2] ^= 0xFF
+	if _, err := Decode(data); err == nil {
+		t.Errorf("expected corrupt data to fail its checksum")
+	}
+}
+
+func TestDecodeCounts(t *testing.T) {
+	// a file that passes its checksum but claims more modules than it has bytes for
+	data := append([]byte("ANBC"), 0, VERSION)
+	data = binary.AppendUvarint(data, math.MaxInt32)
+	data = binary.BigEndian.AppendUint32(data, crc32.ChecksumIEEE(data))
+
+	if _, err := Decode(data); err == nil || !strings.Contains(err.Error(), "truncated") {
+		t.Errorf("expected a truncated file error, got %v", err)
+	}
+}
+
+func TestDecodeVerifies(t *testing.T) {
+	tests := []struct {
+		instructions []byte
+		expected     string
+	}{
+		{[]byte{0xFE}, "opcode 254 undefined"},
+		{Make(OpConstant, 1)[:2], "missing operands"},
+		{append(Make(OpConstant, 40), Make(OpReturnValue)...), "unknown constant 40"},
+		{append(Make(OpJump, 1), Make(OpReturn)...), "jumps to 1"},
+		{append(Make(OpGetGlobal, 3), Make(OpReturnValue)...), "unknown global 3"},
+		{append(Make(OpGetLocal, 0, 2), Make(OpReturnValue)...), "slot 2 of a frame 0 up"},
+		{append(Make(OpGetLocal, 1, 0), Make(OpReturnValue)...), "slot 0 of a frame 1 up"},
+		{append(Make(OpMember, 0), Make(OpReturnValue)...), "constant 0 to be a string"},
+		{append(Make(OpClosure, 1), Make(OpReturnValue)...), "constant 1 to be a function"},
+		{Make(OpTrue), "does not end in a return"},
+	}
+
+	for _, tt := range tests {
+		program := &Program{
+			Main:      &Function{Name: "main", FrameSize: 2, Instructions: tt.instructions},
+			Constants: []object.Object{&object.Integer{Value: 1}, &object.String{Value: "x"}},
+			Globals:   []string{"a"},
+		}
+
+		data, err := Encode([]*Module{{Name: "test", Path: "/test.an", Program: program}})
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := Decode(data); err == nil || !strings.Contains(err.Error(), tt.expected) {
+			t.Errorf("%v: expected an error containing %q, got %v", tt.instructions, tt.expected, err)
+		}
+	}
+}
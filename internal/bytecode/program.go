@@ -0,0 +1,77 @@
+package bytecode
+
+import (
+	"errors"
+
+	"github.com/mantton/anthe/internal/module"
+	"github.com/mantton/anthe/internal/object"
+	"github.com/mantton/anthe/internal/resolver"
+	"github.com/mantton/anthe/internal/token"
+)
+
+// the compiled form of a program, run by the vm
+type Program struct {
+	Main      *Function
+	Constants []object.Object // integers, floats, strings, functions and struct definitions
+	Globals   []string        // names of the global slots, by index
+}
+
+// a compiled function body, closed over its frame when evaluated
+type Function struct {
+	Name         string
+	Instructions Instructions
+	NumParams    int
+	FrameSize    int    // slots of a call frame, parameters first
+	Lines        []Line // position of the instructions from each offset on
+}
+
+type Line struct {
+	Offset   int
+	Position token.Position
+}
+
+func (f *Function) Type() object.ObjectType { return object.FUNCTION }
+func (f *Function) Inspect() string         { return "FUNCTION" }
+
+// the position of the source the instruction at offset was compiled from
+func (f *Function) PositionAt(offset int) token.Position {
+	pos := token.Position{}
+
+	for _, line := range f.Lines {
+		if line.Offset > offset {
+			break
+		}
+
+		pos = line.Position
+	}
+
+	return pos
+}
+
+// a compiled module, run in dependency order with globals of its own
+type Module struct {
+	Name    string
+	Path    string // resolved path, which imports of the module refer to
+	Program *Program
+}
+
+// resolves and compiles modules in dependency order
+func CompileModules(modules []*module.Module) ([]*Module, error) {
+	compiled := make([]*Module, 0, len(modules))
+
+	for _, m := range modules {
+		if errs := resolver.New().Resolve(m.Program); len(errs) > 0 {
+			return nil, errors.Join(errs...)
+		}
+
+		program, err := NewCompiler().Compile(m.Program)
+
+		if err != nil {
+			return nil, err
+		}
+
+		compiled = append(compiled, &Module{Name: m.Name, Path: m.Path, Program: program})
+	}
+
+	return compiled, nil
+}
@@ -0,0 +1,311 @@
+package bytecode
+
+import (
+	"fmt"
+
+	"github.com/mantton/anthe/internal/ast"
+	"github.com/mantton/anthe/internal/object"
+)
+
+// compiles a statement, returning whether it left a value on the stack
+func (c *Compiler) compileStatement(node ast.Statement) (bool, error) {
+	defer c.at(node)()
+
+	switch node := node.(type) {
+	case *ast.ExpressionStatement:
+		return true, c.compileExpression(node.Expression)
+	case *ast.BlockStatement:
+		return true, c.compileBlock(node)
+	case *ast.LetStatement:
+		return false, c.compileDeclaration(node.Name, node.Type, node.Value, false)
+	case *ast.ConstStatement:
+		return false, c.compileDeclaration(node.Name, node.Type, node.Value, true)
+	case *ast.DestructuringStatement:
+		return false, c.compileDestructuringStatement(node)
+	case *ast.ReturnStatement:
+		if err := c.compileExpression(node.ReturnValue); err != nil {
+			return false, err
+		}
+
+		c.emit(OpReturnValue)
+		return false, nil
+	case *ast.ThrowStatement:
+		if err := c.compileExpression(node.Value); err != nil {
+			return false, err
+		}
+
+		c.emit(OpThrow)
+		return false, nil
+	case *ast.TryStatement:
+		return true, c.compileTryStatement(node)
+	case *ast.NamedFunctionDeclaration:
+		fn, err := c.compileFunction(node.Name, node.Fn)
+
+		if err != nil {
+			return false, err
+		}
+
+		c.emit(OpClosure, fn)
+		return true, c.bindVoid(node.Name, node.Location, false)
+	case *ast.StructDeclaration:
+		def := &object.StructDefinition{Name: node.Name}
+
+		for _, field := range node.Fields {
+			def.Fields = append(def.Fields, field.Name)
+		}
+
+		c.emit(OpConstant, c.addConstant(def))
+		return true, c.bindVoid(node.Name, ast.Location{}, true)
+	case *ast.TypeDeclaration:
+		return true, c.compileTypeDeclaration(node)
+	case *ast.ImportStatement:
+		return true, c.compileImportStatement(node)
+	case *ast.ExportStatement:
+		return c.compileExportStatement(node)
+	}
+
+	return false, fmt.Errorf("unknown node : %T", node)
+}
+
+// binds the value on top of the stack, for declarations evaluating to void
+func (c *Compiler) bindVoid(name string, loc ast.Location, constant bool) error {
+	if err := c.bind(name, loc, constant); err != nil {
+		return err
+	}
+
+	c.emit(OpVoid)
+	return nil
+}
+
+// let and const, only bindings declared as optional, or without a declared type, may hold null
+func (c *Compiler) compileDeclaration(name *ast.IdentifierExpression, t ast.TypeExpression, value ast.Expression, constant bool) error {
+	if err := c.compileExpression(value); err != nil {
+		return err
+	}
+
+	if t != nil {
+		if _, ok := t.(*ast.OptionalType); !ok {
+			msg := fmt.Sprintf("cannot assign null to `%s` declared as `%s`", name.Value, t.Type())
+			c.emit(OpNotNull, c.name(msg))
+		}
+	}
+
+	return c.bind(name.Value, name.Location, constant)
+}
+
+// a block leaves the value of its last statement, void when empty or ending in a declaration
+func (c *Compiler) compileBlock(block *ast.BlockStatement) error {
+	// blocks at the top level have globals of their own
+	if c.frames == 0 {
+		enclosing := c.scope
+		c.scope = newGlobalScope(enclosing)
+		defer func() { c.scope = enclosing }()
+
+		c.declare(block.Statements)
+	}
+
+	pushed := false
+
+	for i, s := range block.Statements {
+		if pushed {
+			c.emit(OpPop)
+		}
+
+		var err error
+		pushed, err = c.compileStatement(s)
+
+		if err != nil {
+			return err
+		}
+
+		if i == len(block.Statements)-1 && !pushed {
+			c.emit(OpVoid)
+			pushed = true
+		}
+	}
+
+	if !pushed {
+		c.emit(OpVoid)
+	}
+
+	return nil
+}
+
+/*
+Try statements are laid out as
+
+	    OpTry catch finally
+	    <body>
+	    OpEndTry end
+	catch:
+	    OpEnterFrame size, bind the error
+	    <catch>
+	    OpLeaveFrame
+	    OpEndTry end
+	finally:
+	    <finally>, its value is dropped
+	    OpEndFinally
+	end:
+*/
+func (c *Compiler) compileTryStatement(node *ast.TryStatement) error {
+	try := c.emit(OpTry, NoAddress, NoAddress)
+
+	if err := c.compileBlock(node.Body); err != nil {
+		return err
+	}
+
+	ends := []int{c.emit(OpEndTry, NoAddress)}
+	catch, finally := NoAddress, NoAddress
+
+	if node.Catch != nil {
+		catch = c.here()
+		c.emit(OpEnterFrame, node.CatchFrameSize)
+		c.frames++
+
+		if node.CatchParam != nil {
+			c.emit(OpSetLocal, 0, node.CatchParam.Location.Slot)
+		} else {
+			c.emit(OpPop)
+		}
+
+		err := c.compileBlock(node.Catch)
+		c.frames--
+
+		if err != nil {
+			return err
+		}
+
+		c.emit(OpLeaveFrame)
+		ends = append(ends, c.emit(OpEndTry, NoAddress))
+	}
+
+	if node.Finally != nil {
+		finally = c.here()
+
+		if err := c.compileBlock(node.Finally); err != nil {
+			return err
+		}
+
+		c.emit(OpPop)
+		c.emit(OpEndFinally)
+	}
+
+	c.patch(try, catch, finally)
+	for _, end := range ends {
+		c.patch(end, c.here())
+	}
+
+	return nil
+}
+
+/*
+Tuple and array patterns replace the value with its parts, bound from the last one down.
+Object patterns read each member from a copy of the value.
+*/
+func (c *Compiler) compileDestructuringStatement(node *ast.DestructuringStatement) error {
+	if err := c.compileExpression(node.Value); err != nil {
+		return err
+	}
+
+	if node.Kind == ast.ObjectPattern {
+		for _, name := range node.Names {
+			c.emit(OpDup)
+			c.emit(OpDestructureMember, c.name(name.Value))
+
+			if err := c.bind(name.Value, name.Location, node.Constant); err != nil {
+				return err
+			}
+		}
+
+		c.emit(OpPop)
+		return nil
+	}
+
+	rest := 0
+	if node.Rest != nil {
+		rest = 1
+	}
+
+	c.emit(OpDestructure, int(node.Kind), len(node.Names), rest)
+
+	if node.Rest != nil {
+		if err := c.bind(node.Rest.Value, node.Rest.Location, node.Constant); err != nil {
+			return err
+		}
+	}
+
+	for i := len(node.Names) - 1; i >= 0; i-- {
+		name := node.Names[i]
+
+		if err := c.bind(name.Value, name.Location, node.Constant); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// aliases only exist for the checker, distinct types bind a constructor that returns its argument as is
+func (c *Compiler) compileTypeDeclaration(node *ast.TypeDeclaration) error {
+	if !node.Distinct {
+		c.emit(OpVoid)
+		return nil
+	}
+
+	enclosing := c.function
+	c.function = &Function{Name: node.Name, NumParams: 1, FrameSize: 1}
+	c.emit(OpGetLocal, 0, 0)
+	c.emit(OpReturnValue)
+	ctor := c.addConstant(c.function)
+	c.function = enclosing
+
+	c.emit(OpClosure, ctor)
+	return c.bindVoid(node.Name, ast.Location{}, true)
+}
+
+// binds the module, or the listed exports of it
+func (c *Compiler) compileImportStatement(node *ast.ImportStatement) error {
+	path := c.name(node.Resolved)
+
+	if node.Names == nil {
+		c.emit(OpImport, path)
+		return c.bindVoid(node.Name, ast.Location{}, true)
+	}
+
+	for _, name := range node.Names {
+		c.emit(OpImport, path)
+		c.emit(OpMember, c.name(name.Value))
+
+		if err := c.bind(name.Value, ast.Location{}, true); err != nil {
+			return err
+		}
+	}
+
+	c.emit(OpVoid)
+	return nil
+}
+
+func (c *Compiler) compileExportStatement(node *ast.ExportStatement) (bool, error) {
+	if c.frames > 0 || c.scope != c.root {
+		return false, fmt.Errorf("exports must be at the top level of a module")
+	}
+
+	pushed, err := c.compileStatement(node.Statement)
+
+	if err != nil {
+		return false, err
+	}
+
+	for _, name := range node.Names() {
+		// aliases have no runtime value
+		if decl, ok := node.Statement.(*ast.TypeDeclaration); ok && !decl.Distinct {
+			c.emit(OpVoid)
+		} else {
+			c.emit(OpGetGlobal, c.root.names[name].index)
+		}
+
+		c.emit(OpExport, c.name(name))
+	}
+
+	return pushed, nil
+}
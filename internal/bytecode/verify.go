@@ -0,0 +1,181 @@
+package bytecode
+
+import (
+	"fmt"
+
+	"github.com/mantton/anthe/internal/object"
+)
+
+// slots addressable by the 2 byte operand of OpGetLocal and OpSetLocal
+const maxFrameSize = 0xFFFF + 1
+
+/*
+Checks a decoded program can run without reading outside of what it was loaded with.
+Every instruction must be defined and complete, jumps must land on an instruction, constants,
+globals and local slots must exist and functions must end rather than run past their instructions.
+Functions are checked where they are closed over, against the frames enclosing them there.
+*/
+func verify(p *Program) error {
+	v := &verifier{program: p, outer: make(map[*Function][]int)}
+	return v.function(p.Main, nil)
+}
+
+type verifier struct {
+	program *Program
+	outer   map[*Function][]int // frame sizes enclosing each checked function, innermost last
+}
+
+func (v *verifier) function(fn *Function, outer []int) error {
+	if previous, ok := v.outer[fn]; ok {
+		if !sameFrames(previous, outer) {
+			return fmt.Errorf("function `%s` is closed over in different frames", fn.Name)
+		}
+
+		return nil
+	}
+
+	v.outer[fn] = outer
+
+	if fn.FrameSize > maxFrameSize || fn.NumParams > fn.FrameSize {
+		return fmt.Errorf("function `%s` has %d parameters in a frame of %d slots", fn.Name, fn.NumParams, fn.FrameSize)
+	}
+
+	starts, err := instructionStarts(fn)
+
+	if err != nil {
+		return err
+	}
+
+	// catch clauses push frames of their own, nested in the order they are laid out
+	frames := append(append([]int{}, outer...), fn.FrameSize)
+	closures := [][]int{}
+	var closed []*Function
+
+	ins := fn.Instructions
+	ends := false
+
+	for ip := 0; ip < len(ins); {
+		op := Opcode(ins[ip])
+		def, _ := Lookup(op)
+		operands, read := ReadOperands(def, ins[ip+1:])
+
+		fail := func(format string, args ...any) error {
+			return fmt.Errorf("function `%s` at %04d: %s %s", fn.Name, ip, def.Name, fmt.Sprintf(format, args...))
+		}
+
+		switch op {
+		case OpJump, OpJumpNotTruthy, OpJumpNull, OpCoalesce, OpEndTry:
+			if !starts[operands[0]] {
+				return fail("jumps to %d, which is not an instruction", operands[0])
+			}
+		case OpTry:
+			for _, target := range operands {
+				if target != NoAddress && !starts[target] {
+					return fail("handles errors at %d, which is not an instruction", target)
+				}
+			}
+		case OpConstant:
+			if operands[0] >= len(v.program.Constants) {
+				return fail("refers to unknown constant %d", operands[0])
+			}
+		case OpGetBuiltin, OpMember, OpSetMember, OpNotNull, OpDestructureMember, OpImport, OpExport:
+			if _, ok := v.constant(operands[0]).(*object.String); !ok {
+				return fail("expects constant %d to be a string", operands[0])
+			}
+		case OpClosure:
+			closure, ok := v.constant(operands[0]).(*Function)
+
+			if !ok {
+				return fail("expects constant %d to be a function", operands[0])
+			}
+
+			closed = append(closed, closure)
+			closures = append(closures, append([]int{}, frames...))
+		case OpGetGlobal, OpDefineGlobal, OpSetGlobal:
+			if operands[0] >= len(v.program.Globals) {
+				return fail("refers to unknown global %d", operands[0])
+			}
+		case OpGetLocal, OpSetLocal:
+			depth, slot := operands[0], operands[1]
+
+			if depth >= len(frames) || slot >= frames[len(frames)-1-depth] {
+				return fail("refers to slot %d of a frame %d up, outside of the enclosing frames", slot, depth)
+			}
+		case OpEnterFrame:
+			frames = append(frames, operands[0])
+		case OpLeaveFrame:
+			if len(frames) == len(outer)+1 {
+				return fail("leaves a frame it did not enter")
+			}
+
+			frames = frames[:len(frames)-1]
+		}
+
+		// the vm reads past the instructions unless the last one leaves the function or jumps back
+		ends = op == OpReturn || op == OpReturnValue || op == OpJump || op == OpThrow
+		ip += 1 + read
+	}
+
+	if !ends {
+		return fmt.Errorf("function `%s` does not end in a return", fn.Name)
+	}
+
+	for i, closure := range closed {
+		if err := v.function(closure, closures[i]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// constant idx, nil if there is none
+func (v *verifier) constant(idx int) object.Object {
+	if idx >= len(v.program.Constants) {
+		return nil
+	}
+
+	return v.program.Constants[idx]
+}
+
+// the offsets instructions start at, failing on undefined opcodes and missing operands
+func instructionStarts(fn *Function) (map[int]bool, error) {
+	starts := make(map[int]bool)
+	ins := fn.Instructions
+
+	for ip := 0; ip < len(ins); {
+		def, err := Lookup(Opcode(ins[ip]))
+
+		if err != nil {
+			return nil, fmt.Errorf("function `%s` at %04d: %s", fn.Name, ip, err)
+		}
+
+		width := 0
+		for _, w := range def.OperandWidths {
+			width += w
+		}
+
+		if ip+1+width > len(ins) {
+			return nil, fmt.Errorf("function `%s` at %04d: %s is missing operands", fn.Name, ip, def.Name)
+		}
+
+		starts[ip] = true
+		ip += 1 + width
+	}
+
+	return starts, nil
+}
+
+func sameFrames(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,186 @@
+package compiler
+
+import (
+	"github.com/llir/llvm/ir"
+	"github.com/llir/llvm/ir/constant"
+	"github.com/llir/llvm/ir/enum"
+	"github.com/llir/llvm/ir/types"
+	"github.com/llir/llvm/ir/value"
+	"github.com/mantton/anthe/internal/ast"
+)
+
+/*
+Function values are closures, a pair of a code pointer and an environment pointer.
+The code takes the environment as its first parameter
+
+	(int) -> int    { i64 (i8*, i64)*, i8* }
+
+Function literals capture the locals visible where they appear by value, copied into a heap
+allocated environment. Named functions used as values are wrapped in a thunk ignoring the environment.
+*/
+
+func closureType(ret types.Type, params []types.Type) *types.StructType {
+	code := types.NewFunc(ret, append([]types.Type{types.I8Ptr}, params...)...)
+	return types.NewStruct(types.NewPointer(code), types.I8Ptr)
+}
+
+// the return and parameter types of a closure type, excluding the environment
+func closureSignature(t types.Type) (types.Type, []types.Type, bool) {
+	st, ok := t.(*types.StructType)
+	if !ok || len(st.Fields) != 2 || !st.Fields[1].Equal(types.I8Ptr) {
+		return nil, nil, false
+	}
+
+	ptr, ok := st.Fields[0].(*types.PointerType)
+	if !ok {
+		return nil, nil, false
+	}
+
+	sig, ok := ptr.ElemType.(*types.FuncType)
+	if !ok || len(sig.Params) == 0 {
+		return nil, nil, false
+	}
+
+	return sig.RetType, sig.Params[1:], true
+}
+
+func (c *Compiler) newClosure(code value.Value, env value.Value, t *types.StructType) value.Value {
+	var cl value.Value = constant.NewZeroInitializer(t)
+	cl = c.currentBlock.NewInsertValue(cl, code, 0)
+	return c.currentBlock.NewInsertValue(cl, env, 1)
+}
+
+func (c *Compiler) compileFunctionLiteral(lit *ast.FunctionLiteral, table *SymbolTable) value.Value {
+	paramTypes, retType := c.literalTypes(lit)
+	captures := []SymbolInfo{}
+	for _, local := range table.Locals(c.symbols) {
+		// named functions are globals, reachable without capturing them
+		if _, ok := local.Value.(*ir.Func); !ok {
+			captures = append(captures, local)
+		}
+	}
+
+	envFields := []types.Type{}
+	for _, capture := range captures {
+		envFields = append(envFields, capture.Type)
+	}
+	envType := types.NewStruct(envFields...)
+
+	// the environment is copied before the body is compiled, which moves the current block
+	var env value.Value = constant.NewNull(types.I8Ptr)
+	if len(captures) > 0 {
+		env = c.allocate(envType)
+		envPtr := c.currentBlock.NewBitCast(env, types.NewPointer(envType))
+
+		for i, capture := range captures {
+			field := c.currentBlock.NewGetElementPtr(envType, envPtr, constant.NewInt(types.I32, 0), constant.NewInt(types.I32, int64(i)))
+			c.currentBlock.NewStore(c.symbolValue(capture), field)
+		}
+	}
+
+	name := c.prefix + "lambda_" + c.genId()
+	envParam := ir.NewParam("env", types.I8Ptr)
+	fn, fnTable := c.newFunction(name, lit, paramTypes, retType, table)
+	fn.Params = append([]*ir.Param{envParam}, fn.Params...)
+	fn.Sig.Params = append([]types.Type{types.I8Ptr}, fn.Sig.Params...)
+
+	block := c.currentBlock
+	c.compileFunctionBody(fn, lit.Body, fnTable, func() {
+		if len(captures) == 0 {
+			return
+		}
+
+		envPtr := c.currentBlock.NewBitCast(envParam, types.NewPointer(envType))
+		for i, capture := range captures {
+			// shadowed by a parameter
+			if _, ok := fnTable.symbols[capture.Name]; ok {
+				continue
+			}
+
+			field := c.currentBlock.NewGetElementPtr(envType, envPtr, constant.NewInt(types.I32, 0), constant.NewInt(types.I32, int64(i)))
+			v := c.currentBlock.NewLoad(capture.Type, field)
+			fnTable.Add(capture.Name, SymbolInfo{Name: capture.Name, Value: v, Type: capture.Type, IsParameter: true})
+		}
+	})
+	c.currentBlock = block
+
+	return c.newClosure(fn, env, closureType(retType, paramTypes))
+}
+
+// parameter and return types of a function literal, anything not inferred is an i64
+func (c *Compiler) literalTypes(lit *ast.FunctionLiteral) ([]types.Type, types.Type) {
+	params := []types.Type{}
+	for range lit.Parameters {
+		params = append(params, types.I64)
+	}
+
+	if c.types == nil {
+		return params, types.I64
+	}
+
+	inferred, ok := c.types.TypeOf(lit)
+	fnType, isFn := inferred.(*ast.FunctionType)
+	if !ok || !isFn || len(fnType.Parameters) != len(params) {
+		return params, types.I64
+	}
+
+	for i, p := range fnType.Parameters {
+		params[i] = c.llvmType(p)
+	}
+
+	return params, c.llvmType(fnType.Return)
+}
+
+// a closure for a named function, calling it through a thunk that drops the environment
+func (c *Compiler) functionValue(fn *ir.Func) value.Value {
+	thunk, ok := c.thunks[fn]
+
+	if !ok {
+		params := []*ir.Param{ir.NewParam("env", types.I8Ptr)}
+		args := []value.Value{}
+
+		for _, p := range fn.Params {
+			param := ir.NewParam(p.Name(), p.Type())
+			params = append(params, param)
+			args = append(args, param)
+		}
+
+		// every module using an imported function as a value makes its own, the linker keeps one
+		thunk = c.module.NewFunc(fn.Name()+"__closure", fn.Sig.RetType, params...)
+		thunk.Linkage = enum.LinkageLinkOnceODR
+		entry := thunk.NewBlock("entry")
+		call := entry.NewCall(fn, args...)
+		call.Tail = enum.TailTail
+		entry.NewRet(call)
+		c.thunks[fn] = thunk
+	}
+
+	return c.newClosure(thunk, constant.NewNull(types.I8Ptr), closureType(fn.Sig.RetType, fn.Sig.Params))
+}
+
+// calls a closure value with its environment
+func (c *Compiler) compileClosureCall(expr *ast.CallExpression, cl value.Value, args []value.Value) value.Value {
+	if _, _, ok := closureSignature(cl.Type()); !ok {
+		panic("unable to call non function")
+	}
+
+	code := c.currentBlock.NewExtractValue(cl, 0)
+	env := c.currentBlock.NewExtractValue(cl, 1)
+
+	return c.emitCall(expr, code, append([]value.Value{env}, args...)...)
+}
+
+// heap allocates a value of type t, returning an i8*
+func (c *Compiler) allocate(t types.Type) value.Value {
+	if c.malloc == nil {
+		c.malloc = c.module.NewFunc("malloc", types.I8Ptr, ir.NewParam("size", types.I64))
+	}
+
+	// the size of t is the address of the second element of a t array starting at null
+	size := constant.NewPtrToInt(
+		constant.NewGetElementPtr(t, constant.NewNull(types.NewPointer(t)), constant.NewInt(types.I32, 1)),
+		types.I64,
+	)
+
+	return c.currentBlock.NewCall(c.malloc, size)
+}
@@ -2,9 +2,13 @@ package compiler
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/llir/llvm/ir"
+	"github.com/llir/llvm/ir/constant"
+	"github.com/llir/llvm/ir/types"
 	"github.com/mantton/anthe/internal/ast"
+	"github.com/mantton/anthe/internal/object"
 	gonanoid "github.com/matoous/go-nanoid/v2"
 )
 
@@ -12,27 +16,89 @@ type Compiler struct {
 	module       *ir.Module
 	symbols      *SymbolTable
 	currentBlock *ir.Block
+
+	errFlag  *ir.Global  // set while an error is propagating
+	errValue *ir.Global  // the value that was thrown
+	handlers []*ir.Block // catch blocks of the enclosing try statements in the current function, innermost last
+	inMain   bool
+
+	depth    *ir.Global // calls in progress
+	maxDepth int64
+
+	types TypeInfo // inferred types, nil when the program was not checked
+
+	generics    map[string]*genericFunction
+	instances   map[string]*ir.Func // specializations of generic functions by mangled name
+	structs     map[string]*ast.StructDeclaration
+	structTypes map[structInstance]*types.StructType // instantiations of structs by declaration
+	structDecls map[*types.StructType]*ast.StructDeclaration
+	structNames map[*ast.StructDeclaration]string // llvm names of structs, prefixed by the module declaring them
+
+	aliases  map[string]ast.TypeExpression // alias and distinct type names to the type they are represented as
+	typeDecl map[namedType]ast.Statement   // struct and type declarations by the module declaring them
+
+	thunks   map[*ir.Func]*ir.Func                      // closure wrappers of named functions
+	declared map[*ast.NamedFunctionDeclaration]*ir.Func // top level functions declared ahead of their bodies
+	malloc   *ir.Func
+
+	path       string                    // resolved path of the module being compiled, empty outside modules
+	prefix     string                    // prepended to the names of the module's functions
+	modules    map[string]*moduleExports // exports of compiled modules by resolved path, shared by all modules
+	exports    *moduleExports            // exports of the module being compiled
+	namespaces map[string]*moduleExports // modules bound by `import "path"` by name
+	imported   map[*ir.Func]*ir.Func     // declarations of imported functions in this module
 }
 
 // Create new compiler struct
 func New() (c *Compiler) {
+	m := ir.NewModule()
+
 	return &Compiler{
-		module:  ir.NewModule(),
-		symbols: NewSymbolTable(nil),
+		module:   m,
+		symbols:  NewSymbolTable(nil),
+		errFlag:  m.NewGlobalDef(PREFIX+"err_flag", constant.False),
+		errValue: m.NewGlobalDef(PREFIX+"err_value", constant.NewInt(types.I64, 0)),
+		depth:    m.NewGlobalDef(PREFIX+"call_depth", constant.NewInt(types.I64, 0)),
+		maxDepth: object.MAX_CALL_DEPTH,
+
+		generics:    make(map[string]*genericFunction),
+		instances:   make(map[string]*ir.Func),
+		structs:     make(map[string]*ast.StructDeclaration),
+		structTypes: make(map[structInstance]*types.StructType),
+		structDecls: make(map[*types.StructType]*ast.StructDeclaration),
+		structNames: make(map[*ast.StructDeclaration]string),
+		aliases:     make(map[string]ast.TypeExpression),
+		typeDecl:    make(map[namedType]ast.Statement),
+		thunks:      make(map[*ir.Func]*ir.Func),
+		declared:    make(map[*ast.NamedFunctionDeclaration]*ir.Func),
+
+		prefix:     PREFIX,
+		modules:    make(map[string]*moduleExports),
+		exports:    newModuleExports(),
+		namespaces: make(map[string]*moduleExports),
+		imported:   make(map[*ir.Func]*ir.Func),
 	}
 }
 
 // compile AST program
-func (c *Compiler) Compile(program *ast.Program) (string, error) {
+func (c *Compiler) Compile(program *ast.Program) (result string, err error) {
+	defer recoverError(&err)
 
 	fmt.Println(len(program.Statements))
+	c.declareFunctions(program.Statements, c.symbols)
+
 	for _, s := range program.Statements {
 		c.compileStatement(s, nil, c.symbols)
 	}
 
-	result := c.module.String()
+	return c.module.String(), nil
+}
 
-	return result, nil
+// turns a panic of the compiler, on a construct it cannot compile, into err
+func recoverError(err *error) {
+	if r := recover(); r != nil {
+		*err = fmt.Errorf("%s", strings.TrimSpace(fmt.Sprint(r)))
+	}
 }
 
 func (c *Compiler) genId() string {
@@ -0,0 +1,164 @@
+package compiler
+
+import (
+	"github.com/llir/llvm/ir"
+	"github.com/llir/llvm/ir/constant"
+	"github.com/llir/llvm/ir/enum"
+	"github.com/llir/llvm/ir/types"
+	"github.com/llir/llvm/ir/value"
+	"github.com/mantton/anthe/internal/ast"
+)
+
+/*
+Errors are lowered to explicit error returns.
+A throw stores the value in errValue, sets errFlag and branches to the innermost catch block,
+or returns from the function when there is none. Every call is followed by a check of errFlag
+so an error raised by the callee continues to propagate in the caller.
+*/
+
+func (c *Compiler) compileThrowStatement(node *ast.ThrowStatement, table *SymbolTable) {
+	val := c.compileExpression(node.Value, table)
+
+	c.currentBlock.NewStore(c.boxError(val), c.errValue)
+	c.currentBlock.NewStore(constant.True, c.errFlag)
+	c.propagateError()
+
+	c.startUnreachable("after_throw")
+}
+
+// errValue holds integers and booleans as they are, floats as their bits, pointers as their address and other values as the address of a heap copy
+func (c *Compiler) boxError(val value.Value) value.Value {
+	switch t := val.Type().(type) {
+	case *types.IntType:
+		if t.BitSize < 64 {
+			return c.currentBlock.NewZExt(val, types.I64)
+		}
+
+		return val
+	case *types.FloatType:
+		return c.currentBlock.NewBitCast(val, types.I64)
+	case *types.PointerType:
+		return c.currentBlock.NewPtrToInt(val, types.I64)
+	}
+
+	box := c.currentBlock.NewBitCast(c.allocate(val.Type()), types.NewPointer(val.Type()))
+	c.currentBlock.NewStore(val, box)
+	return c.currentBlock.NewPtrToInt(box, types.I64)
+}
+
+// statements following a throw or return in the same block never run, they go to a block nothing branches to
+func (c *Compiler) startUnreachable(name string) {
+	c.currentBlock = c.currentBlock.Parent.NewBlock(name + "_" + c.genId())
+}
+
+func (c *Compiler) compileTryStatement(node *ast.TryStatement, table *SymbolTable) {
+	fn := c.currentBlock.Parent
+
+	catchBlock := fn.NewBlock("catch_block_" + c.genId())
+	finallyBlock := fn.NewBlock("finally_block_" + c.genId())
+	mergeBlock := fn.NewBlock("try_merge_block_" + c.genId())
+
+	// body, errors branch to the catch block or straight to finally when there is no catch
+	if node.Catch != nil {
+		c.handlers = append(c.handlers, catchBlock)
+	} else {
+		c.handlers = append(c.handlers, finallyBlock)
+	}
+
+	c.compileStatement(node.Body, c.currentBlock, NewSymbolTable(table))
+	c.handlers = c.handlers[:len(c.handlers)-1]
+	c.branchIfOpen(finallyBlock)
+
+	// catch, clears the error and binds the thrown value, errors raised here still run finally
+	c.currentBlock = catchBlock
+	if node.Catch != nil {
+		catchTable := NewSymbolTable(table)
+		c.currentBlock.NewStore(constant.False, c.errFlag)
+
+		if node.CatchParam != nil {
+			thrown := c.currentBlock.NewLoad(types.I64, c.errValue)
+			param := c.currentBlock.NewAlloca(types.I64)
+			c.currentBlock.NewStore(thrown, param)
+			catchTable.Add(node.CatchParam.Value, SymbolInfo{Name: node.CatchParam.Value, Value: param, Type: types.I64})
+		}
+
+		c.handlers = append(c.handlers, finallyBlock)
+		c.compileStatement(node.Catch, c.currentBlock, catchTable)
+		c.handlers = c.handlers[:len(c.handlers)-1]
+	}
+	c.branchIfOpen(finallyBlock)
+
+	// finally, then continue propagating if an error is still pending
+	c.currentBlock = finallyBlock
+	if node.Finally != nil {
+		c.compileStatement(node.Finally, c.currentBlock, NewSymbolTable(table))
+	}
+	c.checkError()
+	c.branchIfOpen(mergeBlock)
+
+	c.currentBlock = mergeBlock
+}
+
+// branches to target unless the current block already ended, e.g with a return
+func (c *Compiler) branchIfOpen(target *ir.Block) {
+	if c.currentBlock.Term == nil {
+		c.currentBlock.NewBr(target)
+	}
+}
+
+// branches to a new block if no error is pending, otherwise propagates the error
+func (c *Compiler) checkError() {
+	fn := c.currentBlock.Parent
+
+	raised := fn.NewBlock("raised_" + c.genId())
+	cont := fn.NewBlock("no_error_" + c.genId())
+
+	flag := c.currentBlock.NewLoad(types.I1, c.errFlag)
+	c.currentBlock.NewCondBr(flag, raised, cont)
+
+	c.currentBlock = raised
+	c.propagateError()
+
+	c.currentBlock = cont
+}
+
+// the value thrown by a call exceeding the call depth limit
+const STACK_OVERFLOW = -1
+
+// counts the call about to be made, throwing STACK_OVERFLOW past the limit, returns the depth to restore once it returns
+func (c *Compiler) enterCall() value.Value {
+	fn := c.currentBlock.Parent
+
+	depth := c.currentBlock.NewLoad(types.I64, c.depth)
+	next := c.currentBlock.NewAdd(depth, constant.NewInt(types.I64, 1))
+	over := c.currentBlock.NewICmp(enum.IPredSGT, next, constant.NewInt(types.I64, c.maxDepth))
+
+	overflow := fn.NewBlock("overflow_" + c.genId())
+	call := fn.NewBlock("call_" + c.genId())
+	c.currentBlock.NewCondBr(over, overflow, call)
+
+	c.currentBlock = overflow
+	c.currentBlock.NewStore(constant.NewInt(types.I64, STACK_OVERFLOW), c.errValue)
+	c.currentBlock.NewStore(constant.True, c.errFlag)
+	c.propagateError()
+
+	c.currentBlock = call
+	c.currentBlock.NewStore(next, c.depth)
+	return depth
+}
+
+// branches to the innermost handler, or returns from the current function when there is none
+func (c *Compiler) propagateError() {
+	if n := len(c.handlers); n > 0 {
+		c.currentBlock.NewBr(c.handlers[n-1])
+		return
+	}
+
+	// an uncaught error exits main with a non zero status
+	if c.inMain {
+		c.currentBlock.NewRet(constant.NewInt(types.I64, 1))
+		return
+	}
+
+	c.currentBlock.NewRet(zeroValue(c.currentBlock.Parent.Sig.RetType))
+}
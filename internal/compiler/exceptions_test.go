@@ -0,0 +1,32 @@
+package compiler
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/llir/llvm/asm"
+)
+
+func TestThrowValues(t *testing.T) {
+	ir := compileFiles(t, map[string]string{
+		"main.an": `struct P { x: int };
+func f(n: int) -> int {
+	if n > 5 { throw 2.5; };
+	if n > 0 { throw P(n); let after = 1; };
+	throw true;
+	n + 1
+};
+func main() -> int { try { f(1); } catch (e) { return 2; }; return 0; };`,
+	})
+
+	if _, err := asm.ParseString("main.ll", ir); err != nil {
+		t.Fatalf("invalid ir: %s\n%s", err, ir)
+	}
+
+	// every thrown value is stored as an i64
+	for _, boxed := range []string{"bitcast double 2.5 to i64", "call i8* @malloc", "zext i1 true to i64"} {
+		if !strings.Contains(ir, boxed) {
+			t.Errorf("expected %q in\n%s", boxed, ir)
+		}
+	}
+}
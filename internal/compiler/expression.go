@@ -3,6 +3,7 @@ package compiler
 import (
 	"fmt"
 
+	"github.com/llir/llvm/ir"
 	"github.com/llir/llvm/ir/constant"
 	"github.com/llir/llvm/ir/enum"
 	"github.com/llir/llvm/ir/types"
@@ -16,14 +17,28 @@ func (c *Compiler) compileExpression(expr ast.Expression, table *SymbolTable) va
 	switch expr := expr.(type) {
 	case *ast.IntegerLiteral:
 		return constant.NewInt(types.I64, expr.Value)
+	case *ast.FloatLiteral:
+		return constant.NewFloat(types.Double, expr.Value)
+	case *ast.BooleanLiteral:
+		return constant.NewBool(expr.Value)
 	case *ast.InfixExpression:
 		return c.compileInfixExpression(expr, table)
+	case *ast.PrefixExpression:
+		return c.compilePrefixExpression(expr, table)
 	case *ast.IdentifierExpression:
 		return c.compileIdentifierExpression(expr.Value, table)
 	case *ast.CallExpression:
 		return c.compileCallExpression(expr, table)
 	case *ast.IfExpression:
 		return c.compileIfExpression(expr, table)
+	case *ast.MemberExpression:
+		return c.compileMemberExpression(expr, table)
+	case *ast.FunctionLiteral:
+		return c.compileFunctionLiteral(expr, table)
+	case *ast.TupleLiteral:
+		return c.compileTupleLiteral(expr, table)
+	case *ast.IndexExpression:
+		return c.compileIndexExpression(expr, table)
 	}
 	panic("\nexpression not implemented")
 }
@@ -46,7 +61,8 @@ func (c *Compiler) compileInfixExpression(expr *ast.InfixExpression, table *Symb
 	switch {
 	case types.IsInt(left.Type()), left.Type().Equal(types.I64Ptr):
 		return c.compileIntegerInfixExpression(left, right, operator)
-
+	case types.IsFloat(left.Type()):
+		return c.compileFloatInfixExpression(left, right, operator)
 	}
 
 	panic("\ninfix expression not implemented")
@@ -65,6 +81,8 @@ func (c *Compiler) compileIntegerInfixExpression(left, right value.Value, op str
 		return c.currentBlock.NewSDiv(left, right)
 	case "==":
 		return c.currentBlock.NewICmp(enum.IPredEQ, left, right)
+	case "!=":
+		return c.currentBlock.NewICmp(enum.IPredNE, left, right)
 	case ">=":
 		return c.currentBlock.NewICmp(enum.IPredSGE, left, right)
 	case "<=":
@@ -77,6 +95,63 @@ func (c *Compiler) compileIntegerInfixExpression(left, right value.Value, op str
 	panic("unknown operand")
 }
 
+// ordered predicates are false when either side is NaN, `!=` is unordered so NaN != NaN holds
+func (c *Compiler) compileFloatInfixExpression(left, right value.Value, op string) value.Value {
+	switch op {
+	case "+":
+		return c.currentBlock.NewFAdd(left, right)
+	case "-":
+		return c.currentBlock.NewFSub(left, right)
+	case "*":
+		return c.currentBlock.NewFMul(left, right)
+	case "/":
+		return c.currentBlock.NewFDiv(left, right)
+	case "==":
+		return c.currentBlock.NewFCmp(enum.FPredOEQ, left, right)
+	case "!=":
+		return c.currentBlock.NewFCmp(enum.FPredUNE, left, right)
+	case ">=":
+		return c.currentBlock.NewFCmp(enum.FPredOGE, left, right)
+	case "<=":
+		return c.currentBlock.NewFCmp(enum.FPredOLE, left, right)
+	case ">":
+		return c.currentBlock.NewFCmp(enum.FPredOGT, left, right)
+	case "<":
+		return c.currentBlock.NewFCmp(enum.FPredOLT, left, right)
+	}
+	panic("unknown operand")
+}
+
+func (c *Compiler) compilePrefixExpression(expr *ast.PrefixExpression, table *SymbolTable) value.Value {
+	right := c.compileExpression(expr.Right, table)
+
+	switch expr.Operator {
+	case "!":
+		return c.currentBlock.NewXor(c.compileTruthy(right), constant.True)
+	case "-":
+		if types.IsFloat(right.Type()) {
+			return c.currentBlock.NewFNeg(right)
+		}
+		return c.currentBlock.NewSub(constant.NewInt(types.I64, 0), right)
+	}
+	panic("unknown operand")
+}
+
+// converts a value to an i1 following the evaluator's truthiness table, shared by `if` and `!`
+func (c *Compiler) compileTruthy(v value.Value) value.Value {
+	switch t := v.Type().(type) {
+	case *types.IntType:
+		if t.BitSize == 1 {
+			return v
+		}
+		return c.currentBlock.NewICmp(enum.IPredNE, v, constant.NewInt(t, 0))
+	case *types.FloatType:
+		// ordered, so NaN is falsy
+		return c.currentBlock.NewFCmp(enum.FPredONE, v, constant.NewFloat(t, 0))
+	}
+	panic("value cannot be used as a condition")
+}
+
 func (c *Compiler) compileIdentifierExpression(name string, table *SymbolTable) value.Value {
 
 	v, ok := table.Lookup(name)
@@ -86,6 +161,15 @@ func (c *Compiler) compileIdentifierExpression(name string, table *SymbolTable)
 		panic("identifier not found")
 	}
 
+	return c.symbolValue(v)
+}
+
+// the value a symbol holds, functions referenced by name become closures
+func (c *Compiler) symbolValue(v SymbolInfo) value.Value {
+	if fn, ok := v.Value.(*ir.Func); ok {
+		return c.functionValue(fn)
+	}
+
 	if v.IsParameter {
 		return v.Value
 	}
@@ -94,10 +178,26 @@ func (c *Compiler) compileIdentifierExpression(name string, table *SymbolTable)
 }
 
 func (c *Compiler) compileCallExpression(expr *ast.CallExpression, table *SymbolTable) value.Value {
+	if exports, name, ok := c.namespaceMember(expr.Function); ok {
+		return c.compileNamespaceCall(exports, name, expr, table)
+	}
 
 	switch fn := expr.Function.(type) {
 	// ensure caller is an identifier
 	case *ast.IdentifierExpression:
+		if decl, ok := c.structs[fn.Value]; ok {
+			return c.compileStructConstructor(decl, expr, table)
+		}
+
+		// distinct types share the representation of their underlying type
+		if _, ok := c.aliases[fn.Value]; ok && len(expr.Arguments) == 1 {
+			return c.compileExpression(expr.Arguments[0], table)
+		}
+
+		if generic, ok := c.generics[fn.Value]; ok {
+			return c.compileGenericCall(generic, expr, table)
+		}
+
 		// lookup identifier
 		v, ok := table.Lookup(fn.Value)
 
@@ -107,23 +207,46 @@ func (c *Compiler) compileCallExpression(expr *ast.CallExpression, table *Symbol
 			panic("identifier not found")
 		}
 
+		// variables and parameters holding functions are closures
+		if _, ok := v.Value.(*ir.Func); !ok {
+			return c.compileClosureCall(expr, c.symbolValue(v), c.compileExpressionList(expr.Arguments, table))
+		}
+
 		// new call instruction
 
 		if len(expr.Arguments) == 0 {
-			res := c.currentBlock.NewCall(v.Value)
-			return res
+			return c.emitCall(expr, v.Value)
 		} else {
 			args := c.compileExpressionList(expr.Arguments, table)
 			if args == nil || len(args) != len(expr.Arguments) {
 				panic("argument count does not match parameter count")
 			}
 
-			res := c.currentBlock.NewCall(v.Value, args...)
-			return res
+			return c.emitCall(expr, v.Value, args...)
 		}
 
 	}
-	panic("unable to call non function")
+
+	callee := c.compileExpression(expr.Function, table)
+	return c.compileClosureCall(expr, callee, c.compileExpressionList(expr.Arguments, table))
+}
+
+/*
+Calls callee within the call depth limit, then checks for a raised error. Calls the parser marked as in
+tail position are emitted as `tail call`, arguments are values or heap pointers, so the callee never
+reads the caller's allocas.
+*/
+func (c *Compiler) emitCall(expr *ast.CallExpression, callee value.Value, args ...value.Value) value.Value {
+	depth := c.enterCall()
+	res := c.currentBlock.NewCall(callee, args...)
+
+	if expr.Tail {
+		res.Tail = enum.TailTail
+	}
+
+	c.currentBlock.NewStore(depth, c.depth)
+	c.checkError()
+	return res
 }
 
 func (c *Compiler) compileExpressionList(exprs []ast.Expression, table *SymbolTable) []value.Value {
@@ -139,34 +262,58 @@ func (c *Compiler) compileExpressionList(exprs []ast.Expression, table *SymbolTa
 
 }
 
+/*
+Compiles an if expression to the value of the branch taken, the trailing expression of its block.
+A branch without one, or that ends in a value of another type, yields the zero value of the first
+branch's type. Branches that return or throw do not reach the merge block.
+*/
 func (c *Compiler) compileIfExpression(expr *ast.IfExpression, table *SymbolTable) value.Value {
 
 	// Condition
-	condition := c.compileExpression(expr.Condition, table)
+	condition := c.compileTruthy(c.compileExpression(expr.Condition, table))
 
 	thenBlock := c.currentBlock.Parent.NewBlock("then_block_" + c.genId())
 	elseBlock := c.currentBlock.Parent.NewBlock("else_block_" + c.genId())
 	mergeBlock := c.currentBlock.Parent.NewBlock("merge_block_" + c.genId())
 	c.currentBlock.NewCondBr(condition, thenBlock, elseBlock)
 
-	// contents of if statement with break to merge block
-	c.currentBlock = thenBlock
-	thenBlock.NewBr(mergeBlock)
-	c.compileStatement(expr.Action, thenBlock, table)
+	// the value each branch reaches the merge block with, from the block the branch ended in
+	incoming := []*ir.Incoming{}
+	branch := func(block *ir.Block, body *ast.BlockStatement) {
+		c.currentBlock = block
 
-	// contents of else statement with break to merge block
-
-	if expr.Alternative != nil {
-		c.currentBlock = elseBlock
-		elseBlock.NewBr(mergeBlock)
-		c.compileStatement(expr.Alternative, elseBlock, table)
-	} else {
-		elseBlock.NewBr(mergeBlock)
+		var val value.Value
+		if body != nil {
+			val = c.compileBlockValue(body.Statements, block, NewSymbolTable(table))
+		}
 
+		if c.currentBlock.Term == nil {
+			incoming = append(incoming, ir.NewIncoming(val, c.currentBlock))
+			c.currentBlock.NewBr(mergeBlock)
+		}
 	}
 
+	branch(thenBlock, expr.Action)
+	branch(elseBlock, expr.Alternative)
+
 	c.currentBlock = mergeBlock
 
-	mergeBlock.NewRet(nil)
-	return nil
+	var typ types.Type
+	for _, inc := range incoming {
+		if inc.X != nil && typ == nil && !inc.X.Type().Equal(types.Void) {
+			typ = inc.X.Type()
+		}
+	}
+
+	if typ == nil || len(incoming) == 0 {
+		return nil
+	}
+
+	for _, inc := range incoming {
+		if inc.X == nil || !inc.X.Type().Equal(typ) {
+			inc.X = zeroValue(typ)
+		}
+	}
+
+	return mergeBlock.NewPhi(incoming...)
 }
@@ -0,0 +1,52 @@
+package compiler
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/llir/llvm/asm"
+	"github.com/mantton/anthe/internal/lexer"
+	"github.com/mantton/anthe/internal/parser"
+	"github.com/mantton/anthe/internal/typing"
+)
+
+func TestIfValues(t *testing.T) {
+	ir := compileFiles(t, map[string]string{
+		"main.an": `func count(n) { if n == 0 { 0 } else { count(n - 1) } };
+func sum(n) { if n == 0 { 0 } else { n + sum(n - 1) } };
+func sign(n: int) -> int { if n < 0 { return -1; }; if n == 0 { 0 } else { 1 } };
+func main() -> int { sum(3) + count(5) + sign(2) };`,
+	})
+
+	if _, err := asm.ParseString("main.ll", ir); err != nil {
+		t.Fatalf("invalid ir: %s\n%s", err, ir)
+	}
+
+	// the recursive branch of count is its result, so it is a tail call
+	for _, expected := range []string{"tail call i64 @_an__count(", "phi i64 [ 0, %then_block_"} {
+		if !strings.Contains(ir, expected) {
+			t.Errorf("expected %q in\n%s", expected, ir)
+		}
+	}
+
+	if strings.Contains(ir, "tail call i64 @_an__sum(") {
+		t.Errorf("expected the call in sum not to be a tail call\n%s", ir)
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	program := parser.New(lexer.New(`func main() -> int { let s = "a"; 0 };`, "main.an")).ParseProgram()
+
+	checker := typing.New()
+	if ok, errs := checker.CheckProgram(program); !ok {
+		t.Fatalf("type errors: %v", errs)
+	}
+
+	c := New()
+	c.UseTypes(checker)
+
+	// constructs the compiler does not support are reported rather than panicking
+	if _, err := c.Compile(program); err == nil || !strings.Contains(err.Error(), "not implemented") {
+		t.Errorf("expected an error compiling a string literal, got %v", err)
+	}
+}
@@ -0,0 +1,191 @@
+package compiler
+
+import (
+	"strings"
+
+	"github.com/llir/llvm/ir/constant"
+	"github.com/llir/llvm/ir/types"
+	"github.com/llir/llvm/ir/value"
+	"github.com/mantton/anthe/internal/ast"
+)
+
+/*
+Generics are monomorphized.
+A generic function is compiled once for every distinct list of argument types it is called with,
+`first([1])` calls `_an__first__i64`. Structs get an llvm struct type per instantiation, `Box<int>`
+is `%_an__Box__i64`.
+*/
+
+type genericFunction struct {
+	decl  *ast.NamedFunctionDeclaration
+	table *SymbolTable // table the function was declared in
+}
+
+// calls the specialization of a generic function for the argument types, compiling it on first use
+func (c *Compiler) compileGenericCall(generic *genericFunction, expr *ast.CallExpression, table *SymbolTable) value.Value {
+	args := c.compileExpressionList(expr.Arguments, table)
+
+	paramTypes := []types.Type{}
+	for _, arg := range args {
+		paramTypes = append(paramTypes, arg.Type())
+	}
+
+	name := mangle(c.prefix+generic.decl.Name, paramTypes)
+	fn, ok := c.instances[name]
+
+	if !ok {
+		retType := c.returnType(expr)
+		var fnTable *SymbolTable
+		fn, fnTable = c.newFunction(name, generic.decl.Fn, paramTypes, retType, generic.table)
+
+		// registered before the body so recursive calls find it
+		c.instances[name] = fn
+		c.compileFunctionBody(fn, generic.decl.Fn.Body, fnTable, nil)
+	}
+
+	return c.emitCall(expr, fn, args...)
+}
+
+// the llvm type of a call's result, anything not inferred is an i64
+func (c *Compiler) returnType(expr *ast.CallExpression) types.Type {
+	if c.types == nil {
+		return types.I64
+	}
+
+	inferred, ok := c.types.TypeOf(expr)
+	if !ok {
+		return types.I64
+	}
+
+	return c.llvmType(inferred)
+}
+
+// builds a struct value from the constructor arguments
+func (c *Compiler) compileStructConstructor(decl *ast.StructDeclaration, expr *ast.CallExpression, table *SymbolTable) value.Value {
+	args := c.compileExpressionList(expr.Arguments, table)
+
+	fields := []types.Type{}
+	for _, arg := range args {
+		fields = append(fields, arg.Type())
+	}
+
+	st := c.structType(decl, fields)
+
+	var agg value.Value = constant.NewZeroInitializer(st)
+	for i, arg := range args {
+		agg = c.currentBlock.NewInsertValue(agg, arg, uint64(i))
+	}
+
+	return agg
+}
+
+func (c *Compiler) compileMemberExpression(expr *ast.MemberExpression, table *SymbolTable) value.Value {
+	if exports, name, ok := c.namespaceMember(expr); ok {
+		return c.compileNamespaceMember(exports, name)
+	}
+
+	obj := c.compileExpression(expr.Object, table)
+
+	st, ok := obj.Type().(*types.StructType)
+	if !ok {
+		panic("member access on non struct value")
+	}
+
+	decl := c.structDecls[st]
+	for i, field := range decl.Fields {
+		if field.Name == expr.Property.Value {
+			return c.currentBlock.NewExtractValue(obj, uint64(i))
+		}
+	}
+
+	panic("unknown struct member " + expr.Property.Value)
+}
+
+// the llvm types of a struct's fields with its type parameters replaced by args
+func (c *Compiler) fieldTypes(decl *ast.StructDeclaration, args []ast.TypeExpression) []types.Type {
+	params := map[string]ast.TypeExpression{}
+	for i, p := range decl.TypeParameters {
+		if i < len(args) {
+			params[p] = args[i]
+		}
+	}
+
+	fields := []types.Type{}
+	for _, field := range decl.Fields {
+		t := field.Type
+		if s, ok := t.(*ast.ScopeDefinedType); ok && s.Values == nil {
+			if arg, ok := params[s.Name]; ok {
+				t = arg
+			}
+		}
+
+		// a struct holding itself can only do so behind a pointer
+		if s, ok := t.(*ast.ScopeDefinedType); ok && s.Name == decl.Name {
+			fields = append(fields, types.I64)
+			continue
+		}
+
+		fields = append(fields, c.llvmType(t))
+	}
+
+	return fields
+}
+
+// an instantiation of a struct, its mangled name tells apart the field types
+type structInstance struct {
+	decl *ast.StructDeclaration
+	name string
+}
+
+// the named llvm struct type of an instantiation, defined in the module on first use
+func (c *Compiler) structType(decl *ast.StructDeclaration, fields []types.Type) *types.StructType {
+	name := mangle(c.structNames[decl], fields)
+	key := structInstance{decl, name}
+
+	if st, ok := c.structTypes[key]; ok {
+		return st
+	}
+
+	st := types.NewStruct(fields...)
+	c.module.NewTypeDef(name, st)
+	c.structTypes[key] = st
+	c.structDecls[st] = decl
+	return st
+}
+
+// appends the llvm types to name, `first` with (i64, double) is `first__i64_f64`
+func mangle(name string, ts []types.Type) string {
+	if len(ts) == 0 {
+		return name
+	}
+
+	parts := []string{}
+	for _, t := range ts {
+		parts = append(parts, typeSuffix(t))
+	}
+
+	return name + "__" + strings.Join(parts, "_")
+}
+
+func typeSuffix(t types.Type) string {
+	switch t := t.(type) {
+	case *types.IntType:
+		return "i" + strings.TrimPrefix(t.LLString(), "i")
+	case *types.FloatType:
+		if t.Kind == types.FloatKindDouble {
+			return "f64"
+		}
+		return "f32"
+	}
+
+	if t.Name() != "" {
+		return strings.TrimPrefix(t.Name(), PREFIX)
+	}
+
+	// closures, `{ i64 (i8*, i64)*, i8* }` is `fn_i64_i64`
+	if ret, params, ok := closureSignature(t); ok {
+		return mangle("fn_"+typeSuffix(ret), params)
+	}
+
+	return strings.NewReplacer("%", "", " ", "", "{", "", "}", "", ",", "_").Replace(t.LLString())
+}
@@ -0,0 +1,237 @@
+package compiler
+
+import (
+	"fmt"
+
+	"github.com/llir/llvm/ir"
+	"github.com/llir/llvm/ir/enum"
+	"github.com/llir/llvm/ir/value"
+	"github.com/mantton/anthe/internal/ast"
+	"github.com/mantton/anthe/internal/module"
+)
+
+// declarations a module makes visible to its importers
+type moduleExports struct {
+	funcs    map[string]*ir.Func
+	generics map[string]*genericFunction
+	structs  map[string]*ast.StructDeclaration
+	aliases  map[string]ast.TypeExpression
+}
+
+func newModuleExports() *moduleExports {
+	return &moduleExports{
+		funcs:    make(map[string]*ir.Func),
+		generics: make(map[string]*genericFunction),
+		structs:  make(map[string]*ast.StructDeclaration),
+		aliases:  make(map[string]ast.TypeExpression),
+	}
+}
+
+/*
+Compiles each module, in dependency order, into its own llvm module and links them into one.
+Functions of imported modules are prefixed with the module's symbol, `area` of lib/geometry is
+`_an__3lib8geometry__area`, and declared in the modules importing them. The entry module, last, keeps
+the plain prefix and defines main. Calls nested deeper than maxDepth raise STACK_OVERFLOW.
+*/
+func CompileModules(modules []*module.Module, info TypeInfo, maxDepth int) (result string, err error) {
+	defer recoverError(&err)
+
+	compiled := []*ir.Module{}
+	shared := New()
+
+	for i, m := range modules {
+		c := New()
+		c.UseTypes(info)
+		c.maxDepth = int64(maxDepth)
+
+		// struct types are defined once and referred to by name from every module
+		c.modules, c.structTypes, c.structDecls = shared.modules, shared.structTypes, shared.structDecls
+		c.structNames, c.typeDecl = shared.structNames, shared.typeDecl
+		c.path = m.Path
+
+		if i != len(modules)-1 {
+			c.prefix = PREFIX + m.Symbol + "__"
+		}
+
+		c.declareFunctions(m.Program.Statements, c.symbols)
+
+		for _, s := range m.Program.Statements {
+			c.compileStatement(s, nil, c.symbols)
+		}
+
+		c.modules[m.Path] = c.exports
+		compiled = append(compiled, c.module)
+	}
+
+	linked, err := Link(compiled)
+
+	if err != nil {
+		return "", err
+	}
+
+	return linked.String(), nil
+}
+
+/*
+Merges modules into one, dropping declarations of defined functions.
+A function defined by more than one module is an error unless it is linkonce_odr, like the closure
+thunks every importing module makes of a function, then the first definition is kept.
+*/
+func Link(modules []*ir.Module) (*ir.Module, error) {
+	linked := ir.NewModule()
+
+	defined := map[string]bool{}
+	for _, m := range modules {
+		for _, f := range m.Funcs {
+			if len(f.Blocks) == 0 {
+				continue
+			}
+
+			if defined[f.Name()] && f.Linkage != enum.LinkageLinkOnceODR {
+				return nil, fmt.Errorf("duplicate definition of `%s`", f.Name())
+			}
+
+			defined[f.Name()] = true
+		}
+	}
+
+	seen := map[string]bool{}
+	for _, m := range modules {
+		for _, t := range m.TypeDefs {
+			if !seen["%"+t.Name()] {
+				seen["%"+t.Name()] = true
+				linked.TypeDefs = append(linked.TypeDefs, t)
+			}
+		}
+
+		for _, g := range m.Globals {
+			if !seen[g.Ident()] {
+				seen[g.Ident()] = true
+				linked.Globals = append(linked.Globals, g)
+			}
+		}
+
+		for _, f := range m.Funcs {
+			if seen[f.Ident()] || (len(f.Blocks) == 0 && defined[f.Name()]) {
+				continue
+			}
+
+			seen[f.Ident()] = true
+			linked.Funcs = append(linked.Funcs, f)
+		}
+	}
+
+	return linked, nil
+}
+
+func (c *Compiler) compileImportStatement(node *ast.ImportStatement, table *SymbolTable) {
+	exports, ok := c.modules[node.Resolved]
+
+	if !ok {
+		panic("module not compiled " + node.Path)
+	}
+
+	if node.Names == nil {
+		c.namespaces[node.Name] = exports
+		return
+	}
+
+	for _, ident := range node.Names {
+		name := ident.Value
+
+		switch {
+		case exports.funcs[name] != nil:
+			fn := c.declareImported(exports.funcs[name])
+			table.Add(name, SymbolInfo{Name: name, Value: fn, Type: fn.Type()})
+		case exports.generics[name] != nil:
+			c.generics[name] = exports.generics[name]
+		case exports.structs[name] != nil:
+			c.structs[name] = exports.structs[name]
+		case exports.aliases[name] != nil:
+			c.aliases[name] = exports.aliases[name]
+		}
+	}
+}
+
+func (c *Compiler) compileExportStatement(node *ast.ExportStatement, block *ir.Block, table *SymbolTable) {
+	c.compileStatement(node.Statement, block, table)
+
+	for _, name := range node.Names() {
+		if v, ok := table.Lookup(name); ok {
+			if fn, ok := v.Value.(*ir.Func); ok {
+				c.exports.funcs[name] = fn
+			}
+		}
+
+		if generic, ok := c.generics[name]; ok {
+			c.exports.generics[name] = generic
+		}
+
+		if decl, ok := c.structs[name]; ok {
+			c.exports.structs[name] = decl
+		}
+
+		if alias, ok := c.aliases[name]; ok {
+			c.exports.aliases[name] = alias
+		}
+	}
+}
+
+// declares a function defined in another module, it is resolved when the modules are linked
+func (c *Compiler) declareImported(fn *ir.Func) *ir.Func {
+	if decl, ok := c.imported[fn]; ok {
+		return decl
+	}
+
+	params := []*ir.Param{}
+	for _, p := range fn.Params {
+		params = append(params, ir.NewParam(p.Name(), p.Type()))
+	}
+
+	decl := c.module.NewFunc(fn.Name(), fn.Sig.RetType, params...)
+	c.imported[fn] = decl
+	return decl
+}
+
+// `geometry.area` where geometry is bound by `import "lib/geometry"`
+func (c *Compiler) namespaceMember(expr ast.Expression) (*moduleExports, string, bool) {
+	member, ok := expr.(*ast.MemberExpression)
+
+	if !ok {
+		return nil, "", false
+	}
+
+	ident, ok := member.Object.(*ast.IdentifierExpression)
+
+	if !ok {
+		return nil, "", false
+	}
+
+	exports, ok := c.namespaces[ident.Value]
+	return exports, member.Property.Value, ok
+}
+
+// calls an export of a module bound by name, `geometry.area(2)`
+func (c *Compiler) compileNamespaceCall(exports *moduleExports, name string, expr *ast.CallExpression, table *SymbolTable) value.Value {
+	switch {
+	case exports.funcs[name] != nil:
+		return c.emitCall(expr, c.declareImported(exports.funcs[name]), c.compileExpressionList(expr.Arguments, table)...)
+	case exports.generics[name] != nil:
+		return c.compileGenericCall(exports.generics[name], expr, table)
+	case exports.structs[name] != nil:
+		return c.compileStructConstructor(exports.structs[name], expr, table)
+	}
+
+	panic("module does not export " + name)
+}
+
+// an exported function used as a value
+func (c *Compiler) compileNamespaceMember(exports *moduleExports, name string) value.Value {
+	fn, ok := exports.funcs[name]
+
+	if !ok {
+		panic("module does not export " + name)
+	}
+
+	return c.functionValue(c.declareImported(fn))
+}
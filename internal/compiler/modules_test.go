@@ -0,0 +1,100 @@
+package compiler
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/llir/llvm/ir"
+	"github.com/llir/llvm/ir/types"
+	"github.com/mantton/anthe/internal/module"
+	"github.com/mantton/anthe/internal/typing"
+)
+
+// checks and compiles the modules of files, entry is main.an
+func compileFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+
+	for name, src := range files {
+		path := filepath.Join(dir, name)
+
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	modules, err := module.NewResolver(nil).Load(filepath.Join(dir, "main.an"))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checker := typing.New()
+
+	if ok, errs := checker.CheckModules(modules); !ok {
+		t.Fatalf("type errors: %v", errs)
+	}
+
+	ir, err := CompileModules(modules, checker, 100)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return ir
+}
+
+func TestModuleSymbols(t *testing.T) {
+	ir := compileFiles(t, map[string]string{
+		"main.an":         `import { area } from "lib/geometry"; import { sum } from "./lib_geometry"; func main() -> int { area(2, 3) + sum(2, 3) };`,
+		"lib/geometry.an": `export func area(w: int, h: int) -> int { w * h }`,
+		"lib_geometry.an": `export func sum(w: int, h: int) -> int { w + h }`,
+	})
+
+	for _, fn := range []string{"@_an__3lib8geometry__area(", "@_an__12lib_geometry__sum("} {
+		if !strings.Contains(ir, "define i64 "+fn) {
+			t.Errorf("expected a definition of %s in\n%s", fn, ir)
+		}
+	}
+}
+
+func TestModuleStructs(t *testing.T) {
+	ir := compileFiles(t, map[string]string{
+		"main.an":     `import { origin } from "./geometry"; struct P { a: int, b: int }; func main() -> int { let p = P(1, 2); origin().x + p.b };`,
+		"geometry.an": `struct P { value: int }; export struct Point { x: int, y: int }; export func origin() -> Point { Point(P(0).value, 0) }`,
+	})
+
+	for _, def := range []string{
+		"%_an__P__i64_i64 = type { i64, i64 }",
+		"%_an__8geometry__P__i64 = type { i64 }",
+		"define %_an__8geometry__Point__i64_i64 @_an__8geometry__origin()",
+	} {
+		if !strings.Contains(ir, def) {
+			t.Errorf("expected %s in\n%s", def, ir)
+		}
+	}
+
+	if !strings.Contains(ir, "extractvalue %_an__8geometry__Point__i64_i64") {
+		t.Errorf("expected the field of the returned struct to be read in\n%s", ir)
+	}
+}
+
+func TestLinkDuplicates(t *testing.T) {
+	modules := []*ir.Module{}
+
+	for i := 0; i < 2; i++ {
+		m := ir.NewModule()
+		m.NewFunc("_an__area", types.I64).NewBlock("entry").NewUnreachable()
+		modules = append(modules, m)
+	}
+
+	if _, err := Link(modules); err == nil || !strings.Contains(err.Error(), "duplicate definition of `_an__area`") {
+		t.Errorf("expected a duplicate definition error, got %v", err)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"github.com/llir/llvm/ir"
 	"github.com/llir/llvm/ir/constant"
 	"github.com/llir/llvm/ir/types"
+	"github.com/llir/llvm/ir/value"
 	"github.com/mantton/anthe/internal/ast"
 )
 
@@ -17,65 +18,165 @@ func (c *Compiler) compileStatement(node ast.Statement, block *ir.Block, table *
 	switch node := node.(type) {
 	case *ast.NamedFunctionDeclaration:
 		c.compileNamedFunctionDeclaration(node, block, table)
+	case *ast.StructDeclaration, *ast.TypeDeclaration:
+		c.declareType(node)
 	case *ast.LetStatement:
 		c.compileLetStatement(node, table)
+	case *ast.DestructuringStatement:
+		c.compileDestructuringStatement(node, table)
+	case *ast.ImportStatement:
+		c.compileImportStatement(node, table)
+	case *ast.ExportStatement:
+		c.compileExportStatement(node, block, table)
 	case *ast.ExpressionStatement:
 		c.compileExpression(node.Expression, table)
 	case *ast.BlockStatement:
 		c.compileBlockStatement(node, block, table)
 	case *ast.ReturnStatement:
 		c.compileReturnStatement(node, table)
+	case *ast.ThrowStatement:
+		c.compileThrowStatement(node, table)
+	case *ast.TryStatement:
+		c.compileTryStatement(node, table)
 	default:
 		panic("\nstatement compilation not implemented")
 	}
 }
 
 func (c *Compiler) compileNamedFunctionDeclaration(node *ast.NamedFunctionDeclaration, block *ir.Block, table *SymbolTable) {
+	// generic functions are compiled once per set of argument types, when called
+	if len(node.TypeParameters) > 0 {
+		c.generics[node.Name] = &genericFunction{decl: node, table: table}
+		return
+	}
+
 	isMain := node.Name == "main"
 	// TODO: package check too.
+
+	// try statements do not extend across function boundaries
+	handlers, inMain := c.handlers, c.inMain
+	c.handlers, c.inMain = nil, isMain
+	defer func() { c.handlers, c.inMain = handlers, inMain }()
+
 	if isMain {
 		fn := c.module.NewFunc("main", types.I64)
 		entryBlock := fn.NewBlock("entry")
 		c.currentBlock = entryBlock
+		mainTable := NewSymbolTable(table)
 
-		for _, s := range node.Fn.Body.Statements {
-			c.compileStatement(s, entryBlock, table)
-		}
+		last := c.compileBlockValue(node.Fn.Body.Statements, entryBlock, mainTable)
+
+		if c.currentBlock.Term == nil {
+			if last == nil || !last.Type().Equal(types.I64) {
+				last = constant.NewInt(types.I64, 0)
+			}
 
-		if c.currentBlock == entryBlock {
-			entryBlock.NewRet(constant.NewInt(types.I64, 0))
+			c.currentBlock.NewRet(last)
 		}
 
 	} else {
+		fn, ok := c.declared[node]
+
+		if !ok {
+			fn = c.declareFunction(node, table)
+		}
 
-		name := PREFIX + node.Name
 		fnTable := NewSymbolTable(table)
-		fnParams := []*ir.Param{}
-
-		if node.Fn.Parameters != nil {
-			for _, param := range node.Fn.Parameters {
-				// allocate new param
-				p := ir.NewParam(param.Value, types.I64)
-				fnTable.Add(param.Value, SymbolInfo{Name: param.Value, Value: p, Type: p.Type(), IsParameter: true})
-				fnParams = append(fnParams, p)
-			}
+		for _, p := range fn.Params {
+			fnTable.Add(p.Name(), SymbolInfo{Name: p.Name(), Value: p, Type: p.Type(), IsParameter: true})
 		}
 
-		fn := c.module.NewFunc(name, types.I64, fnParams...)
-		table.Add(node.Name, SymbolInfo{Name: node.Name, Value: fn, Type: fn.Type()})
+		c.compileFunctionBody(fn, node.Fn.Body, fnTable, nil)
+	}
 
-		entryBlock := fn.NewBlock("entry")
-		c.currentBlock = entryBlock
+}
+
+// declares the plain functions of the top level first, so they can call each other regardless of order
+func (c *Compiler) declareFunctions(nodes []ast.Statement, table *SymbolTable) {
+	for _, s := range nodes {
+		if export, ok := s.(*ast.ExportStatement); ok {
+			s = export.Statement
+		}
+
+		// types first, the signatures of functions may refer to them
+		switch node := s.(type) {
+		case *ast.StructDeclaration, *ast.TypeDeclaration:
+			c.declareType(node)
+		}
+	}
 
-		for _, s := range node.Fn.Body.Statements {
-			c.compileStatement(s, entryBlock, fnTable)
+	for _, s := range nodes {
+		if export, ok := s.(*ast.ExportStatement); ok {
+			s = export.Statement
 		}
 
-		if c.currentBlock == entryBlock {
-			entryBlock.NewRet(constant.NewInt(types.I64, 0))
+		if node, ok := s.(*ast.NamedFunctionDeclaration); ok && len(node.TypeParameters) == 0 && node.Name != "main" {
+			c.declared[node] = c.declareFunction(node, table)
 		}
 	}
+}
 
+// binds a struct or type declaration to its name in the module, and to the module for the types the checker inferred
+func (c *Compiler) declareType(node ast.Statement) {
+	switch node := node.(type) {
+	case *ast.StructDeclaration:
+		c.structs[node.Name] = node
+		c.structNames[node] = c.prefix + node.Name
+		c.typeDecl[namedType{c.path, node.Name}] = node
+	case *ast.TypeDeclaration:
+		c.aliases[node.Name] = node.Value
+		c.typeDecl[namedType{c.path, node.Name}] = node
+	}
+}
+
+// the llvm function of a named declaration without a body, bound to its name in table
+func (c *Compiler) declareFunction(node *ast.NamedFunctionDeclaration, table *SymbolTable) *ir.Func {
+	paramTypes, retType := c.functionTypes(node)
+	fn, _ := c.newFunction(c.prefix+node.Name, node.Fn, paramTypes, retType, table)
+	table.Add(node.Name, SymbolInfo{Name: node.Name, Value: fn, Type: fn.Type()})
+	return fn
+}
+
+// declares a function with the given llvm signature, returning it and a table holding its parameters
+func (c *Compiler) newFunction(name string, lit *ast.FunctionLiteral, paramTypes []types.Type, retType types.Type, table *SymbolTable) (*ir.Func, *SymbolTable) {
+	fnTable := NewSymbolTable(table)
+	fnParams := []*ir.Param{}
+
+	for i, param := range lit.Parameters {
+		// allocate new param
+		p := ir.NewParam(param.Value, paramTypes[i])
+		fnTable.Add(param.Value, SymbolInfo{Name: param.Value, Value: p, Type: p.Type(), IsParameter: true})
+		fnParams = append(fnParams, p)
+	}
+
+	return c.module.NewFunc(name, retType, fnParams...), fnTable
+}
+
+// compiles body into fn, the block being compiled when it was reached is restored afterwards
+// prologue, if set, runs in the entry block before the body
+func (c *Compiler) compileFunctionBody(fn *ir.Func, body *ast.BlockStatement, table *SymbolTable, prologue func()) {
+	current := c.currentBlock
+	handlers, inMain := c.handlers, c.inMain
+	c.handlers, c.inMain = nil, false
+	defer func() { c.currentBlock, c.handlers, c.inMain = current, handlers, inMain }()
+
+	entryBlock := fn.NewBlock("entry")
+	c.currentBlock = entryBlock
+
+	if prologue != nil {
+		prologue()
+	}
+
+	// a trailing expression is returned implicitly
+	last := c.compileBlockValue(body.Statements, entryBlock, table)
+
+	if c.currentBlock.Term == nil {
+		if last == nil || !last.Type().Equal(fn.Sig.RetType) {
+			last = zeroValue(fn.Sig.RetType)
+		}
+
+		c.currentBlock.NewRet(last)
+	}
 }
 
 func (c *Compiler) compileLetStatement(node *ast.LetStatement, table *SymbolTable) {
@@ -95,8 +196,25 @@ func (c *Compiler) compileLetStatement(node *ast.LetStatement, table *SymbolTabl
 	table.Add(node.Name.Value, SymbolInfo{Name: node.Name.Value, Value: val, Type: rhs.Type()})
 }
 
+// a block declares its names in a table of its own
 func (c *Compiler) compileBlockStatement(node *ast.BlockStatement, block *ir.Block, table *SymbolTable) {
-	c.compileStatements(node.Statements, block, table)
+	c.compileStatements(node.Statements, block, NewSymbolTable(table))
+}
+
+// compiles nodes, returning the value of the trailing expression, nil if they do not end in one
+func (c *Compiler) compileBlockValue(nodes []ast.Statement, block *ir.Block, table *SymbolTable) value.Value {
+	var last value.Value
+
+	for i, s := range nodes {
+		if expr, ok := s.(*ast.ExpressionStatement); ok && i == len(nodes)-1 {
+			last = c.compileExpression(expr.Expression, table)
+			continue
+		}
+
+		c.compileStatement(s, block, table)
+	}
+
+	return last
 }
 
 func (c *Compiler) compileStatements(nodes []ast.Statement, block *ir.Block, table *SymbolTable) {
@@ -111,4 +229,5 @@ func (c *Compiler) compileReturnStatement(node *ast.ReturnStatement, table *Symb
 	val := c.compileExpression(node.ReturnValue, table)
 
 	c.currentBlock.NewRet(val)
+	c.startUnreachable("after_return")
 }
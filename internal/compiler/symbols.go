@@ -1,6 +1,9 @@
 package compiler
 
 import (
+	"fmt"
+	"sort"
+
 	"github.com/llir/llvm/ir/types"
 	"github.com/llir/llvm/ir/value"
 )
@@ -24,7 +27,12 @@ func NewSymbolTable(parent *SymbolTable) *SymbolTable {
 	}
 }
 
+// a name is added once per table, nested tables may shadow it
 func (s *SymbolTable) Add(name string, info SymbolInfo) {
+	if _, ok := s.symbols[name]; ok {
+		panic(fmt.Sprintf("`%s` is already defined", name))
+	}
+
 	s.symbols[name] = info
 }
 
@@ -35,3 +43,28 @@ func (s *SymbolTable) Lookup(name string) (SymbolInfo, bool) {
 	}
 	return info, exists
 }
+
+// symbols declared in s and its parents up to, but not including, stop, inner declarations shadow outer ones
+func (s *SymbolTable) Locals(stop *SymbolTable) []SymbolInfo {
+	seen := map[string]bool{}
+	locals := []SymbolInfo{}
+
+	for table := s; table != nil && table != stop; table = table.parent {
+		names := make([]string, 0, len(table.symbols))
+		for name := range table.symbols {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			if seen[name] {
+				continue
+			}
+
+			seen[name] = true
+			locals = append(locals, table.symbols[name])
+		}
+	}
+
+	return locals
+}
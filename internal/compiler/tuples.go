@@ -0,0 +1,74 @@
+package compiler
+
+import (
+	"github.com/llir/llvm/ir/constant"
+	"github.com/llir/llvm/ir/types"
+	"github.com/llir/llvm/ir/value"
+	"github.com/mantton/anthe/internal/ast"
+)
+
+// tuples are anonymous structs of their element types
+func (c *Compiler) compileTupleLiteral(expr *ast.TupleLiteral, table *SymbolTable) value.Value {
+	elems := c.compileExpressionList(expr.Elements, table)
+
+	fields := []types.Type{}
+	for _, el := range elems {
+		fields = append(fields, el.Type())
+	}
+
+	var agg value.Value = constant.NewZeroInitializer(types.NewStruct(fields...))
+	for i, el := range elems {
+		agg = c.currentBlock.NewInsertValue(agg, el, uint64(i))
+	}
+
+	return agg
+}
+
+// only tuples can be indexed, with an integer literal checked against their arity
+func (c *Compiler) compileIndexExpression(expr *ast.IndexExpression, table *SymbolTable) value.Value {
+	left := c.compileExpression(expr.Left, table)
+
+	st, ok := left.Type().(*types.StructType)
+	if !ok {
+		panic("index expression on non tuple value")
+	}
+
+	lit, ok := expr.Index.(*ast.IntegerLiteral)
+	if !ok || lit.Value < 0 || int(lit.Value) >= len(st.Fields) {
+		panic("tuples must be indexed with an integer literal in range")
+	}
+
+	return c.currentBlock.NewExtractValue(left, uint64(lit.Value))
+}
+
+// binds each name to an element of a tuple or a field of a struct
+func (c *Compiler) compileDestructuringStatement(node *ast.DestructuringStatement, table *SymbolTable) {
+	rhs := c.compileExpression(node.Value, table)
+
+	st, ok := rhs.Type().(*types.StructType)
+	if !ok || node.Kind == ast.ArrayPattern {
+		panic("destructuring is only supported for tuples and structs")
+	}
+
+	for i, name := range node.Names {
+		index := i
+
+		if node.Kind == ast.ObjectPattern {
+			index = -1
+			for j, field := range c.structDecls[st].Fields {
+				if field.Name == name.Value {
+					index = j
+				}
+			}
+
+			if index < 0 {
+				panic("unknown struct member " + name.Value)
+			}
+		}
+
+		el := c.currentBlock.NewExtractValue(rhs, uint64(index))
+		val := c.currentBlock.NewAlloca(el.Type())
+		c.currentBlock.NewStore(el, val)
+		table.Add(name.Value, SymbolInfo{Name: name.Value, Value: val, Type: el.Type()})
+	}
+}
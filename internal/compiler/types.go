@@ -0,0 +1,116 @@
+package compiler
+
+import (
+	"github.com/llir/llvm/ir/constant"
+	"github.com/llir/llvm/ir/types"
+	"github.com/llir/llvm/ir/value"
+	"github.com/mantton/anthe/internal/ast"
+)
+
+// inferred types of checked nodes, implemented by typing.TypeChecker
+type TypeInfo interface {
+	TypeOf(node ast.Node) (ast.TypeExpression, bool)
+}
+
+// use the types inferred by the checker to pick llvm types
+func (c *Compiler) UseTypes(info TypeInfo) {
+	c.types = info
+}
+
+// parameter and return types of a function, anything not inferred is an i64
+func (c *Compiler) functionTypes(node *ast.NamedFunctionDeclaration) ([]types.Type, types.Type) {
+	params := []types.Type{}
+	for range node.Fn.Parameters {
+		params = append(params, types.I64)
+	}
+
+	if c.types == nil {
+		return params, types.I64
+	}
+
+	inferred, ok := c.types.TypeOf(node)
+	if !ok {
+		return params, types.I64
+	}
+
+	fnType, ok := inferred.(*ast.FunctionType)
+	if !ok || len(fnType.Parameters) != len(params) {
+		return params, types.I64
+	}
+
+	for i, p := range fnType.Parameters {
+		params[i] = c.llvmType(p)
+	}
+
+	return params, c.llvmType(fnType.Return)
+}
+
+func (c *Compiler) llvmType(t ast.TypeExpression) types.Type {
+	switch t := t.(type) {
+	case *ast.LiteralFloatType:
+		return types.Double
+	case *ast.LiteralBooleanType:
+		return types.I1
+	case *ast.FunctionType:
+		params := []types.Type{}
+		for _, p := range t.Parameters {
+			params = append(params, c.llvmType(p))
+		}
+
+		return closureType(c.llvmType(t.Return), params)
+	case *ast.TupleType:
+		fields := []types.Type{}
+		for _, el := range t.Elements {
+			fields = append(fields, c.llvmType(el))
+		}
+
+		return types.NewStruct(fields...)
+	case *ast.ScopeDefinedType:
+		switch decl := c.namedType(t).(type) {
+		case *ast.TypeDeclaration:
+			return c.llvmType(decl.Value)
+		case *ast.StructDeclaration:
+			return c.structType(decl, c.fieldTypes(decl, t.Values))
+		}
+	}
+
+	return types.I64
+}
+
+// a struct or type declared in a module, named by the checker or by an annotation
+type namedType struct {
+	module string
+	name   string
+}
+
+/*
+The declaration of a named type. Inferred types name the module declaring them, so values of types
+a module did not import, returned by the functions it did, are found all the same. Annotations are
+resolved by name in the module being compiled.
+*/
+func (c *Compiler) namedType(t *ast.ScopeDefinedType) ast.Statement {
+	if decl, ok := c.typeDecl[namedType{t.Module, t.Name}]; ok {
+		return decl
+	}
+
+	if decl, ok := c.structs[t.Name]; ok {
+		return decl
+	}
+
+	if underlying, ok := c.aliases[t.Name]; ok {
+		return &ast.TypeDeclaration{Name: t.Name, Value: underlying}
+	}
+
+	return nil
+}
+
+func zeroValue(t types.Type) value.Value {
+	switch t := t.(type) {
+	case *types.IntType:
+		return constant.NewInt(t, 0)
+	case *types.FloatType:
+		return constant.NewFloat(t, 0)
+	}
+
+	return constant.NewZeroInitializer(t)
+}
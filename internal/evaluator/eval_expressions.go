@@ -8,6 +8,7 @@ import (
 	"github.com/mantton/anthe/internal/builtins"
 	"github.com/mantton/anthe/internal/object"
 	"github.com/mantton/anthe/internal/scope"
+	"github.com/mantton/anthe/internal/token"
 )
 
 func (e *Evaluator) evaluateExpression(node ast.Expression, scope *scope.Scope) (object.Object, error) {
@@ -27,7 +28,12 @@ func (e *Evaluator) evaluateExpression(node ast.Expression, scope *scope.Scope)
 		if err != nil {
 			return nil, err
 		}
-		return e.applyFunction(function, args)
+
+		if node.Tail {
+			return &tailCall{fn: function, args: args, node: node}, nil
+		}
+
+		return e.applyFunction(function, args, node.Function.Pos())
 
 	case *ast.IndexExpression:
 		left, err := e.eval(node.Left, scope)
@@ -35,6 +41,10 @@ func (e *Evaluator) evaluateExpression(node ast.Expression, scope *scope.Scope)
 			return nil, err
 		}
 
+		if node.Optional && left == builtins.NULL {
+			return builtins.NULL, nil
+		}
+
 		index, err := e.eval(node.Index, scope)
 
 		if err != nil {
@@ -50,6 +60,8 @@ func (e *Evaluator) evaluateExpression(node ast.Expression, scope *scope.Scope)
 		}
 		return e.evalPrefixExpression(node.Operator, rhs)
 
+	case *ast.MemberExpression:
+		return e.evalMemberExpression(node, scope)
 	case *ast.InfixExpression:
 		lhs, err := e.eval(node.Left, scope)
 
@@ -57,6 +69,15 @@ func (e *Evaluator) evaluateExpression(node ast.Expression, scope *scope.Scope)
 			return nil, err
 		}
 
+		// coalescing only evaluates the right hand side when needed
+		if node.Operator == "??" {
+			if lhs != builtins.NULL {
+				return lhs, nil
+			}
+
+			return e.eval(node.Right, scope)
+		}
+
 		rhs, err := e.eval(node.Right, scope)
 
 		if err != nil {
@@ -66,6 +87,10 @@ func (e *Evaluator) evaluateExpression(node ast.Expression, scope *scope.Scope)
 		return e.evalInfixExpression(node.Operator, lhs, rhs)
 	case *ast.AssignmentExpression:
 		return e.evalAssignmentExpression(node, scope)
+	case *ast.ElementAssignmentExpression:
+		return e.evalElementAssignmentExpression(node, scope)
+	case *ast.PropagateExpression:
+		return e.evalPropagateExpression(node, scope)
 	}
 	return nil, fmt.Errorf("unknown expression %T", node)
 }
@@ -91,6 +116,13 @@ func (e *Evaluator) evalExpressionList(
 
 // IDENTIFIERS
 func (e *Evaluator) evalIdentifier(node *ast.IdentifierExpression, s *scope.Scope) (object.Object, error) {
+	if loc := node.Location; loc.Local {
+		if val := s.Load(loc.Depth, loc.Slot); val != nil {
+			return val, nil
+		}
+
+		return nil, fmt.Errorf("`%s` is used before its declaration", node.Value)
+	}
 
 	val, err := s.Get(node.Value) //  try and get withing current scope, e.g parameter or decl
 
@@ -120,7 +152,11 @@ func (e *Evaluator) evalIfExpression(
 		return nil, err
 	}
 
-	valTruthy := isTruthy(condition)
+	valTruthy, err := e.isTruthy(condition)
+
+	if err != nil {
+		return nil, err
+	}
 
 	if valTruthy {
 		return e.eval(ie.Action, s)
@@ -131,32 +167,33 @@ func (e *Evaluator) evalIfExpression(
 	}
 }
 
-func isTruthy(obj object.Object) bool {
-	switch obj {
-	case builtins.NULL:
-		return false
-	case builtins.VOID:
-		return false
-	case builtins.TRUE:
-		return true
-	case builtins.FALSE:
-		return false
+// Truthiness shared by conditions and `!`, a struct's `truthy` member takes precedence over the default table
+func (e *Evaluator) isTruthy(obj object.Object) (bool, error) {
+	if s, ok := obj.(*object.Structure); ok {
+		if fn, ok := s.Members["truthy"]; ok {
+			result, err := e.applyFunction(fn, []object.Object{obj}, token.Position{})
 
-	}
+			if err != nil {
+				return false, err
+			}
 
-	switch obj := obj.(type) {
-	case *object.Integer:
-		return obj.Value == 0
+			b, ok := result.(*object.Boolean)
+			if !ok {
+				return false, fmt.Errorf("`truthy` must return a boolean, got %s", result.Type())
+			}
+
+			return b.Value, nil
+		}
 	}
 
-	return true
+	return object.IsTruthy(obj), nil
 }
 
 // Operand Prefix Operation
 func (e *Evaluator) evalPrefixExpression(operator string, right object.Object) (object.Object, error) {
 	switch operator {
 	case "!":
-		return e.evalNextOperatorExpression(right), nil
+		return e.evalNotOperatorExpression(right)
 	case "-":
 		return e.evalNegatePrefixOperatorExpression(right)
 	default:
@@ -165,28 +202,26 @@ func (e *Evaluator) evalPrefixExpression(operator string, right object.Object) (
 }
 
 // NOT Operator
-func (e *Evaluator) evalNextOperatorExpression(right object.Object) object.Object {
-	// TODO: Call isTruthy Protocol Method
-	switch right {
-	case builtins.TRUE:
-		return builtins.FALSE
-	case builtins.FALSE:
-		return builtins.TRUE
-	case builtins.NULL:
-		return builtins.TRUE
-	default:
-		return builtins.FALSE
+func (e *Evaluator) evalNotOperatorExpression(right object.Object) (object.Object, error) {
+	truthy, err := e.isTruthy(right)
+
+	if err != nil {
+		return nil, err
 	}
+
+	return e.nativeBoolToBooleanObject(!truthy), nil
 }
 
 // NEGATE Operator
 func (e *Evaluator) evalNegatePrefixOperatorExpression(right object.Object) (object.Object, error) {
-	if right.Type() != object.INTEGER {
-		return nil, errors.New("object most conform to `numeric` protocol")
+	switch right := right.(type) {
+	case *object.Integer:
+		return &object.Integer{Value: -right.Value}, nil
+	case *object.Float:
+		return &object.Float{Value: -right.Value}, nil
 	}
 
-	value := right.(*object.Integer).Value
-	return &object.Integer{Value: -value}, nil
+	return nil, errors.New("object most conform to `numeric` protocol")
 }
 
 func (e *Evaluator) evalInfixExpression(
@@ -199,10 +234,17 @@ func (e *Evaluator) evalInfixExpression(
 	switch {
 	case left.Type() == object.INTEGER && right.Type() == object.INTEGER:
 		return e.evalIntegerInfixExpression(operator, left, right)
-	case operator == "==":
-		return e.nativeBoolToBooleanObject(left == right), nil
-	case operator == "!=":
-		return e.nativeBoolToBooleanObject(left != right), nil
+	case operator == "==" || operator == "!=":
+		equal, err := e.equals(left, right)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return e.nativeBoolToBooleanObject(equal == (operator == "==")), nil
+	// past equality, which keeps to values of the same type, integers mix with floats
+	case isNumeric(left) && isNumeric(right) && (left.Type() == object.FLOAT || right.Type() == object.FLOAT):
+		return e.evalFloatInfixExpression(operator, left, right)
 	case left.Type() != right.Type():
 		return nil, fmt.Errorf("type mismatch: %s %s %s",
 			left.Type(), operator, right.Type())
@@ -212,6 +254,28 @@ func (e *Evaluator) evalInfixExpression(
 	}
 }
 
+// Equality, a struct's `equals` member on either side takes precedence over value equality
+func (e *Evaluator) equals(left, right object.Object) (bool, error) {
+	fn, self, other, ok := object.EqualsMember(left, right)
+
+	if !ok {
+		return object.Equals(left, right), nil
+	}
+
+	result, err := e.applyFunction(fn, []object.Object{self, other}, token.Position{})
+
+	if err != nil {
+		return false, err
+	}
+
+	b, ok := result.(*object.Boolean)
+	if !ok {
+		return false, fmt.Errorf("`equals` must return a boolean, got %s", result.Type())
+	}
+
+	return b.Value, nil
+}
+
 func (e *Evaluator) evalIntegerInfixExpression(
 	operator string,
 	left, right object.Object,
@@ -227,6 +291,9 @@ func (e *Evaluator) evalIntegerInfixExpression(
 	case "*":
 		return &object.Integer{Value: leftVal * rightVal}, nil
 	case "/":
+		if rightVal == 0 {
+			return nil, errors.New("division by zero")
+		}
 		return &object.Integer{Value: leftVal / rightVal}, nil
 	case "<":
 		return e.nativeBoolToBooleanObject(leftVal < rightVal), nil
@@ -246,12 +313,58 @@ func (e *Evaluator) evalIntegerInfixExpression(
 	}
 }
 
+// arithmetic and comparisons, integers mixed with floats are promoted
+func (e *Evaluator) evalFloatInfixExpression(
+	operator string,
+	left, right object.Object,
+) (object.Object, error) {
+	leftVal, rightVal := floatValue(left), floatValue(right)
+
+	switch operator {
+	case "+":
+		return &object.Float{Value: leftVal + rightVal}, nil
+	case "-":
+		return &object.Float{Value: leftVal - rightVal}, nil
+	case "*":
+		return &object.Float{Value: leftVal * rightVal}, nil
+	case "/":
+		return &object.Float{Value: leftVal / rightVal}, nil
+	case "<":
+		return e.nativeBoolToBooleanObject(leftVal < rightVal), nil
+	case ">":
+		return e.nativeBoolToBooleanObject(leftVal > rightVal), nil
+	case ">=":
+		return e.nativeBoolToBooleanObject(leftVal >= rightVal), nil
+	case "<=":
+		return e.nativeBoolToBooleanObject(leftVal <= rightVal), nil
+	default:
+		return nil, fmt.Errorf("unknown operator: %s %s %s",
+			left.Type(), operator, right.Type())
+	}
+}
+
+func isNumeric(obj object.Object) bool {
+	return obj.Type() == object.INTEGER || obj.Type() == object.FLOAT
+}
+
+func floatValue(obj object.Object) float64 {
+	if i, ok := obj.(*object.Integer); ok {
+		return float64(i.Value)
+	}
+
+	return obj.(*object.Float).Value
+}
+
 // Index Operation e.g err[i], dict["key"]
 func (e *Evaluator) evalIndexExpression(left, index object.Object) (object.Object, error) {
 	switch {
 	case left.Type() == object.ARRAY && index.Type() == object.INTEGER:
 		return e.evalArrayIndexExpression(left, index)
 
+	case left.Type() == object.TUPLE && index.Type() == object.INTEGER:
+		tuple := left.(*object.Tuple)
+		return e.evalArrayIndexExpression(&object.Array{Elements: tuple.Elements}, index)
+
 	case left.Type() == object.HASH:
 		return e.evalHashIndexExpression(left, index)
 
@@ -277,17 +390,56 @@ func (e *Evaluator) evalArrayIndexExpression(array, index object.Object) (object
 func (e *Evaluator) evalHashIndexExpression(hash, index object.Object) (object.Object, error) {
 	hashObject := hash.(*object.Hash)
 
-	key, ok := index.(object.HashableProtocol)
-	if !ok {
-		return nil, fmt.Errorf("%s does not conform to the `hashable` protocol", index.Type())
+	value, ok, err := hashObject.Get(index)
+	if err != nil {
+		return nil, err
 	}
 
-	pair, ok := hashObject.Pairs[key.HashKey()]
 	if !ok {
 		return builtins.NULL, nil
 	}
 
-	return pair.Value, nil
+	return value, nil
+}
+
+// Member Operation e.g person.name, person?.name
+func (e *Evaluator) evalMemberExpression(node *ast.MemberExpression, s *scope.Scope) (object.Object, error) {
+	obj, err := e.eval(node.Object, s)
+
+	if err != nil {
+		return nil, err
+	}
+
+	name := node.Property.Value
+
+	switch obj := obj.(type) {
+	case *object.Null:
+		if node.Optional {
+			return builtins.NULL, nil
+		}
+
+		return nil, fmt.Errorf("cannot access member `%s` of null, use `?.`", name)
+	case *object.Hash:
+		return e.evalHashIndexExpression(obj, &object.String{Value: name})
+	case *object.Structure:
+		val, ok := obj.Members[name]
+
+		if !ok {
+			return nil, fmt.Errorf("`%s` has no member `%s`", obj.Name, name)
+		}
+
+		return val, nil
+	case *object.Module:
+		val, ok := obj.Exports[name]
+
+		if !ok {
+			return nil, fmt.Errorf("module `%s` does not export `%s`", obj.Name, name)
+		}
+
+		return val, nil
+	}
+
+	return nil, fmt.Errorf("member access not supported: %s", obj.Type())
 }
 
 // Assignment Operation
@@ -299,6 +451,11 @@ func (e *Evaluator) evalAssignmentExpression(a *ast.AssignmentExpression, s *sco
 		return nil, err
 	}
 
+	if loc := a.Target.Location; loc.Local {
+		s.Store(loc.Depth, loc.Slot, val)
+		return builtins.VOID, nil
+	}
+
 	// panic(s)ß
 	err = s.Assign(a.Target.Value, val)
 
@@ -309,3 +466,91 @@ func (e *Evaluator) evalAssignmentExpression(a *ast.AssignmentExpression, s *sco
 	return builtins.VOID, nil
 
 }
+
+// Element Assignment e.g arr[0] = 1, dict["key"] = 1, person.name = "a", refused by frozen containers
+func (e *Evaluator) evalElementAssignmentExpression(a *ast.ElementAssignmentExpression, s *scope.Scope) (object.Object, error) {
+	val, err := e.eval(a.Value, s)
+
+	if err != nil {
+		return nil, err
+	}
+
+	switch target := a.Target.(type) {
+	case *ast.IndexExpression:
+		err = e.assignIndex(target, val, s)
+	case *ast.MemberExpression:
+		err = e.assignMember(target, val, s)
+	default:
+		err = fmt.Errorf("cannot assign to %T", target)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return builtins.VOID, nil
+}
+
+func (e *Evaluator) assignIndex(target *ast.IndexExpression, val object.Object, s *scope.Scope) error {
+	left, err := e.eval(target.Left, s)
+	if err != nil {
+		return err
+	}
+
+	index, err := e.eval(target.Index, s)
+	if err != nil {
+		return err
+	}
+
+	switch left := left.(type) {
+	case *object.Array:
+		idx, ok := index.(*object.Integer)
+		if !ok {
+			return fmt.Errorf("cannot index array with %s", index.Type())
+		}
+
+		return left.SetElement(idx.Value, val)
+	case *object.Hash:
+		return left.Set(index, val)
+	}
+
+	return fmt.Errorf("index assignment not supported: %s", left.Type())
+}
+
+func (e *Evaluator) assignMember(target *ast.MemberExpression, val object.Object, s *scope.Scope) error {
+	obj, err := e.eval(target.Object, s)
+	if err != nil {
+		return err
+	}
+
+	name := target.Property.Value
+
+	switch obj := obj.(type) {
+	case *object.Structure:
+		return obj.SetMember(name, val)
+	case *object.Hash:
+		return obj.Set(&object.String{Value: name}, val)
+	}
+
+	return fmt.Errorf("member assignment not supported: %s", obj.Type())
+}
+
+// Propagate Operation e.g parse(x)?
+func (e *Evaluator) evalPropagateExpression(node *ast.PropagateExpression, s *scope.Scope) (object.Object, error) {
+	val, err := e.eval(node.Value, s)
+
+	if err != nil {
+		return nil, err
+	}
+
+	result, ok := val.(*object.Result)
+	if !ok {
+		return nil, fmt.Errorf("`?` requires a result, got %s", val.Type())
+	}
+
+	if result.Ok {
+		return result.Value, nil
+	}
+
+	return nil, &earlyReturn{Value: result}
+}
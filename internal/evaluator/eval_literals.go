@@ -30,12 +30,24 @@ func (e *Evaluator) evaluateLiteral(node ast.LiteralExpression, scope *scope.Sco
 		}
 
 		return &object.Array{Elements: elems}, nil
+	case *ast.TupleLiteral:
+		elems, err := e.evalExpressionList(node.Elements, scope)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return &object.Tuple{Elements: elems}, nil
 	case *ast.HashLiteral:
 		return e.evalHashLiteral(node, scope)
+	case *ast.SetLiteral:
+		return e.evalSetLiteral(node, scope)
 	case *ast.FunctionLiteral:
 		params := node.Parameters
 		body := node.Body
-		return &object.Function{Parameters: params, Body: body}, nil
+		fn := &object.Function{Parameters: params, Body: body, FrameSize: node.FrameSize}
+		e.envs[fn] = scope
+		return fn, nil
 
 	}
 
@@ -55,29 +67,50 @@ func (e *Evaluator) evalHashLiteral(
 	node *ast.HashLiteral,
 	scope *scope.Scope,
 ) (object.Object, error) {
-	pairs := make(map[object.HashKey]object.HashPair)
+	hash := object.NewHash()
+
+	// pairs are evaluated left to right, key before value
+	for _, pair := range node.Pairs {
+		key, err := e.eval(pair.Key, scope)
+
+		if err != nil {
+			return nil, err
+		}
 
-	for keyNode, valueNode := range node.Pairs {
-		key, err := e.eval(keyNode, scope)
+		value, err := e.eval(pair.Value, scope)
 
 		if err != nil {
 			return nil, err
 		}
 
-		hashKey, ok := key.(object.HashableProtocol)
-		if !ok {
-			return nil, fmt.Errorf("%s does not conform to `hashable` protocol", key.Inspect())
+		err = hash.Set(key, value)
+
+		if err != nil {
+			return nil, err
 		}
+	}
 
-		value, err := e.eval(valueNode, scope)
+	return hash, nil
+}
+
+// Evaluates a set literal, duplicates are dropped
+func (e *Evaluator) evalSetLiteral(
+	node *ast.SetLiteral,
+	scope *scope.Scope,
+) (object.Object, error) {
+	set := object.NewSet()
+
+	for _, el := range node.Elements {
+		value, err := e.eval(el, scope)
 
 		if err != nil {
 			return nil, err
 		}
 
-		hashed := hashKey.HashKey()
-		pairs[hashed] = object.HashPair{Key: key, Value: value}
+		if err := set.Add(value); err != nil {
+			return nil, err
+		}
 	}
 
-	return &object.Hash{Pairs: pairs}, nil
+	return set, nil
 }
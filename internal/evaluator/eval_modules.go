@@ -0,0 +1,117 @@
+package evaluator
+
+import (
+	"fmt"
+
+	"github.com/mantton/anthe/internal/ast"
+	"github.com/mantton/anthe/internal/builtins"
+	"github.com/mantton/anthe/internal/module"
+	"github.com/mantton/anthe/internal/object"
+	"github.com/mantton/anthe/internal/resolver"
+	"github.com/mantton/anthe/internal/scope"
+	"github.com/mantton/anthe/internal/token"
+)
+
+// runs modules in dependency order, each in its own global scope, returning the result of the last
+func (e *Evaluator) RunModules(modules []*module.Module) (object.Object, error) {
+	result, _, err := e.runModules(modules)
+	return result, err
+}
+
+// runs the modules, then the main function of the last one when it declares one
+func (e *Evaluator) RunMain(modules []*module.Module) (object.Object, error) {
+	result, entry, err := e.runModules(modules)
+
+	if err != nil || entry == nil {
+		return result, err
+	}
+
+	if main, err := entry.Get("main"); err == nil {
+		if _, ok := main.(*object.Function); ok {
+			return e.applyFunction(main, nil, token.Position{})
+		}
+	}
+
+	return result, nil
+}
+
+// returns the result and global scope of the last module
+func (e *Evaluator) runModules(modules []*module.Module) (object.Object, *scope.Scope, error) {
+	global, names := e.scope, e.resolver
+	defer func() { e.scope, e.resolver, e.exports = global, names, nil }()
+
+	var result object.Object
+
+	for _, m := range modules {
+		e.scope = scope.New(nil)
+		e.resolver = resolver.New()
+		e.exports = &object.Module{Name: m.Name, Exports: make(map[string]object.Object)}
+
+		r, err := e.RunProgram(m.Program)
+
+		if err != nil {
+			return nil, nil, err
+		}
+
+		e.modules[m.Path] = e.exports
+		result = r
+	}
+
+	return result, e.scope, nil
+}
+
+// binds the module, or the listed exports of it, in the importing scope
+func (e *Evaluator) evalImportStatement(node *ast.ImportStatement, s *scope.Scope) (object.Object, error) {
+	mod, ok := e.modules[node.Resolved]
+
+	if !ok {
+		return nil, fmt.Errorf("module `%s` is not loaded", node.Path)
+	}
+
+	if node.Names == nil {
+		return builtins.VOID, s.DefineConstant(node.Name, mod)
+	}
+
+	for _, name := range node.Names {
+		val, ok := mod.Exports[name.Value]
+
+		if !ok {
+			return nil, fmt.Errorf("module `%s` does not export `%s`", mod.Name, name.Value)
+		}
+
+		if err := s.DefineConstant(name.Value, val); err != nil {
+			return nil, err
+		}
+	}
+
+	return builtins.VOID, nil
+}
+
+func (e *Evaluator) evalExportStatement(node *ast.ExportStatement, s *scope.Scope) (object.Object, error) {
+	if !s.IsGlobalScope() {
+		return nil, fmt.Errorf("exports must be at the top level of a module")
+	}
+
+	result, err := e.eval(node.Statement, s)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if e.exports == nil {
+		return result, nil
+	}
+
+	for _, name := range node.Names() {
+		// aliases have no runtime value
+		val, err := s.Get(name)
+
+		if err != nil {
+			val = builtins.VOID
+		}
+
+		e.exports.Exports[name] = val
+	}
+
+	return result, nil
+}
@@ -1,13 +1,13 @@
 package evaluator
 
 import (
-	"errors"
 	"fmt"
 
 	"github.com/mantton/anthe/internal/ast"
 	"github.com/mantton/anthe/internal/builtins"
 	"github.com/mantton/anthe/internal/object"
 	"github.com/mantton/anthe/internal/scope"
+	"github.com/mantton/anthe/internal/token"
 )
 
 func (e *Evaluator) evaluateStatement(node ast.Statement, scope *scope.Scope) (object.Object, error) {
@@ -27,7 +27,13 @@ func (e *Evaluator) evaluateStatement(node ast.Statement, scope *scope.Scope) (o
 			return nil, err
 		}
 
-		err = scope.DefineVariable(node.Name.Value, val)
+		err = checkNullable(node.Name.Value, node.Type, val)
+
+		if err != nil {
+			return nil, err
+		}
+
+		err = define(scope, node.Name, val, false)
 
 		if err != nil {
 			return nil, err
@@ -40,7 +46,13 @@ func (e *Evaluator) evaluateStatement(node ast.Statement, scope *scope.Scope) (o
 			return nil, err
 		}
 
-		err = scope.DefineConstant(node.Name.Value, val)
+		err = checkNullable(node.Name.Value, node.Type, val)
+
+		if err != nil {
+			return nil, err
+		}
+
+		err = define(scope, node.Name, val, true)
 
 		if err != nil {
 			return nil, err
@@ -54,6 +66,24 @@ func (e *Evaluator) evaluateStatement(node ast.Statement, scope *scope.Scope) (o
 		}
 
 		return &object.ReturnValue{Value: val}, nil
+	case *ast.ThrowStatement:
+		return e.evalThrowStatement(node, scope)
+	case *ast.TryStatement:
+		return e.evalTryStatement(node, scope)
+	case *ast.ImportStatement:
+		return e.evalImportStatement(node, scope)
+	case *ast.ExportStatement:
+		return e.evalExportStatement(node, scope)
+	case *ast.DestructuringStatement:
+		err := e.evalDestructuringStatement(node, scope)
+
+		if err != nil {
+			return nil, err
+		}
+	case *ast.StructDeclaration:
+		return e.evalStructDeclaration(node, scope)
+	case *ast.TypeDeclaration:
+		return e.evalTypeDeclaration(node, scope)
 	case *ast.NamedFunctionDeclaration:
 		val, err := e.evalNamedFunctionDeclaration(node, scope)
 
@@ -71,9 +101,40 @@ func (e *Evaluator) evaluateStatement(node ast.Statement, scope *scope.Scope) (o
 	return nil, nil
 }
 
+// binds name in its slot when the resolver placed it in one, by name otherwise
+// constants hold a frozen copy, so neither the binding nor the value it holds can change
+func define(s *scope.Scope, name *ast.IdentifierExpression, val object.Object, constant bool) error {
+	if constant {
+		val = object.FrozenCopy(val)
+	}
+
+	switch {
+	case name.Location.Local:
+		s.Store(0, name.Location.Slot, val)
+		return nil
+	case constant:
+		return s.DefineConstant(name.Value, val)
+	}
+
+	return s.DefineVariable(name.Value, val)
+}
+
+// only bindings declared as optional, or without a declared type, may hold null
+func checkNullable(name string, t ast.TypeExpression, val object.Object) error {
+	if t == nil || val != builtins.NULL {
+		return nil
+	}
+
+	if _, ok := t.(*ast.OptionalType); ok {
+		return nil
+	}
+
+	return fmt.Errorf("cannot assign null to `%s` declared as `%s`", name, t.Type())
+}
+
 func (e *Evaluator) evalBlockStatement(
 	block *ast.BlockStatement,
-	scope *scope.Scope,
+	s *scope.Scope,
 ) (object.Object, error) {
 	var result object.Object
 	var err error
@@ -83,8 +144,11 @@ func (e *Evaluator) evalBlockStatement(
 		return builtins.VOID, nil
 	}
 
+	// declarations do not outlive the block
+	blockScope := scope.New(s)
+
 	for _, statement := range block.Statements {
-		result, err = e.eval(statement, scope)
+		result, err = e.eval(statement, blockScope)
 
 		if err != nil {
 			return nil, err
@@ -99,14 +163,16 @@ func (e *Evaluator) evalBlockStatement(
 		}
 	}
 
+	// a block ending in a declaration has no value
 	if result == nil {
-		return nil, errors.New("unexpected nil result")
+		return builtins.VOID, nil
 	}
 
 	return result, nil
 }
 
-func (e *Evaluator) applyFunction(fn object.Object, args []object.Object) (object.Object, error) {
+// calls fn with args, callSite is recorded in the stack trace of any runtime error raised by fn
+func (e *Evaluator) applyFunction(fn object.Object, args []object.Object, callSite token.Position) (object.Object, error) {
 	switch fn := fn.(type) {
 
 	case *object.Function:
@@ -115,29 +181,115 @@ func (e *Evaluator) applyFunction(fn object.Object, args []object.Object) (objec
 			return nil, fmt.Errorf("`%s` requires %d arguments, received %d", fn.Inspect(), len(fn.Parameters), len(args))
 		}
 
-		scope := e.createFunctionScope(fn, args)
-		evaluated, err := e.eval(fn.Body, scope)
-		if err != nil {
-			return nil, err
+		if e.depth >= e.maxDepth {
+			return nil, object.CallDepthError(e.maxDepth)
 		}
-		return unwrapReturnValue(evaluated)
+
+		e.depth++
+		defer func() { e.depth-- }()
+
+		return e.callFunction(fn, args, callSite)
 
 	case *object.Builtin:
 		return fn.Fn(args...), nil
 
+	case *object.StructDefinition:
+		if len(args) != len(fn.Fields) {
+			return nil, fmt.Errorf("`%s` requires %d fields, received %d", fn.Name, len(fn.Fields), len(args))
+		}
+
+		members := make(map[string]object.Object, len(args))
+		for i, field := range fn.Fields {
+			members[field] = args[i]
+		}
+
+		return &object.Structure{Name: fn.Name, Members: members}, nil
+
 	default:
 		return nil, fmt.Errorf("%s is not a function", fn.Type())
 	}
 }
 
+/*
+Calls fn, then the calls in tail position it ends in, on the same Go stack frame.
+The trace of an error raised in a tail call names the function that failed and the one called at
+callSite, the functions that tail called in between have no frames left to report.
+*/
+func (e *Evaluator) callFunction(called *object.Function, args []object.Object, callSite token.Position) (object.Object, error) {
+	fn, site, tailed := called, callSite, false
+	unwind := func(err error) error {
+		if rErr, ok := err.(*object.Error); ok {
+			rErr.Unwind(fn.Name, site)
+
+			if tailed {
+				rErr.Unwind(called.Name, callSite)
+			}
+		}
+
+		return err
+	}
+
+	for {
+		result, err := e.evalFunctionBody(fn, args)
+
+		tail, ok := result.(*tailCall)
+		if err != nil || !ok {
+			return result, unwind(err)
+		}
+
+		next, ok := tail.fn.(*object.Function)
+
+		switch {
+		case !ok:
+			// builtins and constructors do not recurse
+			result, err = e.applyFunction(tail.fn, tail.args, tail.node.Function.Pos())
+		case len(tail.args) != len(next.Parameters):
+			err = fmt.Errorf("`%s` requires %d arguments, received %d", next.Inspect(), len(next.Parameters), len(tail.args))
+		default:
+			fn, args, site, tailed = next, tail.args, tail.node.Function.Pos(), true
+			continue
+		}
+
+		if err != nil {
+			err = runtimeError(err, tail.node)
+		}
+
+		return result, unwind(err)
+	}
+}
+
+// the result of fn, or the call it ends in
+func (e *Evaluator) evalFunctionBody(fn *object.Function, args []object.Object) (object.Object, error) {
+	evaluated, err := e.eval(fn.Body, e.createFunctionScope(fn, args))
+
+	if err != nil {
+		if ret, ok := err.(*earlyReturn); ok {
+			return ret.Value, nil
+		}
+
+		return nil, err
+	}
+
+	return unwrapReturnValue(evaluated)
+}
+
 func (e *Evaluator) createFunctionScope(
 	fn *object.Function,
 	args []object.Object,
 ) *scope.Scope {
-	s := scope.New(e.scope)
+	parent := e.scope
+	if env, ok := e.envs[fn]; ok {
+		parent = env
+	}
+
+	s := scope.NewFrame(parent, fn.FrameSize)
 
 	for paramIdx, param := range fn.Parameters {
-		s.Inject(param.Value, args[paramIdx])
+		if param.Location.Local {
+			s.Store(0, param.Location.Slot, args[paramIdx])
+		} else {
+			s.DefineVariable(param.Value, args[paramIdx])
+		}
 	}
 
 	return s
@@ -154,13 +306,186 @@ func unwrapReturnValue(obj object.Object) (object.Object, error) {
 // named function
 func (e *Evaluator) evalNamedFunctionDeclaration(fn *ast.NamedFunctionDeclaration, s *scope.Scope) (object.Object, error) {
 
-	obj := &object.Function{Name: fn.Name, Parameters: fn.Fn.Parameters, Body: fn.Fn.Body}
+	obj := &object.Function{Name: fn.Name, Parameters: fn.Fn.Parameters, Body: fn.Fn.Body, FrameSize: fn.Fn.FrameSize}
+	e.envs[obj] = s
+
+	if fn.Location.Local {
+		s.Store(0, fn.Location.Slot, obj)
+	} else if err := s.DefineVariable(obj.Name, obj); err != nil {
+		return nil, err
+	}
+
+	return builtins.VOID, nil
+}
 
-	err := s.Inject(obj.Name, obj)
+/*
+Binds each name of a pattern to the matching part of the value.
+Tuples must have exactly as many elements as names. Arrays need at least as many, the rest binding
+takes whatever is left. Object patterns read the members of structs and the string keys of hashes,
+missing keys bind null.
+*/
+func (e *Evaluator) evalDestructuringStatement(node *ast.DestructuringStatement, s *scope.Scope) error {
+	val, err := e.eval(node.Value, s)
 
 	if err != nil {
+		return err
+	}
+
+	values := []object.Object{}
+
+	switch node.Kind {
+	case ast.TuplePattern:
+		tuple, ok := val.(*object.Tuple)
+
+		if !ok {
+			return fmt.Errorf("cannot destructure %s as a tuple", val.Type())
+		}
+
+		if len(tuple.Elements) != len(node.Names) {
+			return fmt.Errorf("cannot destructure a tuple of %d elements into %d names", len(tuple.Elements), len(node.Names))
+		}
+
+		values = tuple.Elements
+	case ast.ArrayPattern:
+		arr, ok := val.(*object.Array)
+
+		if !ok {
+			return fmt.Errorf("cannot destructure %s as an array", val.Type())
+		}
+
+		if len(arr.Elements) < len(node.Names) || (node.Rest == nil && len(arr.Elements) != len(node.Names)) {
+			return fmt.Errorf("cannot destructure an array of %d elements into %d names", len(arr.Elements), len(node.Names))
+		}
+
+		values = arr.Elements[:len(node.Names)]
+	case ast.ObjectPattern:
+		for _, name := range node.Names {
+			var member object.Object
+
+			switch obj := val.(type) {
+			case *object.Structure:
+				m, ok := obj.Members[name.Value]
+
+				if !ok {
+					return fmt.Errorf("`%s` has no member `%s`", obj.Name, name.Value)
+				}
+
+				member = m
+			case *object.Hash:
+				member, err = e.evalHashIndexExpression(obj, &object.String{Value: name.Value})
+
+				if err != nil {
+					return err
+				}
+			default:
+				return fmt.Errorf("cannot destructure %s as an object", val.Type())
+			}
+
+			values = append(values, member)
+		}
+	}
+
+	for i, name := range node.Names {
+		if err := define(s, name, values[i], node.Constant); err != nil {
+			return err
+		}
+	}
+
+	if node.Rest != nil {
+		rest := append([]object.Object{}, val.(*object.Array).Elements[len(node.Names):]...)
+
+		if err := define(s, node.Rest, &object.Array{Elements: rest}, node.Constant); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// struct declaration, binds a constructor taking the fields in order
+func (e *Evaluator) evalStructDeclaration(node *ast.StructDeclaration, s *scope.Scope) (object.Object, error) {
+	def := &object.StructDefinition{Name: node.Name}
+
+	for _, field := range node.Fields {
+		def.Fields = append(def.Fields, field.Name)
+	}
+
+	if err := s.DefineConstant(def.Name, def); err != nil {
 		return nil, err
 	}
 
 	return builtins.VOID, nil
 }
+
+// aliases only exist for the checker, distinct types bind a constructor that returns its argument as is
+func (e *Evaluator) evalTypeDeclaration(node *ast.TypeDeclaration, s *scope.Scope) (object.Object, error) {
+	if !node.Distinct {
+		return builtins.VOID, nil
+	}
+
+	ctor := &object.Builtin{
+		Name: node.Name,
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return builtins.NULL
+			}
+
+			return args[0]
+		},
+	}
+
+	if err := s.DefineConstant(node.Name, ctor); err != nil {
+		return nil, err
+	}
+
+	return builtins.VOID, nil
+}
+
+// THROW, errors are raised as is, any other value is carried in an error and caught as itself
+func (e *Evaluator) evalThrowStatement(node *ast.ThrowStatement, s *scope.Scope) (object.Object, error) {
+	val, err := e.eval(node.Value, s)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// the trace restarts at the throw, including rethrows of caught errors
+	return nil, object.Thrown(node.Pos(), val)
+}
+
+// TRY
+func (e *Evaluator) evalTryStatement(node *ast.TryStatement, s *scope.Scope) (object.Object, error) {
+	result, err := e.eval(node.Body, s)
+
+	// only runtime errors are caught, early returns pass through to the enclosing function
+	if rErr, ok := err.(*object.Error); ok && node.Catch != nil {
+		catchScope := scope.NewFrame(s, node.CatchFrameSize)
+
+		if node.CatchParam != nil {
+			if err := define(catchScope, node.CatchParam, rErr.Caught(), false); err != nil {
+				return nil, err
+			}
+		}
+
+		result, err = e.eval(node.Catch, catchScope)
+	}
+
+	if node.Finally != nil {
+		final, fErr := e.eval(node.Finally, s)
+
+		// an error or return in finally replaces the outcome of the body and catch
+		if fErr != nil {
+			return nil, fErr
+		}
+
+		if final != nil && final.Type() == object.RETURN_VALUE {
+			return final, nil
+		}
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
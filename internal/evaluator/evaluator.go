@@ -1,24 +1,50 @@
 package evaluator
 
 import (
+	"errors"
 	"fmt"
 
 	"github.com/mantton/anthe/internal/ast"
 	"github.com/mantton/anthe/internal/object"
+	"github.com/mantton/anthe/internal/resolver"
 	"github.com/mantton/anthe/internal/scope"
+	"github.com/mantton/anthe/internal/token"
 )
 
 type Evaluator struct {
 	scope *scope.Scope
+
+	modules  map[string]*object.Module         // evaluated modules by resolved path
+	exports  *object.Module                    // exports of the module being evaluated, nil outside modules
+	envs     map[*object.Function]*scope.Scope // scope each function was created in, the parent of its call frames
+	resolver *resolver.Resolver
+
+	depth    int // calls being evaluated, tail calls replace the call that made them
+	maxDepth int
 }
 
 func New() *Evaluator {
-	return &Evaluator{scope: scope.New(nil)}
+	return &Evaluator{
+		scope:    scope.New(nil),
+		modules:  make(map[string]*object.Module),
+		envs:     make(map[*object.Function]*scope.Scope),
+		resolver: resolver.New(),
+		maxDepth: object.MAX_CALL_DEPTH,
+	}
+}
+
+// calls nested deeper than max raise a runtime error
+func (e *Evaluator) SetMaxCallDepth(max int) {
+	e.maxDepth = max
 }
 
 func (e *Evaluator) RunProgram(program *ast.Program) (object.Object, error) {
 	// fmt.Println("\nExecution List:")
 
+	if errs := e.resolver.Resolve(program); len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
 	var result object.Object
 	var err error
 
@@ -26,6 +52,13 @@ func (e *Evaluator) RunProgram(program *ast.Program) (object.Object, error) {
 		result, err = e.eval(statement, e.scope)
 
 		if err != nil {
+			if ret, ok := err.(*earlyReturn); ok {
+				return ret.Value, nil
+			}
+
+			if rErr, ok := err.(*object.Error); ok {
+				rErr.Unwind("<main>", token.Position{})
+			}
 			return nil, err
 		}
 
@@ -40,15 +73,55 @@ func (e *Evaluator) RunProgram(program *ast.Program) (object.Object, error) {
 
 func (e *Evaluator) eval(node ast.Node, scope *scope.Scope) (object.Object, error) {
 	// fmt.Printf("\n%T", node)
-	switch node := node.(type) {
+	var result object.Object
+	var err error
+
+	switch n := node.(type) {
 	case ast.LiteralExpression:
-		return e.evaluateLiteral(node, scope)
+		result, err = e.evaluateLiteral(n, scope)
 	case ast.Expression:
-		return e.evaluateExpression(node, scope)
+		result, err = e.evaluateExpression(n, scope)
 	case ast.Statement:
-		return e.evaluateStatement(node, scope)
+		result, err = e.evaluateStatement(n, scope)
+	default:
+		err = fmt.Errorf("unknown node `%s`", node.TokenLiteral())
 	}
 
-	return nil, fmt.Errorf("unknown node `%s`", node.TokenLiteral())
+	if err != nil {
+		return nil, runtimeError(err, node)
+	}
 
+	return result, nil
 }
+
+// converts an error raised while evaluating node into a runtime error, errors raised deeper are kept as is
+func runtimeError(err error, node ast.Node) error {
+	switch err.(type) {
+	case *object.Error, *earlyReturn:
+		return err
+	}
+
+	// a failed call is raised at the function being called, which is also its call site in traces
+	if call, ok := node.(*ast.CallExpression); ok {
+		node = call.Function
+	}
+
+	return object.NewError(node.Pos(), err.Error())
+}
+
+// unwinds the evaluation to the enclosing function call, which returns Value
+type earlyReturn struct {
+	Value object.Object
+}
+
+func (r *earlyReturn) Error() string { return "early return of " + r.Value.Inspect() }
+
+// a call in tail position, returned to the enclosing applyFunction which makes it in place of the caller
+type tailCall struct {
+	fn   object.Object
+	args []object.Object
+	node *ast.CallExpression
+}
+
+func (t *tailCall) Type() object.ObjectType { return "TAIL_CALL" }
+func (t *tailCall) Inspect() string         { return "tail call of " + t.fn.Inspect() }
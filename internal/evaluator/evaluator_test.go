@@ -2,10 +2,19 @@ package evaluator
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"strings"
 	"testing"
 
+	"github.com/mantton/anthe/internal/ast"
 	"github.com/mantton/anthe/internal/lexer"
+	"github.com/mantton/anthe/internal/module"
+	"github.com/mantton/anthe/internal/object"
 	"github.com/mantton/anthe/internal/parser"
+	"github.com/mantton/anthe/internal/typing"
+	"github.com/mantton/anthe/internal/vm"
 )
 
 func TestEvaluator(t *testing.T) {
@@ -33,3 +42,637 @@ func TestEvaluator(t *testing.T) {
 
 	// fmt.Println(evaluator.Inspect())
 }
+
+type backend interface {
+	RunProgram(program *ast.Program) (object.Object, error)
+	RunModules(modules []*module.Module) (object.Object, error)
+	SetMaxCallDepth(max int)
+}
+
+type namedBackend struct {
+	name string
+	backend
+}
+
+// every program runs on both the evaluator and the vm
+func backends() []namedBackend {
+	return []namedBackend{{"evaluator", New()}, {"vm", vm.New()}}
+}
+
+func run(b backend, input string) (object.Object, error) {
+	return b.RunProgram(parser.New(lexer.New(input, "test.an")).ParseProgram())
+}
+
+// the result of the evaluator, which the vm must agree with
+func testEval(t *testing.T, input string) object.Object {
+	t.Helper()
+
+	prog := parser.New(lexer.New(input, "test.an")).ParseProgram()
+
+	if len(prog.Errors) > 0 {
+		t.Fatalf("program failed to parse: %v", prog.Errors)
+	}
+
+	var expected object.Object
+
+	for _, b := range backends() {
+		result, err := run(b, input)
+
+		if err != nil {
+			t.Fatalf("%s: %s", b.name, err)
+		}
+
+		if expected == nil {
+			expected = result
+		} else if result.Inspect() != expected.Inspect() {
+			t.Errorf("%s: %s: expected %s, got %s", b.name, input, expected.Inspect(), result.Inspect())
+		}
+	}
+
+	return expected
+}
+
+// the result of a program the checker accepts, which every backend must then run
+func testChecked(t *testing.T, input string) object.Object {
+	t.Helper()
+
+	prog := parser.New(lexer.New(input, "test.an")).ParseProgram()
+
+	if ok, errs := typing.New().CheckProgram(prog); !ok {
+		t.Fatalf("%s: type errors: %v", input, errs)
+	}
+
+	return testEval(t, input)
+}
+
+// the error of each backend running the input
+func testErrors(t *testing.T, input string) map[string]error {
+	t.Helper()
+
+	errs := make(map[string]error)
+
+	for _, b := range backends() {
+		_, errs[b.name] = run(b, input)
+	}
+
+	return errs
+}
+
+func TestHashLiteralOrder(t *testing.T) {
+	input := `{"c": 1, "a": 2, "b": 3, "a": 4}`
+
+	result := testEval(t, input)
+
+	if result.Inspect() != "{c: 1, a: 4, b: 3}" {
+		t.Fatalf("hash not in insertion order, got %s", result.Inspect())
+	}
+}
+
+func TestHashKeys(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`{1.2: "a", 1.7: "b"}`, "{1.2: a, 1.7: b}"},
+		{`{[1, 2]: "a", [2, 1]: "b"}[[2, 1]]`, "b"},
+		{`{1: "a", 1.0: "b"}`, "{1: a, 1.0: b}"},
+		{`let k = [1]; let h = {k: "a"}; k[0] = 2; h[[1]]`, "a"},
+		{`let k = [1]; let h = {k: "a"}; k[0] = 2; (k, h)`, "([2], {[1]: a})"},
+		{`let k = [1]; let s = {k, [3]}; k[0] = 2; s`, "{[1], [3]}"},
+	}
+
+	for _, tt := range tests {
+		result := testEval(t, tt.input)
+
+		if result.Inspect() != tt.expected {
+			t.Errorf("%s: expected %s, got %s", tt.input, tt.expected, result.Inspect())
+		}
+	}
+}
+
+func TestEquality(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{`"a" == "a"`, true},
+		{`"a" != "b"`, true},
+		{`1.0 == 1.0`, true},
+		{`[1, "a"] == [1, "a"]`, true},
+		{`[1] == [2]`, false},
+		{`{"a": 1, "b": 2} == {"b": 2, "a": 1}`, true},
+		{`{"a": 1} == {"a": 2}`, false},
+		{`1 == "1"`, false},
+		{`null == null`, true},
+		{"struct P { n: int, equals: (P, int) -> bool }; let p = P(1, func(a, b) { a.n == b }); p == 1", true},
+		{"struct P { n: int, equals: (P, int) -> bool }; let p = P(1, func(a, b) { a.n == b }); 1 == p", true},
+		{"struct P { n: int, equals: (P, int) -> bool }; let p = P(1, func(a, b) { a.n == b }); 2 != p", true},
+	}
+
+	for _, tt := range tests {
+		result := testEval(t, tt.input)
+
+		b, ok := result.(*object.Boolean)
+		if !ok || b.Value != tt.expected {
+			t.Errorf("%s: expected %t, got %s", tt.input, tt.expected, result.Inspect())
+		}
+	}
+}
+
+func TestTruthiness(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`if 1 { "then" } else { "else" }`, "then"},
+		{`if 0 { "then" } else { "else" }`, "else"},
+		{`if "" { "then" } else { "else" }`, "else"},
+		{`if [0] { "then" } else { "else" }`, "then"},
+		{`if {} { "then" } else { "else" }`, "else"},
+		{`!5`, "false"},
+		{`!0`, "true"},
+		{`!null`, "true"},
+		{`!!"a"`, "true"},
+	}
+
+	for _, tt := range tests {
+		result := testEval(t, tt.input)
+
+		if result.Inspect() != tt.expected {
+			t.Errorf("%s: expected %s, got %s", tt.input, tt.expected, result.Inspect())
+		}
+	}
+}
+
+func TestRuntimeErrorTrace(t *testing.T) {
+	input := `func inner(x) { x[5] }; func outer() { inner([1]) }; outer()`
+
+	expected := []string{"inner", "outer", "<main>"}
+
+	for backend, err := range testErrors(t, input) {
+		rErr, ok := err.(*object.Error)
+		if !ok {
+			t.Fatalf("%s: expected runtime error, got %v", backend, err)
+		}
+
+		if len(rErr.Trace) != len(expected) {
+			t.Fatalf("%s: expected %d frames, got %d", backend, len(expected), len(rErr.Trace))
+		}
+
+		for i, name := range expected {
+			if rErr.Trace[i].Function != name {
+				t.Errorf("%s: frame %d: expected %s, got %s", backend, i, name, rErr.Trace[i].Function)
+			}
+		}
+	}
+}
+
+func TestTryCatch(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`try { throw "boom" } catch (e) { message(e) }`, "boom"},
+		{`func f() { [1][3] }; try { f() } catch (e) { message(e) }`, "index out of range"},
+		{`let x = 1; try { throw error("a") } catch { x = 2 } finally { x = x + 1 }; x`, "3"},
+		{`func f() { try { return 1 } finally { 2 } }; f()`, "1"},
+		{`func f() { try { throw 1 } catch (e) { throw e } }; try { f() } catch (e) { message(e) }`, "1"},
+		{`try { 1 / 0 } catch (e) { message(e) }`, "division by zero"},
+		{`try { throw 42 } catch (e) { e + 1 }`, "43"},
+		{`func f() { throw [1, 2] }; try { f() } catch (e) { e[1] }`, "2"},
+		{`func f() { try { throw 7 } catch (e) { throw e } }; try { f() } catch (e) { e }`, "7"},
+		{`try { throw error("a") } catch (e) { e }`, "error: a"},
+	}
+
+	for _, tt := range tests {
+		result := testEval(t, tt.input)
+
+		if result.Inspect() != tt.expected {
+			t.Errorf("%s: expected %s, got %s", tt.input, tt.expected, result.Inspect())
+		}
+	}
+}
+
+func TestResultPropagation(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`func f() { let x = ok(2)?; ok(x + 1) }; f()`, "ok(3)"},
+		{`func f() { let x = err("bad")?; ok(x + 1) }; f()`, "err(bad)"},
+		{`func g() { err(1) }; func f() { try { g()? } catch { 0 } }; f()`, "err(1)"},
+	}
+
+	for _, tt := range tests {
+		result := testEval(t, tt.input)
+
+		if result.Inspect() != tt.expected {
+			t.Errorf("%s: expected %s, got %s", tt.input, tt.expected, result.Inspect())
+		}
+	}
+}
+
+func TestNullSafety(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let p = {"name": "a"}; p.name`, "a"},
+		{`let p = null; p?.name`, "null"},
+		{`let p = null; p?[0]`, "null"},
+		{`let p = null; p?.name ?? "none"`, "none"},
+		{`let p = {"name": "a"}; p?.name ?? "none"`, "a"},
+		{`let x: int? = null; x ?? 1`, "1"},
+	}
+
+	for _, tt := range tests {
+		result := testEval(t, tt.input)
+
+		if result.Inspect() != tt.expected {
+			t.Errorf("%s: expected %s, got %s", tt.input, tt.expected, result.Inspect())
+		}
+	}
+
+	for backend, err := range testErrors(t, `let x: int = null;`) {
+		if err == nil {
+			t.Errorf("%s: expected error assigning null to a non-optional binding", backend)
+		}
+	}
+}
+
+func TestGenerics(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`func first<T>(xs: array<T>) -> T { return xs[0]; }; first(["a", "b"])`, "a"},
+		{`struct Box<T> { value: T, count: int }; Box("a", 2)`, "Box {count: 2, value: a}"},
+		{`struct Pair<A, B> { left: A; right: B }; let p = Pair(1, true); p.right`, "true"},
+	}
+
+	for _, tt := range tests {
+		result := testEval(t, tt.input)
+
+		if result.Inspect() != tt.expected {
+			t.Errorf("%s: expected %s, got %s", tt.input, tt.expected, result.Inspect())
+		}
+	}
+}
+
+func TestSets(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`{1, 2, 2, 3}`, "{1, 2, 3}"},
+		{`{[1], [1]}`, "{[1]}"},
+		{`union({1, 2}, {2, 3})`, "{1, 2, 3}"},
+		{`intersection({1, 2, 3}, {3, 2})`, "{2, 3}"},
+		{`difference({1, 2, 3}, {2})`, "{1, 3}"},
+		{`{1, 2} == {2, 1}`, "true"},
+		{`if difference({1}, {1}) { 1 } else { 2 }`, "2"},
+	}
+
+	for _, tt := range tests {
+		result := testEval(t, tt.input)
+
+		if result.Inspect() != tt.expected {
+			t.Errorf("%s: expected %s, got %s", tt.input, tt.expected, result.Inspect())
+		}
+	}
+}
+
+func TestTypeDeclarations(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`type ID = int; let id: ID = 4; id`, "4"},
+		{`type Meters distinct int; Meters(2) + Meters(3)`, "5"},
+	}
+
+	for _, tt := range tests {
+		result := testEval(t, tt.input)
+
+		if result.Inspect() != tt.expected {
+			t.Errorf("%s: expected %s, got %s", tt.input, tt.expected, result.Inspect())
+		}
+	}
+}
+
+func TestDestructuring(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`(1, "a")`, "(1, a)"},
+		{`let t = (1, 2); t[1]`, "2"},
+		{`func f() { return 1, 2; }; let (a, b) = f(); b - a`, "1"},
+		{`let [x, y, ...rest] = [1, 2, 3, 4]; rest`, "[3, 4]"},
+		{`let [x, ...rest] = [1]; rest`, "[]"},
+		{`let {name, age} = {"name": "a", "age": 3}; age`, "3"},
+		{`struct P { name: string, age: int }; const {name} = P("b", 2); name`, "b"},
+		{`(1, 2) == (1, 2)`, "true"},
+	}
+
+	for _, tt := range tests {
+		result := testEval(t, tt.input)
+
+		if result.Inspect() != tt.expected {
+			t.Errorf("%s: expected %s, got %s", tt.input, tt.expected, result.Inspect())
+		}
+	}
+
+	for _, input := range []string{`let (a, b) = (1, 2, 3);`, `let [a, b] = [1];`, `let [a] = [1, 2];`} {
+		for backend, err := range testErrors(t, input) {
+			if err == nil {
+				t.Errorf("%s: %s: expected a destructuring error", backend, input)
+			}
+		}
+	}
+}
+
+func TestModules(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"main.an":     `import "geometry"; import { scaled } from "./shapes"; geometry.area(2, 3) + scaled(1)`,
+		"shapes.an":   `import { area } from "./geometry"; const factor = 10; export func scaled(x) { area(x, factor) }`,
+		"geometry.an": `func mul(a, b) { a * b }; export func area(w, h) { mul(w, h) }`,
+	}
+
+	for name, src := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	modules, err := module.NewResolver(nil).Load(filepath.Join(dir, "main.an"))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, b := range backends() {
+		result, err := b.RunModules(modules)
+
+		if err != nil {
+			t.Fatalf("%s: %s", b.name, err)
+		}
+
+		if result.Inspect() != "16" {
+			t.Errorf("%s: expected 16, got %s", b.name, result.Inspect())
+		}
+	}
+}
+
+func TestResolvedFrames(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`func adder(n) { func(x) { x + n } }; let add = adder(2); add(3)`, "5"},
+		{`func f(a) { let b = a + 1; func g(c) { c * b }; g(a) }; f(3)`, "12"},
+		{`func f() { let n = 1; let inc = func() { n = n + 1 }; inc(); inc(); n }; f()`, "3"},
+		{`func f() { try { throw "x" } catch (e) { let m = message(e); m } }; f()`, "x"},
+		{`let g = 1; func f() { g = g + 1; g }; f()`, "2"},
+	}
+
+	for _, tt := range tests {
+		result := testEval(t, tt.input)
+
+		if result.Inspect() != tt.expected {
+			t.Errorf("%s: expected %s, got %s", tt.input, tt.expected, result.Inspect())
+		}
+	}
+
+	for backend, err := range testErrors(t, `func f() { missing }`) {
+		if err == nil {
+			t.Errorf("%s: expected the resolver to report an undefined variable", backend)
+		}
+	}
+}
+
+func TestBlockScopes(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let x = 1; if true { let x = 2; x }`, "2"},
+		{`let x = 1; if true { let x = 2; x }; x`, "1"},
+		{`let x = 1; if true { x = 2; x }; x`, "2"},
+		{`func f(a) { let n = 1; if a { let n = 10; n } else { let n = 20; n + 1 } }; f(false)`, "21"},
+		{`func f() { let n = 1; if true { let m = n + 1; func() { m + n } } }; f()()`, "3"},
+		{`func f(c) { if c { let x = 1; } }; f(true); f(true)`, "void"},
+		{`func f() { if true { let z = 1; }; 5 }; f()`, "5"},
+	}
+
+	for _, tt := range tests {
+		result := testEval(t, tt.input)
+
+		if result.Inspect() != tt.expected {
+			t.Errorf("%s: expected %s, got %s", tt.input, tt.expected, result.Inspect())
+		}
+	}
+
+	// the same branch runs again on a later REPL line without its declarations leaking
+	for _, b := range backends() {
+		for i := 0; i < 2; i++ {
+			for _, input := range []string{`if true { let a = 1; a }`, `if true { let a = 1; }`} {
+				if _, err := run(b, input); err != nil {
+					t.Fatalf("%s: %s: %s", b.name, input, err)
+				}
+			}
+		}
+
+		if _, err := run(b, `a`); err == nil {
+			t.Errorf("%s: expected a of an if block to be undefined outside of it", b.name)
+		}
+	}
+}
+
+func TestFrozenValues(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let a = [1, 2]; a[0] = 5; a`, "[5, 2]"},
+		{`let m = {"a": 1}; m["b"] = 2; m.a = 3; m`, "{a: 3, b: 2}"},
+		{`struct P { x: int }; let p = P(1); p.x = 2; p.x`, "2"},
+		{`const a = [1, 2]; let b = a; try { b[0] = 5 } catch (e) { message(e) }`, "cannot modify a frozen array"},
+		{`const m = {"a": [1]}; let inner = m["a"]; try { inner[0] = 2 } catch (e) { message(e) }`, "cannot modify a frozen array"},
+		{`let s = freeze({"a": 1}); try { s["a"] = 2 } catch (e) { message(e) }`, "cannot modify a frozen hashmap"},
+		{`let a = [1]; freeze(a); try { a[0] = 2 } catch (e) { message(e) }`, "cannot modify a frozen array"},
+		{`let a = [1]; try { a[3] = 2 } catch (e) { message(e) }`, "index out of range"},
+	}
+
+	for _, tt := range tests {
+		result := testEval(t, tt.input)
+
+		if result.Inspect() != tt.expected {
+			t.Errorf("%s: expected %s, got %s", tt.input, tt.expected, result.Inspect())
+		}
+	}
+
+	// a constant holds a frozen copy, the checker allows writes through other bindings and so does the runtime
+	aliases := []struct {
+		input    string
+		expected string
+	}{
+		{`let a = [1]; const b = a; a[0] = 2; (a[0], b[0])`, "(2, 1)"},
+		{`let m = {"a": [1]}; const n = m; m["a"] = [3]; n["a"]`, "[1]"},
+		{`struct P { x: int }; let p = P(1); const q = p; p.x = 2; q.x`, "1"},
+		{`let a = [1]; let b = [a, a]; const c = b; a[0] = 2; c`, "[[1], [1]]"},
+	}
+
+	for _, tt := range aliases {
+		result := testChecked(t, tt.input)
+
+		if result.Inspect() != tt.expected {
+			t.Errorf("%s: expected %s, got %s", tt.input, tt.expected, result.Inspect())
+		}
+	}
+}
+
+func TestTailCalls(t *testing.T) {
+	// deep recursion in tail position runs in constant stack
+	defer debug.SetMaxStack(debug.SetMaxStack(16 << 20))
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`func count(n, acc) { if n == 0 { acc } else { count(n - 1, acc + 1) } }; count(200000, 0)`, "200000"},
+		{`func even(n) { if n == 0 { return true }; odd(n - 1) }; func odd(n) { if n == 0 { false } else { even(n - 1) } }; even(100001)`, "false"},
+		{`func f(n) { if n == 0 { return ok(2) }; return f(n - 1) }; f(100000)`, "ok(2)"},
+		{`func f(n) { try { if n == 0 { throw "x" } else { f(n - 1) } } catch (e) { n } }; f(3)`, "0"},
+	}
+
+	for _, tt := range tests {
+		result := testEval(t, tt.input)
+
+		if result.Inspect() != tt.expected {
+			t.Errorf("%s: expected %s, got %s", tt.input, tt.expected, result.Inspect())
+		}
+	}
+
+	// the trace keeps the function that failed and the one that was called
+	input := `func fail(x) { x[5] }; func loop(n) { if n == 0 { fail([1]) } else { loop(n - 1) } }; loop(3)`
+	expected := []string{"fail", "loop", "<main>"}
+
+	for backend, err := range testErrors(t, input) {
+		rErr, ok := err.(*object.Error)
+		if !ok {
+			t.Fatalf("%s: expected runtime error, got %v", backend, err)
+		}
+
+		if len(rErr.Trace) != len(expected) {
+			t.Fatalf("%s: expected %d frames, got %d", backend, len(expected), len(rErr.Trace))
+		}
+
+		for i, name := range expected {
+			if rErr.Trace[i].Function != name {
+				t.Errorf("%s: frame %d: expected %s, got %s", backend, i, name, rErr.Trace[i].Function)
+			}
+		}
+	}
+}
+
+func TestCallDepth(t *testing.T) {
+	recurse := `func down(n) { 1 + down(n + 1) }; `
+
+	// the default limit is reached before the Go stack overflows
+	for backend, err := range testErrors(t, recurse+`down(0)`) {
+		rErr, ok := err.(*object.Error)
+
+		if !ok || rErr.Message != "maximum call depth of 10000 exceeded" {
+			t.Fatalf("%s: expected the call depth error, got %v", backend, err)
+		}
+
+		if first, last := rErr.Trace[0].Function, rErr.Trace[len(rErr.Trace)-1].Function; first != "down" || last != "<main>" {
+			t.Errorf("%s: expected a trace from down to <main>, got %s to %s", backend, first, last)
+		}
+
+		if lines := strings.Count(rErr.Traceback(), "\n"); lines > 5 {
+			t.Errorf("%s: expected the recursive frames to be collapsed, got %d lines", backend, lines)
+		}
+	}
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{recurse + `try { down(0) } catch (e) { message(e) }`, "maximum call depth of 50 exceeded"},
+		{`func sum(n) { if n == 0 { 0 } else { n + sum(n - 1) } }; sum(40)`, "820"},
+		{`func count(n) { if n == 0 { 0 } else { count(n - 1) } }; count(1000)`, "0"},
+	}
+
+	for _, tt := range tests {
+		for _, b := range backends() {
+			b.SetMaxCallDepth(50)
+			result, err := run(b, tt.input)
+
+			if err != nil {
+				t.Fatalf("%s: %s", b.name, err)
+			}
+
+			if result.Inspect() != tt.expected {
+				t.Errorf("%s: %s: expected %s, got %s", b.name, tt.input, tt.expected, result.Inspect())
+			}
+		}
+	}
+}
+
+// both backends report the same frames, at the same positions
+func TestTracebacks(t *testing.T) {
+	inputs := []string{
+		`func f(n) { if n == 0 { -"a" } else { f(n - 1) } }; func h() { f(2) }; h()`,
+		`func f(n) { if n == 0 { -"a" } else { f(n - 1) } }; func h() { let x = f(2); x }; h()`,
+		`func f(n) { try { if n == 0 { -"a" } else { f(n - 1) } } finally { 1 } }; f(2)`,
+		`func f(n) { f(n + 1) + 1 }; f(0)`,
+	}
+
+	for _, input := range inputs {
+		traces := map[string]string{}
+
+		for backend, err := range testErrors(t, input) {
+			rErr, ok := err.(*object.Error)
+			if !ok {
+				t.Fatalf("%s: expected runtime error, got %v", backend, err)
+			}
+
+			traces[backend] = rErr.Traceback()
+		}
+
+		if traces["evaluator"] != traces["vm"] {
+			t.Errorf("%s: tracebacks differ\nevaluator: %s\nvm: %s", input, traces["evaluator"], traces["vm"])
+		}
+	}
+}
+
+func TestFloatArithmetic(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let x = 1.5 + 2.0; x`, "3.5"},
+		{`2.5 * 2.0 - 1.0 / 2.0`, "4.5"},
+		{`-1.5`, "-1.5"},
+		{`1.5 < 2.0`, "true"},
+		{`2.0 >= 2.5`, "false"},
+		{`type Meters distinct float; let a = Meters(1.5); let b = Meters(2.0); a + b`, "3.5"},
+		{`type Meters distinct float; let a = Meters(1.5); a < Meters(2.0)`, "true"},
+	}
+
+	for _, tt := range tests {
+		result := testChecked(t, tt.input)
+
+		if result.Inspect() != tt.expected {
+			t.Errorf("%s: expected %s, got %s", tt.input, tt.expected, result.Inspect())
+		}
+	}
+
+	// integers mixed with floats are promoted at runtime
+	if result := testEval(t, `1 + 0.5`); result.Inspect() != "1.5" {
+		t.Errorf("expected 1.5, got %s", result.Inspect())
+	}
+}
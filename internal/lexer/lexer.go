@@ -26,7 +26,7 @@ func New(input string, filename string) *Lexer {
 	l.position = 0
 	l.readPosition = 0
 	l.line = 1
-	l.col = 1
+	l.col = 0
 
 	l.next()
 	if l.ch == bom {
@@ -60,17 +60,17 @@ func (l *Lexer) next() {
 		// reached EOF
 		l.ch = eof
 	} else {
-		// Set the ch to the next position
-		l.ch = l.input[l.readPosition]
-
-		if l.ch == '\n' {
-			// moved to new line, reset col to 1 and increment line
+		if l.ch == '\n' && l.readPosition > 0 {
+			// moving past a new line, reset col to 1 and increment line
 			l.col = 1
 			l.line++
 		} else {
 			// moved col by 1 position
 			l.col += 1
 		}
+
+		// Set the ch to the next position
+		l.ch = l.input[l.readPosition]
 	}
 
 	// update the current position to the next position
@@ -95,6 +95,8 @@ func (l *Lexer) NextToken() token.Token {
 	// move to next non whitespace character
 	l.skipWhitespace()
 
+	pos := token.Position{Filename: l.filename, Line: l.line, Col: l.col}
+
 	var tok token.Token
 	// is EOF character
 	if l.ch == eof {
@@ -107,11 +109,13 @@ func (l *Lexer) NextToken() token.Token {
 
 		if tok.Type != token.ILLEGAL && tok.Type != token.STRING {
 			// already shifted cursor dont shift again
+			tok.Pos = pos
 			return tok
 		}
 
 	}
 
+	tok.Pos = pos
 	l.next()
 	return tok
 }
@@ -149,7 +153,11 @@ func (l *Lexer) nextSymbolToken() token.Token {
 	case '+':
 		tok = newRuneToken(token.ADD, l.ch)
 	case '-':
-		tok = newRuneToken(token.SUB, l.ch)
+		if l.matchAndConsume('>') {
+			tok = newStringToken(token.ARROW, "->")
+		} else {
+			tok = newRuneToken(token.SUB, l.ch)
+		}
 	case '*':
 		tok = newRuneToken(token.MUL, l.ch)
 	case '/':
@@ -191,7 +199,25 @@ func (l *Lexer) nextSymbolToken() token.Token {
 	case ':':
 		tok = newRuneToken(token.COLON, l.ch)
 	case '?':
-		tok = newRuneToken(token.Q_MARK, l.ch)
+		if l.matchAndConsume('.') {
+			tok = newStringToken(token.OPT_DOT, "?.")
+		} else if l.matchAndConsume('[') {
+			tok = newStringToken(token.OPT_LBRACKET, "?[")
+		} else if l.matchAndConsume('?') {
+			tok = newStringToken(token.COALESCE, "??")
+		} else {
+			tok = newRuneToken(token.Q_MARK, l.ch)
+		}
+	case '.':
+		if l.matchAndConsume('.') {
+			if l.matchAndConsume('.') {
+				tok = newStringToken(token.ELLIPSIS, "...")
+			} else {
+				tok = newStringToken(token.ILLEGAL, "..")
+			}
+		} else {
+			tok = newRuneToken(token.DOT, l.ch)
+		}
 	default:
 		tok = newRuneToken(token.ILLEGAL, l.ch)
 	}
@@ -73,3 +73,41 @@ let ten = 10;
 		}
 	}
 }
+
+func TestOptionalOperators(t *testing.T) {
+	input := `a?.b; a? .b; a ?? b; a?[0]`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.IDENTIFIER, "a"},
+		{token.OPT_DOT, "?."},
+		{token.IDENTIFIER, "b"},
+		{token.SEMICOLON, ";"},
+		{token.IDENTIFIER, "a"},
+		{token.Q_MARK, "?"},
+		{token.DOT, "."},
+		{token.IDENTIFIER, "b"},
+		{token.SEMICOLON, ";"},
+		{token.IDENTIFIER, "a"},
+		{token.COALESCE, "??"},
+		{token.IDENTIFIER, "b"},
+		{token.SEMICOLON, ";"},
+		{token.IDENTIFIER, "a"},
+		{token.OPT_LBRACKET, "?["},
+		{token.INTEGER, "0"},
+		{token.RBRACKET, "]"},
+		{token.EOF, "EOF"},
+	}
+
+	l := New(input, "test.an")
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType || tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - expected %q %q, got %q %q",
+				i, tt.expectedType, tt.expectedLiteral, tok.Type, tok.Literal)
+		}
+	}
+}
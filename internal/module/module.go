@@ -0,0 +1,202 @@
+package module
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/mantton/anthe/internal/ast"
+	"github.com/mantton/anthe/internal/lexer"
+	"github.com/mantton/anthe/internal/parser"
+)
+
+const EXTENSION = ".an"
+
+// a parsed source file
+type Module struct {
+	Name    string // the name a whole module import binds, geometry for lib/geometry
+	Path    string // absolute path of the file
+	Symbol  string // unique among the loaded modules, used to mangle the module's symbols
+	Program *ast.Program
+}
+
+// a dependency imports refer to by name, "geometry" is its entry and "geometry/shapes" is shapes.an in one of its roots
+type Package struct {
+	Entry string
+	Roots []string
+}
+
+/*
+Loads a program and the modules it imports.
+Paths starting with ./ or ../ are relative to the importing file. Paths starting with the name of a
+package are looked up in that package, others in the directory of the entry file and then in each
+directory of the search path.
+*/
+type Resolver struct {
+	SearchPath []string
+	Packages   map[string]Package
+
+	root    string
+	modules map[string]*Module
+	loading []string // files being loaded, the importing file before the file it imports
+	order   []*Module
+}
+
+func NewResolver(searchPath []string) *Resolver {
+	return &Resolver{SearchPath: searchPath, modules: make(map[string]*Module)}
+}
+
+// loads entry and its imports, returning the modules in dependency order, entry last
+func (r *Resolver) Load(entry string) ([]*Module, error) {
+	path, err := filepath.Abs(entry)
+
+	if err != nil {
+		return nil, err
+	}
+
+	r.root = filepath.Dir(path)
+
+	if _, err := r.load(path); err != nil {
+		return nil, err
+	}
+
+	return r.order, nil
+}
+
+func (r *Resolver) load(path string) (*Module, error) {
+	if m, ok := r.modules[path]; ok {
+		return m, nil
+	}
+
+	for i, loading := range r.loading {
+		if loading == path {
+			cycle := []string{}
+			for _, p := range append(r.loading[i:], path) {
+				cycle = append(cycle, r.relative(p))
+			}
+
+			return nil, fmt.Errorf("import cycle: %s", strings.Join(cycle, " -> "))
+		}
+	}
+
+	r.loading = append(r.loading, path)
+	defer func() { r.loading = r.loading[:len(r.loading)-1] }()
+
+	data, err := os.ReadFile(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	prog := parser.New(lexer.New(string(data), r.relative(path))).ParseProgram()
+
+	if len(prog.Errors) > 0 {
+		return nil, errors.New(strings.Join(prog.Errors, "\n"))
+	}
+
+	for _, s := range prog.Statements {
+		imp, ok := s.(*ast.ImportStatement)
+
+		if !ok {
+			continue
+		}
+
+		resolved, err := r.resolve(filepath.Dir(path), imp.Path)
+
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", imp.Pos(), err.Error())
+		}
+
+		imp.Resolved = resolved
+
+		if _, err := r.load(resolved); err != nil {
+			return nil, err
+		}
+	}
+
+	rel := strings.TrimSuffix(r.relative(path), EXTENSION)
+	m := &Module{
+		Name:    filepath.Base(rel),
+		Path:    path,
+		Symbol:  symbol(rel),
+		Program: prog,
+	}
+
+	r.modules[path] = m
+	r.order = append(r.order, m)
+	return m, nil
+}
+
+// the file an import path refers to from a file in dir
+func (r *Resolver) resolve(dir, spec string) (string, error) {
+	if filepath.Ext(spec) == "" {
+		spec += EXTENSION
+	}
+
+	candidates := []string{}
+	name, rest, nested := strings.Cut(strings.TrimSuffix(spec, EXTENSION), "/")
+	pkg, isPackage := r.Packages[name]
+
+	switch {
+	case strings.HasPrefix(spec, "./") || strings.HasPrefix(spec, "../"):
+		candidates = append(candidates, filepath.Join(dir, spec))
+	case isPackage && !nested:
+		candidates = append(candidates, pkg.Entry)
+	case isPackage:
+		for _, root := range pkg.Roots {
+			candidates = append(candidates, filepath.Join(root, rest+EXTENSION))
+		}
+	default:
+		for _, base := range append([]string{r.root}, r.SearchPath...) {
+			candidates = append(candidates, filepath.Join(base, spec))
+		}
+	}
+
+	for _, c := range candidates {
+		if info, err := os.Stat(c); err == nil && !info.IsDir() {
+			return filepath.Abs(c)
+		}
+	}
+
+	return "", fmt.Errorf("cannot find module `%s`", strings.TrimSuffix(spec, EXTENSION))
+}
+
+// path relative to the entry file's directory, used in messages
+func (r *Resolver) relative(path string) string {
+	if rel, err := filepath.Rel(r.root, path); err == nil {
+		return rel
+	}
+
+	return path
+}
+
+/*
+Each component of the path is prefixed with its length, so paths that only differ in their separators
+get distinct symbols, lib/geometry becomes 3lib8geometry and lib_geometry becomes 12lib_geometry.
+Bytes other than letters, digits, `_`, `-` and `.` are written as `$` and their hex code.
+*/
+func symbol(rel string) string {
+	var b strings.Builder
+
+	for _, part := range strings.Split(filepath.ToSlash(rel), "/") {
+		var escaped strings.Builder
+
+		for i := 0; i < len(part); i++ {
+			ch := part[i]
+
+			if ch < utf8.RuneSelf && (unicode.IsLetter(rune(ch)) || unicode.IsDigit(rune(ch)) || strings.IndexByte("_-.", ch) >= 0) {
+				escaped.WriteByte(ch)
+			} else {
+				fmt.Fprintf(&escaped, "$%02x", ch)
+			}
+		}
+
+		fmt.Fprintf(&b, "%d%s", escaped.Len(), escaped.String())
+	}
+
+	return b.String()
+}
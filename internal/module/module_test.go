@@ -0,0 +1,109 @@
+package module
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+
+	for name, src := range files {
+		path := filepath.Join(dir, name)
+
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return dir
+}
+
+func TestResolverOrder(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"main.an":         `import "lib/geometry"; import { rect } from "./shapes";`,
+		"shapes.an":       `import { area } from "lib/geometry"; export func rect(w, h) { area(w, h) }`,
+		"lib/geometry.an": `export func area(w, h) { w * h }`,
+	})
+
+	modules, err := NewResolver(nil).Load(filepath.Join(dir, "main.an"))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"3lib8geometry", "6shapes", "4main"}
+
+	if len(modules) != len(expected) {
+		t.Fatalf("expected %d modules, got %d", len(expected), len(modules))
+	}
+
+	for i, symbol := range expected {
+		if modules[i].Symbol != symbol {
+			t.Errorf("module %d: expected %s, got %s", i, symbol, modules[i].Symbol)
+		}
+	}
+
+	if modules[0].Name != "geometry" {
+		t.Errorf("expected module name geometry, got %s", modules[0].Name)
+	}
+}
+
+func TestSymbols(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"main.an":         `import "lib/geometry"; import "./lib_geometry"; import { area } from "./lib-geometry";`,
+		"lib/geometry.an": `export func area(w, h) { w * h }`,
+		"lib_geometry.an": `export func area(w, h) { w + h }`,
+		"lib-geometry.an": `export func area(w, h) { w - h }`,
+	})
+
+	modules, err := NewResolver(nil).Load(filepath.Join(dir, "main.an"))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	seen := map[string]string{}
+
+	for _, m := range modules {
+		if other, ok := seen[m.Symbol]; ok {
+			t.Errorf("%s and %s share the symbol %s", other, m.Path, m.Symbol)
+		}
+
+		seen[m.Symbol] = m.Path
+	}
+}
+
+func TestResolverSearchPath(t *testing.T) {
+	lib := writeFiles(t, map[string]string{"util.an": `export const one = 1;`})
+	dir := writeFiles(t, map[string]string{"main.an": `import { one } from "util";`})
+
+	if _, err := NewResolver(nil).Load(filepath.Join(dir, "main.an")); err == nil {
+		t.Errorf("expected util to be missing without a search path")
+	}
+
+	if _, err := NewResolver([]string{lib}).Load(filepath.Join(dir, "main.an")); err != nil {
+		t.Errorf("expected util to be found on the search path, got %v", err)
+	}
+}
+
+func TestResolverCycle(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"main.an": `import "./a";`,
+		"a.an":    `import "./b";`,
+		"b.an":    `import "./a";`,
+	})
+
+	_, err := NewResolver(nil).Load(filepath.Join(dir, "main.an"))
+
+	if err == nil || !strings.Contains(err.Error(), "import cycle: a.an -> b.an -> a.an") {
+		t.Errorf("expected an import cycle error, got %v", err)
+	}
+}
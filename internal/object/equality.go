@@ -0,0 +1,124 @@
+package object
+
+// types that define their own notion of `==`, also used to match them as hash keys and set elements
+type EquatableProtocol interface {
+	Equals(other Object) bool
+}
+
+// the `equals` member of the struct on either side of `==`, with the struct first and the other operand second
+func EqualsMember(left, right Object) (fn Object, self Object, other Object, ok bool) {
+	for _, pair := range [][2]Object{{left, right}, {right, left}} {
+		if s, isStruct := pair[0].(*Structure); isStruct {
+			if fn, ok := s.Members["equals"]; ok {
+				return fn, pair[0], pair[1], true
+			}
+		}
+	}
+
+	return nil, nil, nil, false
+}
+
+/*
+Reports whether two objects are equal by value.
+Objects of different types are never equal, floats follow IEEE 754 so NaN is not equal to itself,
+hashes and sets compare their contents regardless of insertion order and functions compare by identity.
+Types conforming to EquatableProtocol decide on either side of `==`, as elements of other values too.
+*/
+func Equals(a, b Object) bool {
+	if a == b {
+		// same reference, NaN is the only value not equal to itself
+		if f, ok := a.(*Float); ok {
+			return f.Value == f.Value
+		}
+		return true
+	}
+
+	if eq, ok := a.(EquatableProtocol); ok {
+		return eq.Equals(b)
+	}
+
+	if eq, ok := b.(EquatableProtocol); ok {
+		return eq.Equals(a)
+	}
+
+	if a.Type() != b.Type() {
+		return false
+	}
+
+	switch a := a.(type) {
+	case *Integer:
+		return a.Value == b.(*Integer).Value
+	case *Float:
+		return a.Value == b.(*Float).Value
+	case *String:
+		return a.Value == b.(*String).Value
+	case *Boolean:
+		return a.Value == b.(*Boolean).Value
+	case *Null, *Void:
+		return true
+	case *Array:
+		return equalsList(a.Elements, b.(*Array).Elements)
+	case *Tuple:
+		return equalsList(a.Elements, b.(*Tuple).Elements)
+	case *Hash:
+		bh := b.(*Hash)
+
+		if a.Len() != bh.Len() {
+			return false
+		}
+
+		for _, pair := range a.Pairs() {
+			other, ok, err := bh.Get(pair.Key)
+			if err != nil || !ok || !Equals(pair.Value, other) {
+				return false
+			}
+		}
+
+		return true
+	case *Set:
+		bs := b.(*Set)
+
+		if a.Len() != bs.Len() {
+			return false
+		}
+
+		for _, el := range a.Elements() {
+			if ok, err := bs.Has(el); err != nil || !ok {
+				return false
+			}
+		}
+
+		return true
+	case *Structure:
+		bs := b.(*Structure)
+
+		if a.Name != bs.Name || len(a.Members) != len(bs.Members) {
+			return false
+		}
+
+		for name, v := range a.Members {
+			other, ok := bs.Members[name]
+			if !ok || !Equals(v, other) {
+				return false
+			}
+		}
+
+		return true
+	}
+
+	return false
+}
+
+func equalsList(a, b []Object) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if !Equals(a[i], b[i]) {
+			return false
+		}
+	}
+
+	return true
+}
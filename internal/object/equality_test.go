@@ -0,0 +1,75 @@
+package object
+
+import (
+	"strings"
+	"testing"
+)
+
+// a string compared regardless of case
+type caseless struct {
+	Value string
+}
+
+func (c *caseless) Type() ObjectType { return "caseless" }
+func (c *caseless) Inspect() string  { return c.Value }
+
+func (c *caseless) HashKey() HashKey {
+	return (&String{Value: strings.ToLower(c.Value)}).HashKey()
+}
+
+func (c *caseless) Equals(other Object) bool {
+	switch other := other.(type) {
+	case *caseless:
+		return strings.EqualFold(c.Value, other.Value)
+	case *String:
+		return strings.EqualFold(c.Value, other.Value)
+	}
+
+	return false
+}
+
+func TestEquatableProtocol(t *testing.T) {
+	hash := func(key Object) *Hash {
+		h := NewHash()
+		h.Set(key, &Integer{Value: 1})
+		return h
+	}
+
+	set := func(el Object) *Set {
+		s := NewSet()
+		s.Add(el)
+		return s
+	}
+
+	tests := []struct {
+		a, b     Object
+		expected bool
+	}{
+		{&caseless{"Go"}, &caseless{"GO"}, true},
+		{&String{"go"}, &caseless{"GO"}, true},
+		{&caseless{"go"}, &Integer{Value: 1}, false},
+		{&Array{Elements: []Object{&caseless{"A"}}}, &Array{Elements: []Object{&caseless{"a"}}}, true},
+		{&Array{Elements: []Object{&String{"a"}}}, &Array{Elements: []Object{&caseless{"A"}}}, true},
+		{&Tuple{Elements: []Object{&caseless{"A"}}}, &Tuple{Elements: []Object{&caseless{"b"}}}, false},
+		{hash(&caseless{"Key"}), hash(&caseless{"KEY"}), true},
+		{set(&caseless{"x"}), set(&caseless{"X"}), true},
+		{
+			&Structure{Name: "P", Members: map[string]Object{"name": &caseless{"ann"}}},
+			&Structure{Name: "P", Members: map[string]Object{"name": &caseless{"Ann"}}},
+			true,
+		},
+	}
+
+	for _, tt := range tests {
+		if got := Equals(tt.a, tt.b); got != tt.expected {
+			t.Errorf("%s == %s: expected %t, got %t", tt.a.Inspect(), tt.b.Inspect(), tt.expected, got)
+		}
+	}
+
+	// keys match whichever side of the lookup conforms
+	for _, pair := range [][2]Object{{&caseless{"GO"}, &String{"go"}}, {&String{"go"}, &caseless{"GO"}}} {
+		if !KeysEqual(pair[0], pair[1]) {
+			t.Errorf("expected %s and %s to be the same key", pair[0].Inspect(), pair[1].Inspect())
+		}
+	}
+}
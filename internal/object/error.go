@@ -0,0 +1,104 @@
+package object
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mantton/anthe/internal/token"
+)
+
+// a single frame of an Anthe stack trace
+type TraceFrame struct {
+	Function string
+	Position token.Position // position within the function when the error passed through it
+}
+
+/*
+Runtime error raised while evaluating Anthe code.
+As the error unwinds through function calls each function is recorded in Trace, innermost first.
+*/
+type Error struct {
+	Message  string
+	Position token.Position // where the error was raised
+	Trace    []TraceFrame
+	Value    Object // the value thrown, nil for errors raised by the runtime or thrown as errors
+
+	at token.Position // position within the frame currently unwinding
+}
+
+// calls nested deeper than this raise a runtime error, unless the backend is configured otherwise
+const MAX_CALL_DEPTH = 10000
+
+// raised by the call that would exceed max, in place of overflowing the Go stack
+func CallDepthError(max int) error {
+	return fmt.Errorf("maximum call depth of %d exceeded", max)
+}
+
+func NewError(pos token.Position, message string) *Error {
+	return &Error{Message: message, Position: pos, at: pos}
+}
+
+// raised by throw, errors restart their trace at pos and any other value is carried along with the error
+func Thrown(pos token.Position, val Object) *Error {
+	if rErr, ok := val.(*Error); ok {
+		return &Error{Message: rErr.Message, Position: pos, Value: rErr.Value, at: pos}
+	}
+
+	return &Error{Message: val.Inspect(), Position: pos, Value: val, at: pos}
+}
+
+// the value a catch clause binds, what was thrown unless the error was raised as one
+func (e *Error) Caught() Object {
+	if e.Value != nil {
+		return e.Value
+	}
+
+	return e
+}
+
+func (e *Error) Type() ObjectType { return ERROR }
+func (e *Error) Inspect() string  { return "error: " + e.Message }
+func (e *Error) Error() string    { return e.Message }
+
+// records the function the error is unwinding out of, callSite is where that function was called from
+func (e *Error) Unwind(function string, callSite token.Position) {
+	if function == "" {
+		function = "<anonymous>"
+	}
+
+	e.Trace = append(e.Trace, TraceFrame{Function: function, Position: e.at})
+	e.at = callSite
+}
+
+// human readable traceback, innermost call first, runs of the same frame are collapsed
+func (e *Error) Traceback() string {
+	var sb strings.Builder
+
+	sb.WriteString("runtime error: " + e.Message)
+
+	for i := 0; i < len(e.Trace); i++ {
+		frame := e.Trace[i]
+
+		pos := "<unknown>"
+		if frame.Position.Line != 0 {
+			pos = frame.Position.String()
+		}
+
+		sb.WriteString(fmt.Sprintf("\n    at %s (%s)", frame.Function, pos))
+
+		repeated := 0
+		for i+1 < len(e.Trace) && e.Trace[i+1] == frame {
+			repeated++
+			i++
+		}
+
+		switch {
+		case repeated == 1:
+			sb.WriteString("\n    ... repeated 1 more time")
+		case repeated > 1:
+			sb.WriteString(fmt.Sprintf("\n    ... repeated %d more times", repeated))
+		}
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,248 @@
+package object
+
+import "fmt"
+
+/*
+Frozen containers reject every change to their contents. Freezing is deep, whatever a frozen
+value reaches is frozen along with it, and cannot be undone. Tuples are immutable to begin with.
+Constants hold a frozen copy of their value, so other bindings of it stay mutable.
+*/
+
+// freezes obj and everything reachable from it, returning obj
+func Freeze(obj Object) Object {
+	switch obj := obj.(type) {
+	case *Array:
+		if obj.frozen {
+			break
+		}
+
+		obj.frozen = true
+		for _, el := range obj.Elements {
+			Freeze(el)
+		}
+	case *Tuple:
+		for _, el := range obj.Elements {
+			Freeze(el)
+		}
+	case *Hash:
+		if obj.frozen {
+			break
+		}
+
+		obj.frozen = true
+		for _, pair := range obj.pairs {
+			Freeze(pair.Key)
+			Freeze(pair.Value)
+		}
+	case *Set:
+		if obj.frozen {
+			break
+		}
+
+		obj.frozen = true
+		for _, el := range obj.elements {
+			Freeze(el)
+		}
+	case *Structure:
+		if obj.frozen {
+			break
+		}
+
+		obj.frozen = true
+		for _, member := range obj.Members {
+			Freeze(member)
+		}
+	case *Result:
+		Freeze(obj.Value)
+	}
+
+	return obj
+}
+
+/*
+Returns a frozen copy of obj and everything reachable from it, obj itself stays as it was so other
+bindings of it can still change it. Values that are already frozen are shared rather than copied.
+*/
+func FrozenCopy(obj Object) Object {
+	return freezeCopy(obj, make(map[Object]Object))
+}
+
+// copies holds the copy made of each container, so values reachable more than once, or from themselves, are copied once
+func freezeCopy(obj Object, copies map[Object]Object) Object {
+	if !IsFrozen(obj) {
+		if copied, ok := copies[obj]; ok {
+			return copied
+		}
+	}
+
+	switch obj := obj.(type) {
+	case *Array:
+		if obj.frozen {
+			break
+		}
+
+		arr := &Array{Elements: make([]Object, len(obj.Elements)), frozen: true}
+		copies[obj] = arr
+
+		for i, el := range obj.Elements {
+			arr.Elements[i] = freezeCopy(el, copies)
+		}
+
+		return arr
+	case *Tuple:
+		elements := make([]Object, len(obj.Elements))
+		copied := false
+
+		for i, el := range obj.Elements {
+			elements[i] = freezeCopy(el, copies)
+			copied = copied || elements[i] != el
+		}
+
+		if copied {
+			return &Tuple{Elements: elements}
+		}
+	case *Hash:
+		if obj.frozen {
+			break
+		}
+
+		// keys are stored frozen already
+		h := &Hash{pairs: make([]HashPair, len(obj.pairs)), buckets: copyBuckets(obj.buckets), frozen: true}
+		copies[obj] = h
+
+		for i, pair := range obj.pairs {
+			h.pairs[i] = HashPair{Key: pair.Key, Value: freezeCopy(pair.Value, copies)}
+		}
+
+		return h
+	case *Set:
+		if obj.frozen {
+			break
+		}
+
+		// as are the elements of sets
+		return &Set{elements: append([]Object{}, obj.elements...), buckets: copyBuckets(obj.buckets), frozen: true}
+	case *Structure:
+		if obj.frozen {
+			break
+		}
+
+		st := &Structure{Name: obj.Name, Members: make(map[string]Object, len(obj.Members)), frozen: true}
+		copies[obj] = st
+
+		for name, member := range obj.Members {
+			st.Members[name] = freezeCopy(member, copies)
+		}
+
+		return st
+	case *Result:
+		if value := freezeCopy(obj.Value, copies); value != obj.Value {
+			return &Result{Ok: obj.Ok, Value: value}
+		}
+	}
+
+	return obj
+}
+
+func copyBuckets(buckets map[HashKey][]int) map[HashKey][]int {
+	copied := make(map[HashKey][]int, len(buckets))
+
+	for key, indices := range buckets {
+		copied[key] = append([]int{}, indices...)
+	}
+
+	return copied
+}
+
+/*
+Keys of hashes and elements of sets are stored frozen, so changing the value a key was made from cannot
+leave it in the wrong bucket. Mutable keys are copied before freezing, the caller's value stays mutable.
+*/
+func frozenKey(obj Object) Object {
+	switch obj := obj.(type) {
+	case *Array:
+		if obj.frozen {
+			break
+		}
+
+		elements := make([]Object, len(obj.Elements))
+		for i, el := range obj.Elements {
+			elements[i] = frozenKey(el)
+		}
+
+		return &Array{Elements: elements, frozen: true}
+	case *Tuple:
+		elements := make([]Object, len(obj.Elements))
+		copied := false
+
+		for i, el := range obj.Elements {
+			elements[i] = frozenKey(el)
+			copied = copied || elements[i] != el
+		}
+
+		if copied {
+			return &Tuple{Elements: elements}
+		}
+	case *Structure:
+		if obj.frozen {
+			break
+		}
+
+		members := make(map[string]Object, len(obj.Members))
+		for name, member := range obj.Members {
+			members[name] = frozenKey(member)
+		}
+
+		return &Structure{Name: obj.Name, Members: members, frozen: true}
+	}
+
+	return obj
+}
+
+// whether the contents of obj may no longer change, values without contents count as frozen
+func IsFrozen(obj Object) bool {
+	switch obj := obj.(type) {
+	case *Array:
+		return obj.frozen
+	case *Hash:
+		return obj.frozen
+	case *Set:
+		return obj.frozen
+	case *Structure:
+		return obj.frozen
+	}
+
+	return true
+}
+
+func frozenError(obj Object) error {
+	return fmt.Errorf("cannot modify a frozen %s", obj.Type())
+}
+
+// replaces the element at idx
+func (n *Array) SetElement(idx int64, value Object) error {
+	if n.frozen {
+		return frozenError(n)
+	}
+
+	if idx < 0 || idx >= int64(len(n.Elements)) {
+		return fmt.Errorf("index out of range")
+	}
+
+	n.Elements[idx] = value
+	return nil
+}
+
+// replaces the value of an existing member
+func (n *Structure) SetMember(name string, value Object) error {
+	if n.frozen {
+		return frozenError(n)
+	}
+
+	if _, ok := n.Members[name]; !ok {
+		return fmt.Errorf("`%s` has no member `%s`", n.Name, name)
+	}
+
+	n.Members[name] = value
+	return nil
+}
@@ -0,0 +1,202 @@
+package object
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"sort"
+)
+
+/*
+Keys of a hash are bucketed by their HashKey and then compared with
+KeysEqual, so two distinct keys with colliding hashes are both kept.
+*/
+
+// create an empty hash
+func NewHash() *Hash {
+	return &Hash{buckets: make(map[HashKey][]int)}
+}
+
+// sets the value for the given key, keeping the original position if the key already exists
+func (n *Hash) Set(key, value Object) error {
+	if n.frozen {
+		return frozenError(n)
+	}
+
+	hashed, err := HashKeyOf(key)
+
+	if err != nil {
+		return err
+	}
+
+	if idx, ok := n.find(hashed, key); ok {
+		n.pairs[idx].Value = value
+		return nil
+	}
+
+	n.buckets[hashed] = append(n.buckets[hashed], len(n.pairs))
+	n.pairs = append(n.pairs, HashPair{Key: frozenKey(key), Value: value})
+	return nil
+}
+
+// returns the value for the given key, false if the key is not present
+func (n *Hash) Get(key Object) (Object, bool, error) {
+	hashed, err := HashKeyOf(key)
+
+	if err != nil {
+		return nil, false, err
+	}
+
+	if idx, ok := n.find(hashed, key); ok {
+		return n.pairs[idx].Value, true, nil
+	}
+
+	return nil, false, nil
+}
+
+// returns the pairs of the hash in insertion order
+func (n *Hash) Pairs() []HashPair {
+	return n.pairs
+}
+
+func (n *Hash) Len() int {
+	return len(n.pairs)
+}
+
+func (n *Hash) find(hashed HashKey, key Object) (int, bool) {
+	for _, idx := range n.buckets[hashed] {
+		if KeysEqual(n.pairs[idx].Key, key) {
+			return idx, true
+		}
+	}
+
+	return 0, false
+}
+
+// returns the hash key of an object, composite values are hashed from their elements
+func HashKeyOf(obj Object) (HashKey, error) {
+	switch obj := obj.(type) {
+	case HashableProtocol:
+		return obj.HashKey(), nil
+	case *Array:
+		return hashComposite(obj.Type(), nil, obj.Elements)
+	case *Tuple:
+		return hashComposite(obj.Type(), nil, obj.Elements)
+	case *Structure:
+		names := sortedMemberNames(obj)
+		values := make([]Object, 0, len(names))
+
+		for _, name := range names {
+			values = append(values, obj.Members[name])
+		}
+
+		return hashComposite(obj.Type(), append([]string{obj.Name}, names...), values)
+	}
+
+	return HashKey{}, fmt.Errorf("%s does not conform to the `hashable` protocol", obj.Type())
+}
+
+func hashComposite(t ObjectType, names []string, values []Object) (HashKey, error) {
+	h := fnv.New64a()
+	buf := make([]byte, 8)
+
+	for _, name := range names {
+		h.Write([]byte(name))
+		h.Write([]byte{0})
+	}
+
+	for _, v := range values {
+		key, err := HashKeyOf(v)
+
+		if err != nil {
+			return HashKey{}, err
+		}
+
+		h.Write([]byte(key.Type))
+		binary.LittleEndian.PutUint64(buf, key.Value)
+		h.Write(buf)
+	}
+
+	return HashKey{Type: t, Value: h.Sum64()}, nil
+}
+
+func sortedMemberNames(s *Structure) []string {
+	names := make([]string, 0, len(s.Members))
+
+	for name := range s.Members {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+/*
+Reports whether two objects are the same hash key.
+Floats follow SameValueZero: -0 matches 0 and NaN matches NaN, unlike `==`.
+Types conforming to EquatableProtocol decide whichever side of the lookup they are on.
+*/
+func KeysEqual(a, b Object) bool {
+	if eq, ok := a.(EquatableProtocol); ok {
+		return eq.Equals(b)
+	}
+
+	if eq, ok := b.(EquatableProtocol); ok {
+		return eq.Equals(a)
+	}
+
+	if a.Type() != b.Type() {
+		return false
+	}
+
+	switch a := a.(type) {
+	case *Integer:
+		return a.Value == b.(*Integer).Value
+	case *Float:
+		bv := b.(*Float).Value
+		if math.IsNaN(a.Value) && math.IsNaN(bv) {
+			return true
+		}
+		return a.Value == bv
+	case *String:
+		return a.Value == b.(*String).Value
+	case *Boolean:
+		return a.Value == b.(*Boolean).Value
+	case *Array:
+		return keysEqualList(a.Elements, b.(*Array).Elements)
+	case *Tuple:
+		return keysEqualList(a.Elements, b.(*Tuple).Elements)
+	case *Structure:
+		bs := b.(*Structure)
+
+		if a.Name != bs.Name || len(a.Members) != len(bs.Members) {
+			return false
+		}
+
+		for name, v := range a.Members {
+			other, ok := bs.Members[name]
+			if !ok || !KeysEqual(v, other) {
+				return false
+			}
+		}
+
+		return true
+	}
+
+	return a == b
+}
+
+func keysEqualList(a, b []Object) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if !KeysEqual(a[i], b[i]) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -3,6 +3,8 @@ package object
 import (
 	"fmt"
 	"hash/fnv"
+	"math"
+	"strings"
 
 	"github.com/mantton/anthe/internal/ast"
 )
@@ -27,6 +29,12 @@ const (
 	BUILTIN      = "builtin_function"
 	STRING       = "string"
 	FLOAT        = "float"
+	STRUCTURE    = "struct"
+	ERROR        = "error"
+	RESULT       = "result"
+	SET          = "set"
+	TUPLE        = "tuple"
+	MODULE       = "module"
 )
 
 type HashKey struct {
@@ -68,10 +76,30 @@ type ReturnValue struct {
 
 type Array struct {
 	Elements []Object
+	frozen   bool
+}
+
+// fixed size, immutable list of values
+type Tuple struct {
+	Elements []Object
 }
 
 type Hash struct {
-	Pairs map[HashKey]HashPair
+	pairs   []HashPair        // insertion order
+	buckets map[HashKey][]int // hash key to indices of pairs sharing it
+	frozen  bool
+}
+
+type Set struct {
+	elements []Object          // insertion order
+	buckets  map[HashKey][]int // hash key to indices of elements sharing it
+	frozen   bool
+}
+
+// either a successful value or an error value, created by `ok(v)` and `err(e)`
+type Result struct {
+	Ok    bool
+	Value Object
 }
 
 type Builtin struct {
@@ -83,12 +111,26 @@ type Function struct {
 	Name       string
 	Parameters []*ast.IdentifierExpression
 	Body       *ast.BlockStatement
+	FrameSize  int // slots of a call frame
+}
+
+// constructor bound by a struct declaration, called with field values in declaration order
+type StructDefinition struct {
+	Name   string
+	Fields []string
+}
+
+// the exports of an imported module, bound by `import "path"`
+type Module struct {
+	Name    string
+	Exports map[string]Object
 }
 
 type Structure struct {
 	// Parent     *Structure
 	Name    string
 	Members map[string]Object
+	frozen  bool
 	// Methods    map[string]Function
 	// Protocols  map[string]string
 }
@@ -122,14 +164,62 @@ func (n *ReturnValue) Type() ObjectType { return RETURN_VALUE }
 func (n *ReturnValue) Inspect() string  { return n.Value.Inspect() }
 
 func (n *Array) Type() ObjectType { return ARRAY }
-func (n *Array) Inspect() string  { return "ARRAYYYYYY" }
+func (n *Array) Inspect() string {
+	elems := []string{}
+	for _, e := range n.Elements {
+		elems = append(elems, e.Inspect())
+	}
+
+	return "[" + strings.Join(elems, ", ") + "]"
+}
+
+func (t *Tuple) Type() ObjectType { return TUPLE }
+func (t *Tuple) Inspect() string {
+	elements := []string{}
+	for _, el := range t.Elements {
+		elements = append(elements, el.Inspect())
+	}
+
+	return "(" + strings.Join(elements, ", ") + ")"
+}
 
 func (n *Hash) Type() ObjectType { return HASH }
-func (n *Hash) Inspect() string  { return "HASH" }
+func (n *Hash) Inspect() string {
+	pairs := []string{}
+	for _, pair := range n.Pairs() {
+		pairs = append(pairs, pair.Key.Inspect()+": "+pair.Value.Inspect())
+	}
+
+	return "{" + strings.Join(pairs, ", ") + "}"
+}
 
 func (n *Function) Type() ObjectType { return FUNCTION }
 func (n *Function) Inspect() string  { return "FUNCTION" }
 
+func (d *StructDefinition) Type() ObjectType { return BUILTIN }
+func (d *StructDefinition) Inspect() string  { return "struct " + d.Name }
+
+func (m *Module) Type() ObjectType { return MODULE }
+func (m *Module) Inspect() string  { return "module " + m.Name }
+
+func (n *Structure) Type() ObjectType { return STRUCTURE }
+func (n *Structure) Inspect() string {
+	members := []string{}
+	for _, name := range sortedMemberNames(n) {
+		members = append(members, name+": "+n.Members[name].Inspect())
+	}
+
+	return n.Name + " {" + strings.Join(members, ", ") + "}"
+}
+
+func (r *Result) Type() ObjectType { return RESULT }
+func (r *Result) Inspect() string {
+	if r.Ok {
+		return "ok(" + r.Value.Inspect() + ")"
+	}
+	return "err(" + r.Value.Inspect() + ")"
+}
+
 func (b *String) Type() ObjectType { return STRING }
 func (b *String) Inspect() string  { return b.Value }
 func (s *String) HashKey() HashKey {
@@ -142,5 +232,15 @@ func (s *String) HashKey() HashKey {
 func (i *Float) Type() ObjectType { return FLOAT }
 func (i *Float) Inspect() string  { return fmt.Sprintf("%.1f", i.Value) }
 func (i *Float) HashKey() HashKey {
-	return HashKey{Type: i.Type(), Value: uint64(i.Value)}
+	v := i.Value
+
+	// -0 and 0 are the same key, as are all NaNs
+	switch {
+	case v == 0:
+		v = 0
+	case math.IsNaN(v):
+		v = math.NaN()
+	}
+
+	return HashKey{Type: i.Type(), Value: math.Float64bits(v)}
 }
@@ -0,0 +1,111 @@
+package object
+
+import "strings"
+
+/*
+Sets keep their elements in insertion order and bucket them like hash keys,
+so only values conforming to the `hashable` protocol may be added.
+*/
+
+// create an empty set
+func NewSet() *Set {
+	return &Set{buckets: make(map[HashKey][]int)}
+}
+
+// adds the element if it is not already present
+func (n *Set) Add(el Object) error {
+	if n.frozen {
+		return frozenError(n)
+	}
+
+	hashed, err := HashKeyOf(el)
+
+	if err != nil {
+		return err
+	}
+
+	if _, ok := n.find(hashed, el); ok {
+		return nil
+	}
+
+	n.buckets[hashed] = append(n.buckets[hashed], len(n.elements))
+	n.elements = append(n.elements, frozenKey(el))
+	return nil
+}
+
+func (n *Set) Has(el Object) (bool, error) {
+	hashed, err := HashKeyOf(el)
+
+	if err != nil {
+		return false, err
+	}
+
+	_, ok := n.find(hashed, el)
+	return ok, nil
+}
+
+// returns the elements of the set in insertion order
+func (n *Set) Elements() []Object {
+	return n.elements
+}
+
+func (n *Set) Len() int {
+	return len(n.elements)
+}
+
+func (n *Set) find(hashed HashKey, el Object) (int, bool) {
+	for _, idx := range n.buckets[hashed] {
+		if KeysEqual(n.elements[idx], el) {
+			return idx, true
+		}
+	}
+
+	return 0, false
+}
+
+// elements of either set, those of n first
+func (n *Set) Union(other *Set) *Set {
+	result := NewSet()
+
+	for _, el := range n.elements {
+		result.Add(el)
+	}
+
+	for _, el := range other.elements {
+		result.Add(el)
+	}
+
+	return result
+}
+
+// elements of n also in other
+func (n *Set) Intersection(other *Set) *Set {
+	return n.filter(other, true)
+}
+
+// elements of n not in other
+func (n *Set) Difference(other *Set) *Set {
+	return n.filter(other, false)
+}
+
+func (n *Set) filter(other *Set, keep bool) *Set {
+	result := NewSet()
+
+	for _, el := range n.elements {
+		if ok, _ := other.Has(el); ok == keep {
+			result.Add(el)
+		}
+	}
+
+	return result
+}
+
+func (n *Set) Type() ObjectType { return SET }
+func (n *Set) Inspect() string {
+	elements := []string{}
+	for _, el := range n.elements {
+		elements = append(elements, el.Inspect())
+	}
+
+	return "{" + strings.Join(elements, ", ") + "}"
+}
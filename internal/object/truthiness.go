@@ -0,0 +1,34 @@
+package object
+
+import "math"
+
+// types that decide how they behave in conditions and under `!`
+type TruthyProtocol interface {
+	Truthy() bool
+}
+
+/*
+Truthiness table
+
+	false, null, void       falsy
+	0, 0.0, NaN             falsy
+	"", [], {}, empty set   falsy
+	everything else         truthy
+*/
+func IsTruthy(obj Object) bool {
+	if t, ok := obj.(TruthyProtocol); ok {
+		return t.Truthy()
+	}
+
+	return true
+}
+
+func (b *Boolean) Truthy() bool { return b.Value }
+func (i *Integer) Truthy() bool { return i.Value != 0 }
+func (i *Float) Truthy() bool   { return i.Value != 0 && !math.IsNaN(i.Value) }
+func (b *String) Truthy() bool  { return b.Value != "" }
+func (n *Array) Truthy() bool   { return len(n.Elements) != 0 }
+func (n *Hash) Truthy() bool    { return n.Len() != 0 }
+func (n *Set) Truthy() bool     { return n.Len() != 0 }
+func (n *Null) Truthy() bool    { return false }
+func (n *Void) Truthy() bool    { return false }
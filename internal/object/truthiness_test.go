@@ -0,0 +1,33 @@
+package object
+
+import (
+	"strings"
+	"testing"
+)
+
+// text that is falsy when blank, unlike a string, which is only falsy when empty
+type text struct {
+	Value string
+}
+
+func (t *text) Type() ObjectType { return "text" }
+func (t *text) Inspect() string  { return t.Value }
+func (t *text) Truthy() bool     { return strings.TrimSpace(t.Value) != "" }
+
+func TestTruthyProtocol(t *testing.T) {
+	tests := []struct {
+		obj      Object
+		expected bool
+	}{
+		{&text{"a"}, true},
+		{&text{"  "}, false},
+		{&Array{Elements: []Object{&text{""}}}, true},
+		{&Integer{Value: 0}, false},
+	}
+
+	for _, tt := range tests {
+		if got := IsTruthy(tt.obj); got != tt.expected {
+			t.Errorf("%s: expected %t, got %t", tt.obj.Inspect(), tt.expected, got)
+		}
+	}
+}
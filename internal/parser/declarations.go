@@ -12,7 +12,7 @@ KEYWORD | IDENTIFIER | EXPRESSION LIST | BOCK_STATEMENT
 func myFunc() {}
 */
 func (p *Parser) parseFunctionDeclaration() (*ast.NamedFunctionDeclaration, error) {
-	expr := &ast.NamedFunctionDeclaration{}
+	expr := &ast.NamedFunctionDeclaration{Token: p.curToken}
 	// on the func keyword
 
 	if !p.consumeIfPeekMatches(token.IDENTIFIER) {
@@ -27,6 +27,18 @@ func (p *Parser) parseFunctionDeclaration() (*ast.NamedFunctionDeclaration, erro
 		return nil, err
 	}
 
+	// generic function, func first<T>(...)
+	if p.consumeIfPeekMatches(token.LSS) {
+		params, err := p.parseTypeParameters()
+
+		if err != nil {
+			return nil, err
+		}
+
+		expr.TypeParameters = params
+		defer p.enterTypeScope(params)()
+	}
+
 	// at this point, it becomes a regular FunctionLiteral, parse that
 
 	fn, err := p.parseFunctionLiteral()
@@ -49,3 +61,138 @@ func (p *Parser) parseFunctionDeclaration() (*ast.NamedFunctionDeclaration, erro
 	return expr, nil
 
 }
+
+/*
+KEYWORD | IDENTIFIER | TYPE PARAMETERS | FIELD LIST
+struct Box<T> { value: T, count: int }
+*/
+func (p *Parser) parseStructDeclaration() (*ast.StructDeclaration, error) {
+	decl := &ast.StructDeclaration{Token: p.curToken}
+
+	if !p.consumeIfPeekMatches(token.IDENTIFIER) {
+		return nil, fmt.Errorf("expected struct name got %s instead", p.peekToken.Literal)
+	}
+
+	decl.Name = p.curToken.Literal
+
+	// declared before the fields so they may refer to the struct itself
+	p.types.Declare(decl.Name)
+
+	if p.consumeIfPeekMatches(token.LSS) {
+		params, err := p.parseTypeParameters()
+
+		if err != nil {
+			return nil, err
+		}
+
+		decl.TypeParameters = params
+		defer p.enterTypeScope(params)()
+	}
+
+	if !p.consumeIfPeekMatches(token.LBRACE) {
+		return nil, fmt.Errorf("expected '{' after struct name got %s instead", p.peekToken.Literal)
+	}
+
+	for !p.peekMatches(token.RBRACE) {
+		if !p.consumeIfPeekMatches(token.IDENTIFIER) {
+			return nil, fmt.Errorf("expected field name got %s instead", p.peekToken.Literal)
+		}
+
+		field := ast.StructField{Name: p.curToken.Literal}
+
+		if !p.consumeIfPeekMatches(token.COLON) {
+			return nil, fmt.Errorf("expected ':' after field name got %s instead", p.peekToken.Literal)
+		}
+
+		p.next()
+
+		t, err := p.parseTypeDeclaration()
+
+		if err != nil {
+			return nil, err
+		}
+
+		field.Type = t
+		decl.Fields = append(decl.Fields, field)
+
+		if !p.consumeIfPeekMatches(token.COMMA) && !p.consumeIfPeekMatches(token.SEMICOLON) && !p.peekMatches(token.RBRACE) {
+			return nil, fmt.Errorf("expected ',' or '}' after field got %s instead", p.peekToken.Literal)
+		}
+	}
+
+	p.next() // on '}'
+
+	if p.peekMatches(token.SEMICOLON) {
+		p.next()
+	}
+
+	return decl, nil
+}
+
+/*
+KEYWORD | IDENTIFIER | ASSIGN or DISTINCT | TYPE
+type UserID = int
+type Meters distinct float
+*/
+func (p *Parser) parseTypeAliasDeclaration() (*ast.TypeDeclaration, error) {
+	decl := &ast.TypeDeclaration{Token: p.curToken}
+
+	if !p.consumeIfPeekMatches(token.IDENTIFIER) {
+		return nil, fmt.Errorf("expected type name got %s instead", p.peekToken.Literal)
+	}
+
+	decl.Name = p.curToken.Literal
+
+	if token.LookUpBuiltInType(decl.Name) != token.IDENTIFIER || p.types.Has(decl.Name) {
+		return nil, fmt.Errorf("type `%s` is already defined", decl.Name)
+	}
+
+	switch {
+	case p.consumeIfPeekMatches(token.ASSIGN):
+	case p.peekMatches(token.IDENTIFIER) && p.peekToken.Literal == "distinct":
+		p.next()
+		decl.Distinct = true
+	default:
+		return nil, fmt.Errorf("expected '=' or `distinct` after type name got %s instead", p.peekToken.Literal)
+	}
+
+	p.next()
+
+	t, err := p.parseTypeDeclaration()
+
+	if err != nil {
+		return nil, err
+	}
+
+	decl.Value = t
+	p.types.Declare(decl.Name)
+
+	if p.peekMatches(token.SEMICOLON) {
+		p.next()
+	}
+
+	return decl, nil
+}
+
+// parses `T, U>` after the '<' of a generic declaration
+func (p *Parser) parseTypeParameters() ([]string, error) {
+	params := []string{}
+
+	for {
+		if !p.consumeIfPeekMatches(token.IDENTIFIER) {
+			return nil, fmt.Errorf("expected type parameter got %s instead", p.peekToken.Literal)
+		}
+
+		params = append(params, p.curToken.Literal)
+
+		if !p.consumeIfPeekMatches(token.COMMA) {
+			break
+		}
+	}
+
+	if !p.consumeIfPeekMatches(token.GTR) {
+		return nil, fmt.Errorf("expected '>' after type parameters got %s instead", p.peekToken.Literal)
+	}
+
+	return params, nil
+}
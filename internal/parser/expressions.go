@@ -81,6 +81,7 @@ func (p *Parser) parseInfixExpression(lhs ast.Expression) (ast.Expression, error
 }
 
 func (p *Parser) parseGroupedExpression() (ast.Expression, error) {
+	tok := p.curToken
 	p.next()
 
 	exp, err := p.parseExpression(LOWEST)
@@ -88,6 +89,15 @@ func (p *Parser) parseGroupedExpression() (ast.Expression, error) {
 		return nil, err
 	}
 
+	// (a, b) is a tuple
+	if p.peekMatches(token.COMMA) {
+		exp, err = p.parseTupleElements(tok, exp, token.RPAREN)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	if !p.consumeIfPeekMatches(token.RPAREN) {
 		return nil, fmt.Errorf("expected ')' got %s instead", p.peekToken.Literal)
 	}
@@ -163,9 +173,9 @@ func (p *Parser) parseCallExpression(function ast.Expression) (ast.Expression, e
 }
 
 func (p *Parser) parseIndexExpression(left ast.Expression) (ast.Expression, error) {
-	exp := &ast.IndexExpression{Token: p.curToken, Left: left}
+	exp := &ast.IndexExpression{Token: p.curToken, Left: left, Optional: p.currentMatches(token.OPT_LBRACKET)}
 
-	p.next() // move away from '['
+	p.next() // move away from '[' or '?['
 	idx, err := p.parseExpression(LOWEST)
 
 	if err != nil {
@@ -182,24 +192,71 @@ func (p *Parser) parseIndexExpression(left ast.Expression) (ast.Expression, erro
 }
 
 func (p *Parser) parseAssignmentExpression(left ast.Expression) (ast.Expression, error) {
+	tok := p.curToken
 
 	switch left := left.(type) {
 	case *ast.IdentifierExpression:
-		expr := &ast.AssignmentExpression{Token: p.curToken, Target: left}
+	case *ast.IndexExpression:
+		if left.Optional {
+			return nil, fmt.Errorf("cannot assign through an optional index")
+		}
+	case *ast.MemberExpression:
+		if left.Optional {
+			return nil, fmt.Errorf("cannot assign through an optional member")
+		}
+	default:
+		return nil, fmt.Errorf("invalid assignment call")
+	}
+
+	p.next() // move to token after `=`
+
+	rhs, err := p.parseExpression(LOWEST)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if ident, ok := left.(*ast.IdentifierExpression); ok {
+		return &ast.AssignmentExpression{Token: tok, Target: ident, Value: rhs}, nil
+	}
+
+	return &ast.ElementAssignmentExpression{Token: tok, Target: left, Value: rhs}, nil
+}
+
+func (p *Parser) parsePropagateExpression(left ast.Expression) (ast.Expression, error) {
+	return &ast.PropagateExpression{Token: p.curToken, Value: left}, nil
+}
+
+func (p *Parser) parseMemberExpression(left ast.Expression) (ast.Expression, error) {
+	exp := &ast.MemberExpression{Token: p.curToken, Object: left, Optional: p.currentMatches(token.OPT_DOT)}
+
+	if !p.consumeIfPeekMatches(token.IDENTIFIER) {
+		return nil, fmt.Errorf("expected member name after '%s' got %s", exp.Token.Literal, p.peekToken.Literal)
+	}
+
+	exp.Property = &ast.IdentifierExpression{Token: p.curToken, Value: p.curToken.Literal}
 
-		p.next() // move to token after `=`
+	return exp, nil
+}
 
-		rhs, err := p.parseExpression(LOWEST)
+// the elements of a tuple after the first, up to but not including end, a trailing comma is allowed
+func (p *Parser) parseTupleElements(tok token.Token, first ast.Expression, end token.TokenType) (*ast.TupleLiteral, error) {
+	tuple := &ast.TupleLiteral{Token: tok, Elements: []ast.Expression{first}}
+
+	for p.consumeIfPeekMatches(token.COMMA) {
+		if p.peekMatches(end) {
+			break
+		}
+
+		p.next()
+		el, err := p.parseExpression(LOWEST)
 
 		if err != nil {
 			return nil, err
 		}
 
-		expr.Value = rhs
-
-		return expr, err
-	default:
-		return nil, fmt.Errorf("invalid assignment call")
+		tuple.Elements = append(tuple.Elements, el)
 	}
 
+	return tuple, nil
 }
@@ -49,13 +49,27 @@ func (p *Parser) parseFunctionLiteral() (ast.Expression, error) {
 		return nil, fmt.Errorf("expected '(' found %s instead", p.peekToken.Literal)
 	}
 
-	params, err := p.parseFunctionParameters()
+	params, paramTypes, err := p.parseFunctionParameters()
 
 	if err != nil {
 		return nil, err
 	}
 
 	lit.Parameters = params
+	lit.ParameterTypes = paramTypes
+
+	// return type, func(x: int) -> int
+	if p.consumeIfPeekMatches(token.ARROW) {
+		p.next()
+
+		t, err := p.parseReturnType()
+
+		if err != nil {
+			return nil, err
+		}
+
+		lit.ReturnType = t
+	}
 
 	if !p.consumeIfPeekMatches(token.LBRACE) {
 		return nil, fmt.Errorf("expected function body found %s instead", p.peekToken.Literal)
@@ -73,36 +87,50 @@ func (p *Parser) parseFunctionLiteral() (ast.Expression, error) {
 		return nil, fmt.Errorf("expected '}' found %s instead", p.peekToken.Literal)
 	}
 
-	p.next()
+	ast.MarkTailCalls(lit.Body)
 	return lit, nil
 }
 
-func (p *Parser) parseFunctionParameters() ([]*ast.IdentifierExpression, error) {
+// parameters with optional type annotations, x, y: int
+func (p *Parser) parseFunctionParameters() ([]*ast.IdentifierExpression, []ast.TypeExpression, error) {
 	identifiers := []*ast.IdentifierExpression{}
+	types := []ast.TypeExpression{}
 
 	if p.peekMatches(token.RPAREN) {
 		p.next()
-		return identifiers, nil
+		return identifiers, types, nil
 	}
 
-	p.next()
-
-	ident := &ast.IdentifierExpression{Token: p.curToken, Value: p.curToken.Literal}
-	identifiers = append(identifiers, ident)
-
-	for p.peekMatches(token.COMMA) {
-		p.next() // move to comma
-		p.next() // move to token after comma
+	for {
+		p.next() // move to parameter name
 
 		ident := &ast.IdentifierExpression{Token: p.curToken, Value: p.curToken.Literal}
 		identifiers = append(identifiers, ident)
+
+		var t ast.TypeExpression
+		if p.consumeIfPeekMatches(token.COLON) {
+			p.next() // move to type
+
+			annotation, err := p.parseTypeDeclaration()
+
+			if err != nil {
+				return nil, nil, err
+			}
+
+			t = annotation
+		}
+		types = append(types, t)
+
+		if !p.consumeIfPeekMatches(token.COMMA) {
+			break
+		}
 	}
 
 	if !p.consumeIfPeekMatches(token.RPAREN) {
-		return nil, fmt.Errorf("expected ')' after parameter list found %s", p.peekToken.Literal)
+		return nil, nil, fmt.Errorf("expected ')' after parameter list found %s", p.peekToken.Literal)
 	}
 
-	return identifiers, nil
+	return identifiers, types, nil
 }
 
 func (p *Parser) parseArrayLiteral() (ast.Expression, error) {
@@ -156,9 +184,10 @@ func (p *Parser) parseExpressionList(end token.TokenType, c rune) ([]ast.Express
 	return list, nil
 }
 
+// `{k: v}` is a hash and `{a, b}` a set, decided by the first entry, `{}` is an empty hash
 func (p *Parser) parseHashLiteral() (ast.Expression, error) {
 	hash := &ast.HashLiteral{Token: p.curToken}
-	hash.Pairs = make(map[ast.Expression]ast.Expression)
+	hash.Pairs = []ast.HashLiteralPair{}
 
 	for !p.peekMatches(token.RBRACE) {
 		p.next()
@@ -168,6 +197,10 @@ func (p *Parser) parseHashLiteral() (ast.Expression, error) {
 			return nil, err
 		}
 
+		if len(hash.Pairs) == 0 && (p.peekMatches(token.COMMA) || p.peekMatches(token.RBRACE)) {
+			return p.parseSetLiteral(hash.Token, key)
+		}
+
 		if !p.consumeIfPeekMatches(token.COLON) {
 			return nil, fmt.Errorf("expected ':' after key found %s", p.peekToken.Literal)
 		}
@@ -180,7 +213,7 @@ func (p *Parser) parseHashLiteral() (ast.Expression, error) {
 			return nil, err
 		}
 
-		hash.Pairs[key] = value
+		hash.Pairs = append(hash.Pairs, ast.HashLiteralPair{Key: key, Value: value})
 
 		if !p.peekMatches(token.RBRACE) && !p.consumeIfPeekMatches(token.COMMA) {
 			return nil, fmt.Errorf("invalid object expression")
@@ -194,6 +227,32 @@ func (p *Parser) parseHashLiteral() (ast.Expression, error) {
 	return hash, nil
 }
 
+// the rest of a set literal, first is the already parsed first element
+func (p *Parser) parseSetLiteral(tok token.Token, first ast.Expression) (ast.Expression, error) {
+	set := &ast.SetLiteral{Token: tok, Elements: []ast.Expression{first}}
+
+	for p.consumeIfPeekMatches(token.COMMA) {
+		if p.peekMatches(token.RBRACE) {
+			break // trailing comma
+		}
+
+		p.next()
+		el, err := p.parseExpression(LOWEST)
+
+		if err != nil {
+			return nil, err
+		}
+
+		set.Elements = append(set.Elements, el)
+	}
+
+	if !p.consumeIfPeekMatches(token.RBRACE) {
+		return nil, fmt.Errorf("expected '}' found %s", p.peekToken.Literal)
+	}
+
+	return set, nil
+}
+
 func (p *Parser) parseStringLiteral() (ast.Expression, error) {
 	return &ast.StringLiteral{Token: p.curToken, Value: p.curToken.Literal}, nil
 }
@@ -0,0 +1,109 @@
+package parser
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"unicode"
+
+	"github.com/mantton/anthe/internal/ast"
+	"github.com/mantton/anthe/internal/token"
+)
+
+/*
+KEYWORD | STRING
+import "lib/geometry"
+
+KEYWORD | NAME LIST | FROM | STRING
+import { area, Point } from "./shapes"
+*/
+func (p *Parser) parseImportStatement() (*ast.ImportStatement, error) {
+	stmt := &ast.ImportStatement{Token: p.curToken}
+
+	if p.consumeIfPeekMatches(token.LBRACE) {
+		stmt.Names = []*ast.IdentifierExpression{}
+
+		for {
+			if !p.consumeIfPeekMatches(token.IDENTIFIER) {
+				return nil, fmt.Errorf("expected imported name got %s instead", p.peekToken.Literal)
+			}
+
+			stmt.Names = append(stmt.Names, &ast.IdentifierExpression{Token: p.curToken, Value: p.curToken.Literal})
+
+			if !p.consumeIfPeekMatches(token.COMMA) || p.peekMatches(token.RBRACE) {
+				break
+			}
+		}
+
+		if !p.consumeIfPeekMatches(token.RBRACE) {
+			return nil, fmt.Errorf("expected '}' after imported names got %s instead", p.peekToken.Literal)
+		}
+
+		if !p.peekMatches(token.IDENTIFIER) || p.peekToken.Literal != "from" {
+			return nil, fmt.Errorf("expected `from` after imported names got %s instead", p.peekToken.Literal)
+		}
+
+		p.next()
+	}
+
+	if !p.consumeIfPeekMatches(token.STRING) {
+		return nil, fmt.Errorf("expected module path got %s instead", p.peekToken.Literal)
+	}
+
+	stmt.Path = p.curToken.Literal
+	stmt.Name = path.Base(strings.TrimSuffix(stmt.Path, ".an"))
+
+	if stmt.Names == nil && !isIdentifier(stmt.Name) {
+		return nil, fmt.Errorf("cannot bind module `%s` to a name, import its exports with `import { ... } from`", stmt.Path)
+	}
+
+	// imported names may be types, the module itself is checked once it is resolved
+	for _, name := range stmt.Names {
+		p.types.Declare(name.Value)
+	}
+
+	if p.peekMatches(token.SEMICOLON) {
+		p.next()
+	}
+
+	return stmt, nil
+}
+
+/*
+KEYWORD | DECLARATION
+export func area(r) { ... }
+*/
+func (p *Parser) parseExportStatement() (*ast.ExportStatement, error) {
+	stmt := &ast.ExportStatement{Token: p.curToken}
+
+	p.next()
+
+	switch p.curToken.Type {
+	case token.LET, token.CONST, token.FUNCTION, token.STRUCT, token.TYPE:
+	default:
+		return nil, fmt.Errorf("expected a declaration after `export` got %s instead", p.curToken.Literal)
+	}
+
+	s, err := p.parseStatement()
+
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := s.(*ast.ExpressionStatement); ok {
+		return nil, fmt.Errorf("anonymous functions cannot be exported")
+	}
+
+	stmt.Statement = s
+	return stmt, nil
+}
+
+func isIdentifier(name string) bool {
+	for _, ch := range name {
+		if !unicode.IsLetter(ch) && ch != '_' {
+			return false
+		}
+	}
+
+	return name != ""
+}
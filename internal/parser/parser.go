@@ -14,10 +14,12 @@ const (
 	ASSIGN      // x = y
 	EQUALS      // ==
 	LESSGREATER // > or <
+	COALESCE    // a ?? b
 	SUM         //+
 	PRODUCT     //*
 	PREFIX      //-Xor!X
 	CALL        // myFunction(X)
+	POSTFIX     // result?
 	INDEX       // array[index]
 )
 
@@ -40,6 +42,12 @@ var precedences = map[token.TokenType]ExpPrecedence{
 	token.MUL:      PRODUCT,
 	token.LPAREN:   CALL,
 	token.LBRACKET: INDEX,
+	token.Q_MARK:   POSTFIX,
+
+	token.DOT:          INDEX,
+	token.OPT_DOT:      INDEX,
+	token.OPT_LBRACKET: INDEX,
+	token.COALESCE:     COALESCE,
 }
 
 type Parser struct {
@@ -50,10 +58,12 @@ type Parser struct {
 
 	prefixParseFns map[token.TokenType]prefixParseFn
 	infixParseFns  map[token.TokenType]infixParseFn
+
+	types *TypeScope
 }
 
 func New(l *lexer.Lexer) *Parser {
-	p := &Parser{l: l}
+	p := &Parser{l: l, types: NewTypeScope(nil)}
 
 	// read two tokens, setting both current and peekToken
 	p.next() // sets peek
@@ -94,6 +104,11 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerInfix(token.LPAREN, p.parseCallExpression)
 	p.registerInfix(token.LBRACKET, p.parseIndexExpression)
 	p.registerInfix(token.ASSIGN, p.parseAssignmentExpression)
+	p.registerInfix(token.Q_MARK, p.parsePropagateExpression)
+	p.registerInfix(token.DOT, p.parseMemberExpression)
+	p.registerInfix(token.OPT_DOT, p.parseMemberExpression)
+	p.registerInfix(token.OPT_LBRACKET, p.parseIndexExpression)
+	p.registerInfix(token.COALESCE, p.parseInfixExpression)
 
 	return p
 }
@@ -62,3 +62,63 @@ func testLetStatement(t *testing.T, s ast.Statement, name string) bool {
 	}
 	return true
 }
+
+func TestTypeNames(t *testing.T) {
+	tests := []struct {
+		input string
+		ok    bool
+	}{
+		{`let x: Foo = 1;`, false},
+		{`type ID = int; let x: ID = 1;`, true},
+		{`type Meters distinct float; let m: Meters = Meters(1.0);`, true},
+		{`type int = float;`, false},
+		{`type ID = int; type ID = string;`, false},
+		{`struct Node { next: Node? }`, true},
+		{`func first<T>(xs: array<T>) -> T { xs[0] }; let y: T = 1;`, false},
+		{`let e: error = error("a");`, true},
+	}
+
+	for _, tt := range tests {
+		program := New(lexer.New(tt.input, "test.an")).ParseProgram()
+
+		if ok := len(program.Errors) == 0; ok != tt.ok {
+			t.Errorf("%s: expected ok=%t, got %t %v", tt.input, tt.ok, ok, program.Errors)
+		}
+	}
+}
+
+func TestDestructuringStatements(t *testing.T) {
+	tests := []struct {
+		input string
+		kind  ast.PatternKind
+		names int
+		rest  bool
+	}{
+		{`let (a, b) = (1, 2);`, ast.TuplePattern, 2, false},
+		{`const [x, y, ...rest] = xs;`, ast.ArrayPattern, 2, true},
+		{`let {name, age} = person;`, ast.ObjectPattern, 2, false},
+	}
+
+	for _, tt := range tests {
+		program := New(lexer.New(tt.input, "test.an")).ParseProgram()
+
+		if len(program.Errors) > 0 {
+			t.Fatalf("%s: failed to parse: %v", tt.input, program.Errors)
+		}
+
+		stmt, ok := program.Statements[0].(*ast.DestructuringStatement)
+		if !ok {
+			t.Fatalf("%s: expected *ast.DestructuringStatement, got %T", tt.input, program.Statements[0])
+		}
+
+		if stmt.Kind != tt.kind || len(stmt.Names) != tt.names || (stmt.Rest != nil) != tt.rest {
+			t.Errorf("%s: unexpected pattern %+v", tt.input, stmt)
+		}
+	}
+
+	for _, input := range []string{`let [...rest, x] = xs;`, `let () = t;`, `let (a, b);`} {
+		if program := New(lexer.New(input, "test.an")).ParseProgram(); len(program.Errors) == 0 {
+			t.Errorf("%s: expected a parse error", input)
+		}
+	}
+}
@@ -10,13 +10,34 @@ import (
 func (p *Parser) parseStatement() (ast.Statement, error) {
 	switch p.curToken.Type {
 	case token.FUNCTION:
+		// anonymous functions are expressions
+		if !p.peekMatches(token.IDENTIFIER) {
+			return p.parseExpressionStatement()
+		}
 		return p.parseFunctionDeclaration()
-	case token.LET:
-		return p.parseLetStatement()
-	case token.CONST:
+	case token.LET, token.CONST:
+		if p.peekMatches(token.LPAREN) || p.peekMatches(token.LBRACKET) || p.peekMatches(token.LBRACE) {
+			return p.parseDestructuringStatement()
+		}
+
+		if p.curToken.Type == token.LET {
+			return p.parseLetStatement()
+		}
 		return p.parseConstStatement()
 	case token.RETURN:
 		return p.parseReturnStatement()
+	case token.STRUCT:
+		return p.parseStructDeclaration()
+	case token.TYPE:
+		return p.parseTypeAliasDeclaration()
+	case token.THROW:
+		return p.parseThrowStatement()
+	case token.TRY:
+		return p.parseTryStatement()
+	case token.IMPORT:
+		return p.parseImportStatement()
+	case token.EXPORT:
+		return p.parseExportStatement()
 	default:
 		return p.parseExpressionStatement()
 	}
@@ -120,6 +141,79 @@ func (p *Parser) parseConstStatement() (*ast.ConstStatement, error) {
 
 }
 
+/*
+LET or CONST | PATTERN | ASSIGN | EXPRESSION
+let (a, b) = pair
+let [first, ...rest] = list
+const {name, age} = person
+*/
+func (p *Parser) parseDestructuringStatement() (*ast.DestructuringStatement, error) {
+	stmt := &ast.DestructuringStatement{Token: p.curToken, Constant: p.curToken.Type == token.CONST}
+
+	p.next() // on the opening delimiter
+
+	var end token.TokenType
+	switch p.curToken.Type {
+	case token.LPAREN:
+		stmt.Kind, end = ast.TuplePattern, token.RPAREN
+	case token.LBRACKET:
+		stmt.Kind, end = ast.ArrayPattern, token.RBRACKET
+	default:
+		stmt.Kind, end = ast.ObjectPattern, token.RBRACE
+	}
+
+	for !p.peekMatches(end) {
+		if stmt.Kind == ast.ArrayPattern && p.consumeIfPeekMatches(token.ELLIPSIS) {
+			if !p.consumeIfPeekMatches(token.IDENTIFIER) {
+				return nil, fmt.Errorf("expected a name after '...' got %s instead", p.peekToken.Literal)
+			}
+
+			stmt.Rest = &ast.IdentifierExpression{Token: p.curToken, Value: p.curToken.Literal}
+
+			if !p.peekMatches(end) {
+				return nil, fmt.Errorf("the rest binding must be last, found %s", p.peekToken.Literal)
+			}
+			break
+		}
+
+		if !p.consumeIfPeekMatches(token.IDENTIFIER) {
+			return nil, fmt.Errorf("syntax error: expected an `identifier` got %s instead", p.peekToken.Literal)
+		}
+
+		stmt.Names = append(stmt.Names, &ast.IdentifierExpression{Token: p.curToken, Value: p.curToken.Literal})
+
+		if !p.peekMatches(end) && !p.consumeIfPeekMatches(token.COMMA) {
+			return nil, fmt.Errorf("expected ',' in pattern got %s instead", p.peekToken.Literal)
+		}
+	}
+
+	p.next() // on the closing delimiter
+
+	if len(stmt.Names) == 0 && stmt.Rest == nil {
+		return nil, fmt.Errorf("empty destructuring pattern")
+	}
+
+	if !p.consumeIfPeekMatches(token.ASSIGN) {
+		return nil, fmt.Errorf("expected variable assignment ('=') found %s instead", p.peekToken.Literal)
+	}
+
+	p.next()
+
+	v, err := p.parseExpression(LOWEST)
+
+	if err != nil {
+		return nil, err
+	}
+
+	stmt.Value = v
+
+	for p.peekMatches(token.SEMICOLON) {
+		p.next()
+	}
+
+	return stmt, nil
+}
+
 func (p *Parser) parseReturnStatement() (*ast.ReturnStatement, error) {
 	stmt := &ast.ReturnStatement{Token: p.curToken}
 
@@ -139,6 +233,15 @@ func (p *Parser) parseReturnStatement() (*ast.ReturnStatement, error) {
 		return nil, err
 	}
 
+	// return a, b returns the tuple (a, b)
+	if p.peekMatches(token.COMMA) {
+		v, err = p.parseTupleElements(stmt.Token, v, token.SEMICOLON)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	stmt.ReturnValue = v
 	for p.peekMatches(token.SEMICOLON) {
 		p.next()
@@ -146,6 +249,94 @@ func (p *Parser) parseReturnStatement() (*ast.ReturnStatement, error) {
 	return stmt, nil
 }
 
+func (p *Parser) parseThrowStatement() (*ast.ThrowStatement, error) {
+	stmt := &ast.ThrowStatement{Token: p.curToken}
+
+	p.next()
+
+	v, err := p.parseExpression(LOWEST)
+
+	if err != nil {
+		return nil, err
+	}
+
+	stmt.Value = v
+	for p.peekMatches(token.SEMICOLON) {
+		p.next()
+	}
+	return stmt, nil
+}
+
+/*
+try { ... } catch (e) { ... } finally { ... }
+the catch parameter is optional, at least one of catch or finally is required
+*/
+func (p *Parser) parseTryStatement() (*ast.TryStatement, error) {
+	stmt := &ast.TryStatement{Token: p.curToken}
+
+	if !p.consumeIfPeekMatches(token.LBRACE) {
+		return nil, fmt.Errorf("expected '{' after try got %s instead", p.peekToken.Literal)
+	}
+
+	body, err := p.parseBlockStatement()
+
+	if err != nil {
+		return nil, err
+	}
+
+	stmt.Body = body
+
+	if p.consumeIfPeekMatches(token.CATCH) {
+		if p.consumeIfPeekMatches(token.LPAREN) {
+			if !p.consumeIfPeekMatches(token.IDENTIFIER) {
+				return nil, fmt.Errorf("expected catch parameter got %s instead", p.peekToken.Literal)
+			}
+
+			stmt.CatchParam = &ast.IdentifierExpression{Token: p.curToken, Value: p.curToken.Literal}
+
+			if !p.consumeIfPeekMatches(token.RPAREN) {
+				return nil, fmt.Errorf("expected ')' after catch parameter got %s instead", p.peekToken.Literal)
+			}
+		}
+
+		if !p.consumeIfPeekMatches(token.LBRACE) {
+			return nil, fmt.Errorf("expected '{' after catch got %s instead", p.peekToken.Literal)
+		}
+
+		catch, err := p.parseBlockStatement()
+
+		if err != nil {
+			return nil, err
+		}
+
+		stmt.Catch = catch
+	}
+
+	if p.consumeIfPeekMatches(token.FINALLY) {
+		if !p.consumeIfPeekMatches(token.LBRACE) {
+			return nil, fmt.Errorf("expected '{' after finally got %s instead", p.peekToken.Literal)
+		}
+
+		finally, err := p.parseBlockStatement()
+
+		if err != nil {
+			return nil, err
+		}
+
+		stmt.Finally = finally
+	}
+
+	if stmt.Catch == nil && stmt.Finally == nil {
+		return nil, fmt.Errorf("expected catch or finally after try block got %s instead", p.peekToken.Literal)
+	}
+
+	for p.peekMatches(token.SEMICOLON) {
+		p.next()
+	}
+
+	return stmt, nil
+}
+
 func (p *Parser) parseExpressionStatement() (*ast.ExpressionStatement, error) {
 	stmt := &ast.ExpressionStatement{Token: p.curToken}
 
@@ -188,6 +379,10 @@ func (p *Parser) parseBlockStatement() (*ast.BlockStatement, error) {
 
 func (p *Parser) parseTypeDeclaration() (ast.TypeExpression, error) {
 
+	if p.curToken.Type == token.LPAREN {
+		return p.parseFunctionType()
+	}
+
 	if p.curToken.Type != token.IDENTIFIER {
 		return nil, fmt.Errorf("unknown type identifier `%s`", p.curToken.Literal)
 	}
@@ -224,11 +419,36 @@ func (p *Parser) parseTypeDeclaration() (ast.TypeExpression, error) {
 		t = &ast.LiteralBooleanType{}
 
 	// generics
+	case token.ARR_T, token.SET_T:
+		if gen == nil || len(gen) != 1 {
+			return nil, fmt.Errorf("generic type `%s` requires parameter definition: `%s`<T>", name, name)
+		}
+
+		if tok == token.ARR_T {
+			t = &ast.ArrayType{Element: gen[0]}
+		} else {
+			t = &ast.SetType{Element: gen[0]}
+		}
+	case token.MAP_T:
+		if gen == nil || len(gen) != 2 {
+			return nil, fmt.Errorf("generic type `%s` requires parameter definition: `%s`<K, V>", name, name)
+		}
+		t = &ast.MapType{Key: gen[0], Value: gen[1]}
 	case token.OPTIONAL_T:
 		if gen == nil || len(gen) != 1 {
 			return nil, fmt.Errorf("generic type `%s` requires parameter definition: `%s`<T>", name, name)
 		}
 		t = &ast.OptionalType{Value: gen[0]}
+	case token.RSLT_T:
+		if gen == nil || len(gen) != 2 {
+			return nil, fmt.Errorf("generic type `%s` requires parameter definition: `%s`<T, E>", name, name)
+		}
+		t = &ast.ResultType{Value: gen[0], Error: gen[1]}
+	case token.IDENTIFIER:
+		if !p.types.Has(name) {
+			return nil, fmt.Errorf("unknown type `%s`", name)
+		}
+		t = &ast.ScopeDefinedType{Values: gen, Name: name}
 	default:
 		t = &ast.ScopeDefinedType{Values: gen, Name: name}
 	}
@@ -241,6 +461,61 @@ func (p *Parser) parseTypeDeclaration() (ast.TypeExpression, error) {
 	return t, nil
 }
 
+/*
+PARAMETER TYPES | ARROW | RETURN TYPE
+(int, int) -> int
+
+or a tuple type, (int, string)
+*/
+func (p *Parser) parseFunctionType() (ast.TypeExpression, error) {
+	fn := &ast.FunctionType{}
+
+	params, err := p.parseTypeGenericList(token.RPAREN, ')')
+
+	if err != nil {
+		return nil, err
+	}
+
+	fn.Parameters = params
+
+	// without an arrow the list is a tuple, a single parenthesized type is just that type
+	if !p.consumeIfPeekMatches(token.ARROW) {
+		switch len(params) {
+		case 0:
+			return nil, fmt.Errorf("expected '->' after function parameter types got %s instead", p.peekToken.Literal)
+		case 1:
+			return params[0], nil
+		}
+
+		var t ast.TypeExpression = &ast.TupleType{Elements: params}
+		if p.consumeIfPeekMatches(token.Q_MARK) {
+			t = &ast.OptionalType{Value: t}
+		}
+
+		return t, nil
+	}
+
+	p.next()
+
+	ret, err := p.parseReturnType()
+
+	if err != nil {
+		return nil, err
+	}
+
+	fn.Return = ret
+	return fn, nil
+}
+
+// the type after `->`, which unlike a variable's type may be void
+func (p *Parser) parseReturnType() (ast.TypeExpression, error) {
+	if p.curToken.Type == token.VOID {
+		return &ast.ScopeDefinedType{Name: "void"}, nil
+	}
+
+	return p.parseTypeDeclaration()
+}
+
 func (p *Parser) parseTypeGenericList(end token.TokenType, c rune) ([]ast.TypeExpression, error) {
 
 	list := []ast.TypeExpression{}
@@ -0,0 +1,49 @@
+package parser
+
+/*
+Names usable as types besides the builtin ones, struct names, aliases, distinct types and the
+type parameters of the enclosing generic declarations. A type must be declared before it is used.
+*/
+type TypeScope struct {
+	parent *TypeScope
+	names  map[string]bool
+}
+
+func NewTypeScope(parent *TypeScope) *TypeScope {
+	s := &TypeScope{parent: parent, names: make(map[string]bool)}
+
+	if parent == nil {
+		s.Declare("error")
+	}
+
+	return s
+}
+
+func (s *TypeScope) Declare(name string) {
+	s.names[name] = true
+}
+
+func (s *TypeScope) Has(name string) bool {
+	if s.names[name] {
+		return true
+	}
+
+	return s.parent != nil && s.parent.Has(name)
+}
+
+// shares type declarations across parsers, e.g. between REPL lines
+func (p *Parser) UseTypeScope(s *TypeScope) {
+	p.types = s
+}
+
+// declares names in a new nested scope, returning a function restoring the enclosing one
+func (p *Parser) enterTypeScope(names []string) func() {
+	enclosing := p.types
+	p.types = NewTypeScope(enclosing)
+
+	for _, name := range names {
+		p.types.Declare(name)
+	}
+
+	return func() { p.types = enclosing }
+}
@@ -0,0 +1,231 @@
+package project
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const MANIFEST = "anthe.toml"
+
+/*
+The project file at the root of a project directory.
+
+	[package]
+	name = "shapes"
+	entry = "src/main.an"
+	sources = ["src"]
+
+	[dependencies]
+	geometry = { path = "../geometry" }
+*/
+type Manifest struct {
+	Name         string
+	Entry        string            // relative to the project root
+	Sources      []string          // directories searched for imports, relative to the project root
+	Dependencies map[string]string // package name to the path of its project directory
+}
+
+/*
+Parses the subset of TOML the manifest uses: tables, and keys holding strings, arrays of strings or
+inline tables of strings. Comments start with #.
+*/
+func ParseManifest(r io.Reader) (*Manifest, error) {
+	m := &Manifest{Dependencies: make(map[string]string)}
+	table := ""
+	scanner := bufio.NewScanner(r)
+
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(stripComment(scanner.Text()))
+
+		if text == "" {
+			continue
+		}
+
+		if strings.HasPrefix(text, "[") {
+			if !strings.HasSuffix(text, "]") {
+				return nil, fmt.Errorf("%s:%d: unterminated table header", MANIFEST, line)
+			}
+
+			table = strings.TrimSpace(text[1 : len(text)-1])
+
+			if table != "package" && table != "dependencies" {
+				return nil, fmt.Errorf("%s:%d: unknown table `%s`", MANIFEST, line, table)
+			}
+			continue
+		}
+
+		key, value, ok := strings.Cut(text, "=")
+
+		if !ok {
+			return nil, fmt.Errorf("%s:%d: expected `key = value`", MANIFEST, line)
+		}
+
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+
+		if err := m.set(table, key, value); err != nil {
+			return nil, fmt.Errorf("%s:%d: %s", MANIFEST, line, err.Error())
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if m.Name == "" {
+		return nil, fmt.Errorf("%s: missing package name", MANIFEST)
+	}
+
+	return m, nil
+}
+
+func (m *Manifest) set(table, key, value string) error {
+	switch table {
+	case "package":
+		switch key {
+		case "name":
+			return parseString(value, &m.Name)
+		case "entry":
+			return parseString(value, &m.Entry)
+		case "sources":
+			sources, err := parseStringArray(value)
+			m.Sources = sources
+			return err
+		}
+
+		return fmt.Errorf("unknown package key `%s`", key)
+	case "dependencies":
+		if _, ok := m.Dependencies[key]; ok {
+			return fmt.Errorf("duplicate dependency `%s`", key)
+		}
+
+		// geometry = "../geometry" or geometry = { path = "../geometry" }
+		var path string
+		if strings.HasPrefix(value, "{") {
+			fields, err := parseInlineTable(value)
+
+			if err != nil {
+				return err
+			}
+
+			p, ok := fields["path"]
+
+			if !ok || len(fields) != 1 {
+				return fmt.Errorf("dependency `%s` must only declare a `path`, only local dependencies are supported", key)
+			}
+
+			path = p
+		} else if err := parseString(value, &path); err != nil {
+			return err
+		}
+
+		m.Dependencies[key] = path
+		return nil
+	}
+
+	return fmt.Errorf("key `%s` outside of a table", key)
+}
+
+func parseString(value string, into *string) error {
+	s, err := strconv.Unquote(value)
+
+	if err != nil || !strings.HasPrefix(value, `"`) {
+		return fmt.Errorf("expected a string, got %s", value)
+	}
+
+	*into = s
+	return nil
+}
+
+// ["src", "lib"]
+func parseStringArray(value string) ([]string, error) {
+	if !strings.HasPrefix(value, "[") || !strings.HasSuffix(value, "]") {
+		return nil, fmt.Errorf("expected an array of strings, got %s", value)
+	}
+
+	values := []string{}
+
+	for _, item := range splitItems(value[1 : len(value)-1]) {
+		var s string
+		if err := parseString(item, &s); err != nil {
+			return nil, err
+		}
+		values = append(values, s)
+	}
+
+	return values, nil
+}
+
+// { path = "../geometry" }
+func parseInlineTable(value string) (map[string]string, error) {
+	if !strings.HasSuffix(value, "}") {
+		return nil, fmt.Errorf("unterminated inline table %s", value)
+	}
+
+	fields := map[string]string{}
+
+	for _, item := range splitItems(value[1 : len(value)-1]) {
+		key, val, ok := strings.Cut(item, "=")
+
+		if !ok {
+			return nil, fmt.Errorf("expected `key = value` in %s", value)
+		}
+
+		var s string
+		if err := parseString(strings.TrimSpace(val), &s); err != nil {
+			return nil, err
+		}
+
+		fields[strings.TrimSpace(key)] = s
+	}
+
+	return fields, nil
+}
+
+// splits comma separated items, ignoring commas inside strings, an empty list has no items
+func splitItems(s string) []string {
+	items := []string{}
+	start, quoted := 0, false
+
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			quoted = !quoted
+		case ',':
+			if !quoted {
+				items = append(items, strings.TrimSpace(s[start:i]))
+				start = i + 1
+			}
+		}
+	}
+
+	if last := strings.TrimSpace(s[start:]); last != "" {
+		items = append(items, last)
+	}
+
+	return items
+}
+
+// drops a # comment, unless it is inside a string
+func stripComment(line string) string {
+	quoted := false
+
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case '\\':
+			i++
+		case '"':
+			quoted = !quoted
+		case '#':
+			if !quoted {
+				return line[:i]
+			}
+		}
+	}
+
+	return line
+}
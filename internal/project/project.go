@@ -0,0 +1,111 @@
+package project
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/mantton/anthe/internal/module"
+)
+
+// a project directory and its manifest
+type Project struct {
+	Root     string
+	Manifest *Manifest
+	Deps     map[string]*Project // local dependencies, including those of dependencies, by package name
+}
+
+// reads the manifest of the project at dir and of its dependencies
+func Load(dir string) (*Project, error) {
+	return load(dir, map[string]*Project{}, nil)
+}
+
+// deps collects every package by name, stack holds the roots being loaded to detect cycles
+func load(dir string, deps map[string]*Project, stack []string) (*Project, error) {
+	root, err := filepath.Abs(dir)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, r := range stack {
+		if r == root {
+			return nil, fmt.Errorf("dependency cycle through %s", root)
+		}
+	}
+
+	f, err := os.Open(filepath.Join(root, MANIFEST))
+
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	m, err := ParseManifest(f)
+
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", root, err.Error())
+	}
+
+	if m.Entry == "" {
+		m.Entry = filepath.Join("src", "main.an")
+	}
+
+	if m.Sources == nil {
+		m.Sources = []string{filepath.Dir(m.Entry)}
+	}
+
+	p := &Project{Root: root, Manifest: m, Deps: deps}
+
+	for name, path := range m.Dependencies {
+		dep, err := load(filepath.Join(root, path), deps, append(stack, root))
+
+		if err != nil {
+			return nil, err
+		}
+
+		if dep.Manifest.Name != name {
+			return nil, fmt.Errorf("dependency `%s` at %s is named `%s`", name, dep.Root, dep.Manifest.Name)
+		}
+
+		if existing, ok := deps[name]; ok && existing.Root != dep.Root {
+			return nil, fmt.Errorf("dependency `%s` is declared at both %s and %s", name, existing.Root, dep.Root)
+		}
+
+		deps[name] = dep
+	}
+
+	return p, nil
+}
+
+// the entry file of the project
+func (p *Project) Entry() string {
+	return filepath.Join(p.Root, p.Manifest.Entry)
+}
+
+// the source roots of the project
+func (p *Project) Roots() []string {
+	roots := []string{}
+	for _, src := range p.Manifest.Sources {
+		roots = append(roots, filepath.Join(p.Root, src))
+	}
+
+	return roots
+}
+
+// a resolver looking up imports in the project's source roots and its dependencies by package name
+func (p *Project) Resolver() *module.Resolver {
+	r := module.NewResolver(p.Roots())
+	r.Packages = make(map[string]module.Package, len(p.Deps))
+
+	for name, dep := range p.Deps {
+		r.Packages[name] = module.Package{Entry: dep.Entry(), Roots: dep.Roots()}
+	}
+
+	return r
+}
+
+// loads the modules of the project, entry last
+func (p *Project) Modules() ([]*module.Module, error) {
+	return p.Resolver().Load(p.Entry())
+}
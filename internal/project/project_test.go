@@ -0,0 +1,95 @@
+package project
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseManifest(t *testing.T) {
+	input := `
+# comment
+[package]
+name = "app" # trailing comment
+entry = "src/main.an"
+sources = ["src", "lib"]
+
+[dependencies]
+geometry = { path = "../geometry" }
+util = "../util"
+`
+
+	m, err := ParseManifest(strings.NewReader(input))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if m.Name != "app" || m.Entry != "src/main.an" || len(m.Sources) != 2 || m.Sources[1] != "lib" {
+		t.Errorf("unexpected package %+v", m)
+	}
+
+	if m.Dependencies["geometry"] != "../geometry" || m.Dependencies["util"] != "../util" {
+		t.Errorf("unexpected dependencies %v", m.Dependencies)
+	}
+
+	for _, bad := range []string{
+		`[package]`,
+		`[package]` + "\n" + `name = app`,
+		`[package]` + "\n" + `name = "a"` + "\n" + `[dependencies]` + "\n" + `x = { git = "https://example.com/x" }`,
+		`[tool]`,
+	} {
+		if _, err := ParseManifest(strings.NewReader(bad)); err == nil {
+			t.Errorf("%q: expected an error", bad)
+		}
+	}
+}
+
+func TestLoadProject(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"app/anthe.toml":         "[package]\nname = \"app\"\n[dependencies]\ngeometry = { path = \"../geometry\" }",
+		"app/src/main.an":        `import "geometry"; import { square } from "geometry/shapes"; import { inc } from "util";`,
+		"app/src/util.an":        `export func inc(x) { x + 1 }`,
+		"geometry/anthe.toml":    "[package]\nname = \"geometry\"\nentry = \"src/lib.an\"",
+		"geometry/src/lib.an":    `export func area(w, h) { w * h }`,
+		"geometry/src/shapes.an": `import { area } from "geometry"; export func square(s) { area(s, s) }`,
+		"cyclic/anthe.toml":      "[package]\nname = \"cyclic\"\n[dependencies]\ncyclic = \".\"",
+		"misnamed/anthe.toml":    "[package]\nname = \"misnamed\"\n[dependencies]\nshapes = \"../geometry\"",
+		"misnamed/src/main.an":   ``,
+		"cyclic/src/main.an":     ``,
+	}
+
+	for name, src := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	p, err := Load(filepath.Join(dir, "app"))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	modules, err := p.Modules()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(modules) != 4 || modules[len(modules)-1].Path != p.Entry() {
+		t.Errorf("expected 4 modules ending with the entry, got %d", len(modules))
+	}
+
+	for _, name := range []string{"cyclic", "misnamed"} {
+		if _, err := Load(filepath.Join(dir, name)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
@@ -0,0 +1,362 @@
+package resolver
+
+import (
+	"fmt"
+
+	"github.com/mantton/anthe/internal/ast"
+	"github.com/mantton/anthe/internal/builtins"
+)
+
+// slots allocated together at runtime, for a function call or a catch clause
+type frame struct {
+	parent *frame
+	size   int
+}
+
+type binding struct {
+	location ast.Location
+	constant bool
+}
+
+// a lexical scope, blocks share the frame of their function
+type blockScope struct {
+	parent *blockScope
+	frame  *frame // nil at the top level, where bindings are globals looked up by name
+	names  map[string]*binding
+	later  map[string]bool // declared directly in the block, before or after the current statement
+}
+
+/*
+Resolves the names of a program ahead of evaluation.
+Undefined names, uses before declaration, redeclarations and assignments to constants are reported.
+Locals of functions and catch clauses are given a slot in their frame, which every use of them is
+annotated with along with the number of frames to walk up to reach it. Globals and builtins are left
+to be looked up by name, functions may use globals declared after them.
+*/
+type Resolver struct {
+	globals map[string]*binding // top level names of earlier programs, kept across REPL lines
+
+	scope     *blockScope
+	frame     *frame
+	functions int // enclosing functions of the current node
+	errors    []error
+}
+
+func New() *Resolver {
+	return &Resolver{globals: make(map[string]*binding)}
+}
+
+// annotates the program, the top level names are kept for later programs when no errors are found
+func (r *Resolver) Resolve(program *ast.Program) []error {
+	r.errors = nil
+	r.frame = nil
+	r.scope = r.newScope(nil, program.Statements)
+
+	for _, s := range program.Statements {
+		r.resolveStatement(s)
+	}
+
+	if len(r.errors) == 0 {
+		for name, b := range r.scope.names {
+			r.globals[name] = b
+		}
+	}
+
+	r.scope = nil
+	return r.errors
+}
+
+func (r *Resolver) fail(node ast.Node, format string, args ...any) {
+	r.errors = append(r.errors, fmt.Errorf("%s: %s", node.Pos(), fmt.Sprintf(format, args...)))
+}
+
+func (r *Resolver) newScope(parent *blockScope, statements []ast.Statement) *blockScope {
+	s := &blockScope{parent: parent, frame: r.frame, names: make(map[string]*binding), later: make(map[string]bool)}
+
+	for _, stmt := range statements {
+		for _, name := range DeclaredNames(stmt) {
+			s.later[name] = true
+		}
+	}
+
+	return s
+}
+
+// DeclaredNames returns the names a statement binds in the scope it appears in
+func DeclaredNames(stmt ast.Statement) []string {
+	switch stmt := stmt.(type) {
+	case *ast.LetStatement:
+		return []string{stmt.Name.Value}
+	case *ast.ConstStatement:
+		return []string{stmt.Name.Value}
+	case *ast.NamedFunctionDeclaration:
+		return []string{stmt.Name}
+	case *ast.StructDeclaration:
+		return []string{stmt.Name}
+	case *ast.TypeDeclaration:
+		return []string{stmt.Name}
+	case *ast.ImportStatement:
+		if stmt.Names == nil {
+			return []string{stmt.Name}
+		}
+
+		names := []string{}
+		for _, name := range stmt.Names {
+			names = append(names, name.Value)
+		}
+		return names
+	case *ast.DestructuringStatement:
+		names := []string{}
+		for _, name := range stmt.Names {
+			names = append(names, name.Value)
+		}
+		if stmt.Rest != nil {
+			names = append(names, stmt.Rest.Value)
+		}
+		return names
+	case *ast.ExportStatement:
+		return stmt.Names()
+	}
+
+	return nil
+}
+
+// binds name in the current scope, in a slot of its frame unless at the top level
+func (r *Resolver) declare(node ast.Node, name string, constant bool) ast.Location {
+	_, ok := r.scope.names[name]
+
+	// REPL lines share the top level scope
+	if r.scope.parent == nil && r.globals[name] != nil {
+		ok = true
+	}
+
+	if ok {
+		r.fail(node, "`%s` is already defined", name)
+	}
+
+	b := &binding{constant: constant}
+
+	if r.scope.frame != nil {
+		b.location = ast.Location{Local: true, Slot: r.scope.frame.size}
+		r.scope.frame.size++
+	}
+
+	r.scope.names[name] = b
+	return b.location
+}
+
+// the binding name refers to from the current scope, with the depth of its frame from the current one
+func (r *Resolver) lookup(node ast.Node, name string) (ast.Location, *binding, bool) {
+	for s := r.scope; s != nil; s = s.parent {
+		if b, ok := s.names[name]; ok {
+			loc := b.location
+
+			if loc.Local {
+				for f := r.frame; f != s.frame; f = f.parent {
+					loc.Depth++
+				}
+			}
+
+			return loc, b, true
+		}
+
+		if !s.later[name] {
+			continue
+		}
+
+		// globals declared further down may be used from functions, which run once the program has
+		if s.frame == nil && r.functions > 0 {
+			return ast.Location{}, &binding{}, true
+		}
+
+		r.fail(node, "`%s` is used before its declaration", name)
+		return ast.Location{}, nil, false
+	}
+
+	if b, ok := r.globals[name]; ok {
+		return ast.Location{}, b, true
+	}
+
+	if _, ok := builtins.BuiltInFunctions[name]; ok {
+		return ast.Location{}, &binding{constant: true}, true
+	}
+
+	r.fail(node, "undefined variable `%s`", name)
+	return ast.Location{}, nil, false
+}
+
+func (r *Resolver) resolveStatement(stmt ast.Statement) {
+	switch stmt := stmt.(type) {
+	case *ast.LetStatement:
+		r.resolveExpression(stmt.Value)
+		stmt.Name.Location = r.declare(stmt, stmt.Name.Value, false)
+	case *ast.ConstStatement:
+		r.resolveExpression(stmt.Value)
+		stmt.Name.Location = r.declare(stmt, stmt.Name.Value, true)
+	case *ast.DestructuringStatement:
+		r.resolveExpression(stmt.Value)
+
+		for _, name := range stmt.Names {
+			name.Location = r.declare(name, name.Value, stmt.Constant)
+		}
+
+		if stmt.Rest != nil {
+			stmt.Rest.Location = r.declare(stmt.Rest, stmt.Rest.Value, stmt.Constant)
+		}
+	case *ast.NamedFunctionDeclaration:
+		// declared before the body so it can call itself
+		stmt.Location = r.declare(stmt, stmt.Name, false)
+		r.resolveFunction(stmt.Fn)
+	case *ast.StructDeclaration:
+		r.declare(stmt, stmt.Name, true)
+	case *ast.TypeDeclaration:
+		r.declare(stmt, stmt.Name, true)
+	case *ast.ImportStatement:
+		for _, name := range DeclaredNames(stmt) {
+			r.declare(stmt, name, true)
+		}
+	case *ast.ExportStatement:
+		r.resolveStatement(stmt.Statement)
+	case *ast.ExpressionStatement:
+		r.resolveExpression(stmt.Expression)
+	case *ast.ReturnStatement:
+		r.resolveExpression(stmt.ReturnValue)
+	case *ast.ThrowStatement:
+		r.resolveExpression(stmt.Value)
+	case *ast.BlockStatement:
+		r.resolveBlock(stmt)
+	case *ast.TryStatement:
+		r.resolveBlock(stmt.Body)
+
+		if stmt.Catch != nil {
+			r.resolveCatch(stmt)
+		}
+
+		if stmt.Finally != nil {
+			r.resolveBlock(stmt.Finally)
+		}
+	}
+}
+
+func (r *Resolver) resolveBlock(block *ast.BlockStatement) {
+	if block == nil {
+		return
+	}
+
+	enclosing := r.scope
+	r.scope = r.newScope(enclosing, block.Statements)
+	defer func() { r.scope = enclosing }()
+
+	for _, s := range block.Statements {
+		r.resolveStatement(s)
+	}
+}
+
+// parameters and the locals of the body share the frame of the call
+func (r *Resolver) resolveFunction(fn *ast.FunctionLiteral) {
+	enclosingScope, enclosingFrame := r.scope, r.frame
+	r.frame = &frame{parent: enclosingFrame}
+	r.scope = r.newScope(enclosingScope, fn.Body.Statements)
+	r.functions++
+
+	defer func() {
+		fn.FrameSize = r.frame.size
+		r.scope, r.frame = enclosingScope, enclosingFrame
+		r.functions--
+	}()
+
+	for _, param := range fn.Parameters {
+		if _, ok := r.scope.names[param.Value]; ok {
+			r.fail(param, "duplicate parameter `%s`", param.Value)
+			continue
+		}
+
+		param.Location = r.declare(param, param.Value, false)
+	}
+
+	for _, s := range fn.Body.Statements {
+		r.resolveStatement(s)
+	}
+}
+
+// the catch clause runs in a frame of its own holding the caught error and its locals
+func (r *Resolver) resolveCatch(stmt *ast.TryStatement) {
+	enclosingScope, enclosingFrame := r.scope, r.frame
+	r.frame = &frame{parent: enclosingFrame}
+	r.scope = r.newScope(enclosingScope, stmt.Catch.Statements)
+
+	defer func() {
+		stmt.CatchFrameSize = r.frame.size
+		r.scope, r.frame = enclosingScope, enclosingFrame
+	}()
+
+	if stmt.CatchParam != nil {
+		stmt.CatchParam.Location = r.declare(stmt.CatchParam, stmt.CatchParam.Value, false)
+	}
+
+	for _, s := range stmt.Catch.Statements {
+		r.resolveStatement(s)
+	}
+}
+
+func (r *Resolver) resolveExpression(expr ast.Expression) {
+	switch expr := expr.(type) {
+	case *ast.IdentifierExpression:
+		if loc, _, ok := r.lookup(expr, expr.Value); ok {
+			expr.Location = loc
+		}
+	case *ast.AssignmentExpression:
+		r.resolveExpression(expr.Value)
+
+		if loc, b, ok := r.lookup(expr.Target, expr.Target.Value); ok {
+			if b.constant {
+				r.fail(expr, "cannot reassign constant `%s`", expr.Target.Value)
+			}
+
+			expr.Target.Location = loc
+		}
+	case *ast.ElementAssignmentExpression:
+		// the container is only read, writes into frozen values are refused by the checker and at runtime
+		r.resolveExpression(expr.Value)
+		r.resolveExpression(expr.Target)
+	case *ast.PrefixExpression:
+		r.resolveExpression(expr.Right)
+	case *ast.InfixExpression:
+		r.resolveExpression(expr.Left)
+		r.resolveExpression(expr.Right)
+	case *ast.IfExpression:
+		r.resolveExpression(expr.Condition)
+		r.resolveBlock(expr.Action)
+		r.resolveBlock(expr.Alternative)
+	case *ast.CallExpression:
+		r.resolveExpression(expr.Function)
+		r.resolveExpressions(expr.Arguments)
+	case *ast.IndexExpression:
+		r.resolveExpression(expr.Left)
+		r.resolveExpression(expr.Index)
+	case *ast.MemberExpression:
+		// the property is a name, not a variable
+		r.resolveExpression(expr.Object)
+	case *ast.PropagateExpression:
+		r.resolveExpression(expr.Value)
+	case *ast.FunctionLiteral:
+		r.resolveFunction(expr)
+	case *ast.ArrayLiteral:
+		r.resolveExpressions(expr.Elements)
+	case *ast.SetLiteral:
+		r.resolveExpressions(expr.Elements)
+	case *ast.TupleLiteral:
+		r.resolveExpressions(expr.Elements)
+	case *ast.HashLiteral:
+		for _, pair := range expr.Pairs {
+			r.resolveExpression(pair.Key)
+			r.resolveExpression(pair.Value)
+		}
+	}
+}
+
+func (r *Resolver) resolveExpressions(exprs []ast.Expression) {
+	for _, expr := range exprs {
+		r.resolveExpression(expr)
+	}
+}
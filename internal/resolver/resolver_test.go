@@ -0,0 +1,111 @@
+package resolver
+
+import (
+	"testing"
+
+	"github.com/mantton/anthe/internal/ast"
+	"github.com/mantton/anthe/internal/lexer"
+	"github.com/mantton/anthe/internal/parser"
+)
+
+func parse(t *testing.T, input string) *ast.Program {
+	t.Helper()
+	prog := parser.New(lexer.New(input, "test.an")).ParseProgram()
+
+	if len(prog.Errors) > 0 {
+		t.Fatalf("%s: failed to parse: %v", input, prog.Errors)
+	}
+
+	return prog
+}
+
+func TestResolveErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		ok    bool
+	}{
+		{`let x = 1; x;`, true},
+		{`y;`, false},
+		{`x; let x = 1;`, false},
+		{`func f() { g() }; func g() { 1 }; f();`, true},
+		{`func f() { let a = b; let b = 1; }`, false},
+		{`let x = 1; let x = 2;`, false},
+		{`let x = 1; const x = 2;`, false},
+		{`func f(a, a) { a }`, false},
+		{`func f(a) { let a = 1; }`, false},
+		{`func f(a) { if a { let a = 1; a } else { a } }`, true},
+		{`const c = 1; c = 2;`, false},
+		{`func f() { const c = 1; func() { c = 2 } }`, false},
+		{`if true { let a = 1; }; a;`, false},
+		{`if true { let a = 1; a } else { let a = 2; a }`, true},
+		{`try { throw "a" } catch (e) { message(e) }`, true},
+		{`try { throw "a" } catch (e) { let e = 1; }`, false},
+		{`let p = {"a": 1}; p.a;`, true},
+		{`let x = x;`, false},
+	}
+
+	for _, tt := range tests {
+		errs := New().Resolve(parse(t, tt.input))
+
+		if ok := len(errs) == 0; ok != tt.ok {
+			t.Errorf("%s: expected ok=%t, got %t %v", tt.input, tt.ok, ok, errs)
+		}
+	}
+}
+
+func TestResolveLocations(t *testing.T) {
+	prog := parse(t, `let g = 1; func outer(a, b) { let c = a; func(x) { x + c + g } }`)
+
+	if errs := New().Resolve(prog); len(errs) > 0 {
+		t.Fatal(errs)
+	}
+
+	outer := prog.Statements[1].(*ast.NamedFunctionDeclaration)
+
+	if outer.Fn.FrameSize != 3 {
+		t.Errorf("expected 3 slots in outer, got %d", outer.Fn.FrameSize)
+	}
+
+	lit := outer.Fn.Body.Statements[1].(*ast.ExpressionStatement).Expression.(*ast.FunctionLiteral)
+	sum := lit.Body.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.InfixExpression)
+	inner := sum.Left.(*ast.InfixExpression)
+
+	expected := []struct {
+		ident *ast.IdentifierExpression
+		loc   ast.Location
+	}{
+		{inner.Left.(*ast.IdentifierExpression), ast.Location{Local: true, Depth: 0, Slot: 0}},
+		{inner.Right.(*ast.IdentifierExpression), ast.Location{Local: true, Depth: 1, Slot: 2}},
+		{sum.Right.(*ast.IdentifierExpression), ast.Location{}},
+	}
+
+	for _, tt := range expected {
+		if tt.ident.Location != tt.loc {
+			t.Errorf("%s: expected %+v, got %+v", tt.ident.Value, tt.loc, tt.ident.Location)
+		}
+	}
+}
+
+func TestResolveAcrossPrograms(t *testing.T) {
+	r := New()
+
+	if errs := r.Resolve(parse(t, `let x = 1;`)); len(errs) > 0 {
+		t.Fatal(errs)
+	}
+
+	if errs := r.Resolve(parse(t, `x + 1;`)); len(errs) > 0 {
+		t.Errorf("expected x from an earlier program to be defined, got %v", errs)
+	}
+
+	if errs := r.Resolve(parse(t, `let y = z;`)); len(errs) == 0 {
+		t.Errorf("expected z to be undefined")
+	}
+
+	if errs := r.Resolve(parse(t, `y;`)); len(errs) == 0 {
+		t.Errorf("expected y of a rejected program to be undefined")
+	}
+
+	if errs := r.Resolve(parse(t, `const x = 2;`)); len(errs) == 0 {
+		t.Errorf("expected x of an earlier program to be already defined")
+	}
+}
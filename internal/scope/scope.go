@@ -6,100 +6,122 @@ import (
 	"github.com/mantton/anthe/internal/object"
 )
 
+type binding struct {
+	value    object.Object
+	constant bool
+}
+
+/*
+Bindings of a block, function call or program, every block statement gets a scope of its own, variables, constants, functions and types alike.
+A name may only be bound once per scope, whatever declared it, REPL lines share the global scope.
+A nested scope may shadow a name of an enclosing one, lookups and assignments use the innermost binding.
+*/
 type Scope struct {
 	parent *Scope
 
-	variables map[string]object.Object // holds variables
-	constants map[string]object.Object // holds constants
+	bindings map[string]*binding
+
+	slots []object.Object // locals placed by the resolver, nil for scopes looked up by name only
 }
 
 // create a new scope
 func New(p *Scope) *Scope {
 	return &Scope{
-		parent:    p,
-		variables: make(map[string]object.Object),
-		constants: make(map[string]object.Object),
+		parent:   p,
+		bindings: make(map[string]*binding),
 	}
 }
 
-// returns true if the scope is the current global scope
-func (s *Scope) IsGlobalScope() bool {
-	return s.parent == nil
+// create a frame with size slots for resolved locals
+func NewFrame(p *Scope, size int) *Scope {
+	s := New(p)
+	s.slots = make([]object.Object, size)
+	return s
 }
 
-func (s *Scope) InjectList(values map[string]object.Object) error {
-	return nil
-}
-func (s *Scope) Inject(name string, value object.Object) error {
+// the frame s belongs to, blocks have no slots of their own and share the frame enclosing them
+func (s *Scope) frame() *Scope {
+	for s.slots == nil {
+		s = s.parent
+	}
 
-	s.variables[name] = value
-	return nil
+	return s
 }
 
-func (s *Scope) DefineVariable(name string, value object.Object) error {
-	_, ok := s.variables[name]
+// the frame depth frames up from the one of s
+func (s *Scope) up(depth int) *Scope {
+	f := s.frame()
 
-	if ok {
-		return fmt.Errorf("`%s` is already defined", name)
+	for ; depth > 0; depth-- {
+		f = f.parent.frame()
 	}
 
-	s.variables[name] = value
+	return f
+}
 
-	return nil
+// the value in slot of the frame depth frames up, nil before it is stored
+func (s *Scope) Load(depth, slot int) object.Object {
+	return s.up(depth).slots[slot]
 }
 
-func (s *Scope) DefineConstant(name string, value object.Object) error {
-	_, ok := s.constants[name]
+func (s *Scope) Store(depth, slot int, value object.Object) {
+	s.up(depth).slots[slot] = value
+}
 
-	if ok {
+// returns true if the scope is the current global scope
+func (s *Scope) IsGlobalScope() bool {
+	return s.parent == nil
+}
+
+func (s *Scope) define(name string, value object.Object, constant bool) error {
+	if _, ok := s.bindings[name]; ok {
 		return fmt.Errorf("`%s` is already defined", name)
 	}
 
-	s.constants[name] = value
-
+	s.bindings[name] = &binding{value: value, constant: constant}
 	return nil
 }
 
-func (s *Scope) Assign(name string, value object.Object) error {
+func (s *Scope) DefineVariable(name string, value object.Object) error {
+	return s.define(name, value, false)
+}
 
-	// check variable
-	_, ok := s.variables[name]
+func (s *Scope) DefineConstant(name string, value object.Object) error {
+	return s.define(name, value, true)
+}
 
-	if !ok {
+// the innermost binding of name
+func (s *Scope) lookup(name string) (*binding, bool) {
+	for ; s != nil; s = s.parent {
+		if b, ok := s.bindings[name]; ok {
+			return b, true
+		}
+	}
 
-		_, ok = s.constants[name]
+	return nil, false
+}
 
-		if ok {
-			// is constant, cannot reassign
-			return fmt.Errorf("cannot reassign constant '%s'", name)
-		}
+func (s *Scope) Assign(name string, value object.Object) error {
+	b, ok := s.lookup(name)
 
-		// recursively go up the chain till the variable is found to be assigned
-		if s.parent != nil {
-			return s.parent.Assign(name, value)
-		}
+	if !ok {
 		return fmt.Errorf("undefined variable %s", name)
 	}
 
-	s.variables[name] = value
-	return nil
+	if b.constant {
+		return fmt.Errorf("cannot reassign constant '%s'", name)
+	}
 
+	b.value = value
+	return nil
 }
 
 func (s *Scope) Get(name string) (object.Object, error) {
-	val, ok := s.variables[name]
-	if !ok {
-		val, ok = s.constants[name]
+	b, ok := s.lookup(name)
 
-		if ok {
-			return val, nil
-		}
-
-		// recursively search environments for variables
-		if s.parent != nil {
-			return s.parent.Get(name)
-		}
+	if !ok {
 		return nil, fmt.Errorf("undefined identifier %s", name)
 	}
-	return val, nil
+
+	return b.value, nil
 }
@@ -0,0 +1,52 @@
+package scope
+
+import (
+	"testing"
+
+	"github.com/mantton/anthe/internal/object"
+)
+
+func TestBindings(t *testing.T) {
+	global := New(nil)
+
+	if err := global.DefineVariable("x", &object.Integer{Value: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := global.DefineConstant("x", &object.Integer{Value: 2}); err == nil {
+		t.Errorf("expected a constant to conflict with a variable of the same scope")
+	}
+
+	if err := global.DefineConstant("c", &object.Integer{Value: 3}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := global.DefineVariable("c", &object.Integer{Value: 4}); err == nil {
+		t.Errorf("expected a variable to conflict with a constant of the same scope")
+	}
+
+	// an inner scope shadows, assignments reach the innermost binding
+	inner := New(global)
+
+	if err := inner.DefineConstant("x", &object.Integer{Value: 5}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := inner.Assign("x", &object.Integer{Value: 6}); err == nil {
+		t.Errorf("expected assigning the shadowing constant to fail")
+	}
+
+	if err := inner.Assign("c", &object.Integer{Value: 6}); err == nil {
+		t.Errorf("expected assigning the outer constant to fail")
+	}
+
+	val, _ := inner.Get("x")
+	if val.Inspect() != "5" {
+		t.Errorf("expected the inner x, got %s", val.Inspect())
+	}
+
+	val, _ = global.Get("x")
+	if val.Inspect() != "1" {
+		t.Errorf("expected the outer x to be untouched, got %s", val.Inspect())
+	}
+}
@@ -1,10 +1,23 @@
 package token
 
+import "fmt"
+
 type TokenType byte
 
 type Token struct {
 	Type    TokenType
 	Literal string
+	Pos     Position // where the token starts
+}
+
+type Position struct {
+	Filename string
+	Line     int
+	Col      int
+}
+
+func (p Position) String() string {
+	return fmt.Sprintf("%s:%d:%d", p.Filename, p.Line, p.Col)
 }
 
 const (
@@ -52,6 +65,13 @@ const (
 	RBRACKET  // ]
 
 	Q_MARK // ?
+	DOT    // .
+
+	OPT_DOT      // ?.
+	OPT_LBRACKET // ?[
+	COALESCE     // ??
+	ARROW        // ->
+	ELLIPSIS     // ...
 
 	// Keywords
 	FUNCTION
@@ -85,7 +105,15 @@ const (
 	ANY_T     // any non null value
 
 	STRUCT // struct declaration
+	TYPE   // type alias or distinct type declaration
+
+	TRY     // try block
+	CATCH   // catch block
+	FINALLY // finally block
+	THROW   // throw statement
 
+	IMPORT // import statement
+	EXPORT // exported declaration
 )
 
 var keywords = map[string]TokenType{
@@ -105,6 +133,15 @@ var keywords = map[string]TokenType{
 	"void": VOID,
 
 	"struct": STRUCT,
+	"type":   TYPE,
+
+	"try":     TRY,
+	"catch":   CATCH,
+	"finally": FINALLY,
+	"throw":   THROW,
+
+	"import": IMPORT,
+	"export": EXPORT,
 }
 
 var symbols = map[rune]TokenType{
@@ -133,6 +170,7 @@ var symbols = map[rune]TokenType{
 	'[': LBRACKET,
 	']': RBRACKET,
 	'?': Q_MARK,
+	'.': DOT,
 }
 
 var builtin_types = map[string]TokenType{
@@ -0,0 +1,114 @@
+package typing
+
+import (
+	"fmt"
+
+	"github.com/mantton/anthe/internal/ast"
+)
+
+/*
+`type UserID = int` makes UserID another name for int, the two are interchangeable.
+`type Meters distinct float` makes a new type with the representation of float that only matches
+itself. Its name is bound to a constructor, `Meters(1.5)`, and arithmetic between two values of it
+stays a Meters, while mixing it with a float or another distinct type is rejected.
+*/
+func (t *TypeChecker) checkTypeDeclaration(s *ast.TypeDeclaration) error {
+	if t.isDeclaredType(s.Name) {
+		return fmt.Errorf("type `%s` is already defined", s.Name)
+	}
+
+	underlying, err := t.resolveAnnotation(s.Value, t.typeParams)
+
+	if err != nil {
+		return err
+	}
+
+	key := typeKey{t.module, s.Name}
+
+	if !s.Distinct {
+		t.named[s.Name] = t.module
+		t.aliases[key] = underlying
+		return nil
+	}
+
+	ctor := &ast.FunctionType{Parameters: []ast.TypeExpression{underlying}, Return: &ast.ScopeDefinedType{Name: s.Name, Module: t.module}}
+
+	if err := t.scope.define(s.Name, ctor, true); err != nil {
+		return err
+	}
+
+	t.named[s.Name] = t.module
+	t.distinct[key] = underlying
+	t.types[s] = ctor
+	return nil
+}
+
+// struct, alias and distinct types are visible in the module declaring them and the modules importing them
+func (t *TypeChecker) isDeclaredType(name string) bool {
+	_, ok := t.named[name]
+	return ok
+}
+
+// the declared type a name refers to in the module being checked
+func (t *TypeChecker) declaredType(name string) (typeKey, bool) {
+	module, ok := t.named[name]
+
+	if !ok {
+		return typeKey{}, false
+	}
+
+	return typeKey{module, name}, true
+}
+
+// the underlying type of a distinct type
+func (t *TypeChecker) distinctType(ty ast.TypeExpression) (ast.TypeExpression, bool) {
+	named, ok := t.prune(ty).(*ast.ScopeDefinedType)
+
+	if !ok || named.Values != nil {
+		return nil, false
+	}
+
+	underlying, ok := t.distinct[typeKey{named.Module, named.Name}]
+	return underlying, ok
+}
+
+// arithmetic and comparisons where either operand is of a distinct type, both must be of the same one
+func (t *TypeChecker) distinctOperation(op string, lhs, rhs ast.TypeExpression) (ast.TypeExpression, bool, error) {
+	lu, lok := t.distinctType(lhs)
+	ru, rok := t.distinctType(rhs)
+
+	if !lok && !rok {
+		return nil, false, nil
+	}
+
+	mismatch := fmt.Errorf("cannot use `%s` with `%s` and `%s`", op, t.describe(lhs).Type(), t.describe(rhs).Type())
+
+	// an operand still being inferred takes on the distinct type
+	for _, operand := range []ast.TypeExpression{lhs, rhs} {
+		if isTypeVariable(t.prune(operand)) {
+			if err := t.unify(lhs, rhs); err != nil {
+				return nil, true, mismatch
+			}
+		}
+	}
+
+	if t.resolve(lhs).Type() != t.resolve(rhs).Type() {
+		return nil, true, mismatch
+	}
+
+	underlying := lu
+	if !lok {
+		underlying = ru
+	}
+
+	if !isNumericType(t.prune(underlying)) {
+		return nil, true, fmt.Errorf("unknown operator: %s %s %s", t.describe(lhs).Type(), op, t.describe(rhs).Type())
+	}
+
+	switch op {
+	case "<", ">", "<=", ">=":
+		return &ast.LiteralBooleanType{}, true, nil
+	}
+
+	return t.prune(lhs), true, nil
+}
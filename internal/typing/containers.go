@@ -0,0 +1,114 @@
+package typing
+
+import (
+	"fmt"
+
+	"github.com/mantton/anthe/internal/ast"
+)
+
+/*
+Literals of mixed elements are inferred as containers of `any`, which only containers of `any` accept.
+Where another container type is expected each element of a literal is checked against it instead,
+so `let xs: array<int> = [1, "a"]` is rejected while `let xs: array<int?> = [1, null]` is not.
+*/
+func (t *TypeChecker) checkElements(expected ast.TypeExpression, value ast.Expression) error {
+	expected = t.prune(expected)
+
+	if o, ok := expected.(*ast.OptionalType); ok {
+		expected = t.prune(o.Value)
+	}
+
+	switch value := value.(type) {
+	case *ast.ArrayLiteral:
+		arr, ok := expected.(*ast.ArrayType)
+
+		if !ok {
+			return nil
+		}
+
+		return t.checkElementList(expected, arr.Element, value.Elements)
+	case *ast.SetLiteral:
+		set, ok := expected.(*ast.SetType)
+
+		if !ok {
+			return nil
+		}
+
+		return t.checkElementList(expected, set.Element, value.Elements)
+	case *ast.HashLiteral:
+		m, ok := expected.(*ast.MapType)
+
+		if !ok {
+			return nil
+		}
+
+		keys, values := []ast.Expression{}, []ast.Expression{}
+		for _, pair := range value.Pairs {
+			keys = append(keys, pair.Key)
+			values = append(values, pair.Value)
+		}
+
+		if err := t.checkElementList(expected, m.Key, keys); err != nil {
+			return err
+		}
+
+		return t.checkElementList(expected, m.Value, values)
+	}
+
+	return nil
+}
+
+func (t *TypeChecker) checkElementList(container, elem ast.TypeExpression, elements []ast.Expression) error {
+	if isAnyType(t.prune(elem)) {
+		return nil
+	}
+
+	for _, el := range elements {
+		elType := t.types[el]
+
+		// nested literals are checked against the nested container type
+		ok, err := t.matchValue(elem, elType, el)
+
+		if err != nil {
+			return err
+		}
+
+		if !ok {
+			return fmt.Errorf("cannot use `%s` as an element of `%s`", t.describe(elType).Type(), t.describe(container).Type())
+		}
+	}
+
+	return nil
+}
+
+// reports whether value, of type valueType, can be stored where expected is, checking the elements of literals
+func (t *TypeChecker) matchValue(expected, valueType ast.TypeExpression, value ast.Expression) (bool, error) {
+	if !t.matchTypes(expected, valueType) && !t.isLiteralOf(expected, value) {
+		return false, nil
+	}
+
+	return true, t.checkElements(expected, value)
+}
+
+// reports whether value is a literal of the container kind expected, whose elements checkElements can check
+func (t *TypeChecker) isLiteralOf(expected ast.TypeExpression, value ast.Expression) bool {
+	expected = t.prune(expected)
+
+	if o, ok := expected.(*ast.OptionalType); ok {
+		expected = t.prune(o.Value)
+	}
+
+	switch value.(type) {
+	case *ast.ArrayLiteral:
+		_, ok := expected.(*ast.ArrayType)
+		return ok
+	case *ast.SetLiteral:
+		_, ok := expected.(*ast.SetType)
+		return ok
+	case *ast.HashLiteral:
+		_, ok := expected.(*ast.MapType)
+		return ok
+	}
+
+	return false
+}
@@ -0,0 +1,792 @@
+package typing
+
+import (
+	"fmt"
+
+	"github.com/mantton/anthe/internal/ast"
+	"github.com/mantton/anthe/internal/builtins"
+)
+
+func (t *TypeChecker) visitIdentifierExpression(e *ast.IdentifierExpression) (ast.TypeExpression, error) {
+	if b, ok := t.scope.lookup(e.Value); ok {
+		return t.instantiate(b), nil
+	}
+
+	if _, ok := builtins.BuiltInFunctions[e.Value]; ok {
+		return anyType(), nil
+	}
+
+	return nil, fmt.Errorf("undefined identifier %s", e.Value)
+}
+
+func (t *TypeChecker) visitArrayLiteral(e *ast.ArrayLiteral) (ast.TypeExpression, error) {
+	var elem ast.TypeExpression
+
+	for _, element := range e.Elements {
+		elemType, err := t.visitExpression(element)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if elem == nil {
+			elem = elemType
+		} else {
+			elem = t.join(elem, elemType)
+		}
+	}
+
+	if elem == nil {
+		elem = t.fresh()
+	}
+
+	return arrayOf(elem), nil
+}
+
+func (t *TypeChecker) visitSetLiteral(e *ast.SetLiteral) (ast.TypeExpression, error) {
+	var elem ast.TypeExpression
+
+	for _, element := range e.Elements {
+		elemType, err := t.visitExpression(element)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if _, ok := elemType.(*ast.FunctionType); ok {
+			return nil, fmt.Errorf("`%s` cannot be a set element", t.describe(elemType).Type())
+		}
+
+		if elem == nil {
+			elem = elemType
+		} else {
+			elem = t.join(elem, elemType)
+		}
+	}
+
+	return setOf(elem), nil
+}
+
+func (t *TypeChecker) visitHashLiteral(e *ast.HashLiteral) (ast.TypeExpression, error) {
+	var key, value ast.TypeExpression
+
+	for _, pair := range e.Pairs {
+		keyType, err := t.visitExpression(pair.Key)
+
+		if err != nil {
+			return nil, err
+		}
+
+		valueType, err := t.visitExpression(pair.Value)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if key == nil {
+			key, value = keyType, valueType
+		} else {
+			key, value = t.join(key, keyType), t.join(value, valueType)
+		}
+	}
+
+	if key == nil {
+		key, value = t.fresh(), t.fresh()
+	}
+
+	return mapOf(key, value), nil
+}
+
+func (t *TypeChecker) visitFunctionLiteral(e *ast.FunctionLiteral) (ast.TypeExpression, error) {
+	fnType, err := t.functionSignature(e)
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = t.checkFunctionBody(e, fnType)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return fnType, nil
+}
+
+// unannotated parameters and the return type start as type variables, they are inferred from the body and from calls
+func (t *TypeChecker) functionSignature(fn *ast.FunctionLiteral) (*ast.FunctionType, error) {
+	fnType := &ast.FunctionType{}
+
+	for i := range fn.Parameters {
+		var param ast.TypeExpression = t.fresh()
+
+		if i < len(fn.ParameterTypes) && fn.ParameterTypes[i] != nil {
+			annotated, err := t.resolveAnnotation(fn.ParameterTypes[i], t.typeParams)
+
+			if err != nil {
+				return nil, err
+			}
+
+			param = annotated
+		}
+
+		fnType.Parameters = append(fnType.Parameters, param)
+	}
+
+	fnType.Return = t.fresh()
+
+	if fn.ReturnType != nil {
+		ret, err := t.resolveAnnotation(fn.ReturnType, t.typeParams)
+
+		if err != nil {
+			return nil, err
+		}
+
+		fnType.Return = ret
+	}
+
+	return fnType, nil
+}
+
+// checks the body in a new scope holding the parameters, the return type is the join of every value the body can return
+func (t *TypeChecker) checkFunctionBody(fn *ast.FunctionLiteral, fnType *ast.FunctionType) error {
+	fnScope := newTypeScope(t.scope)
+
+	for i, param := range fn.Parameters {
+		err := fnScope.define(param.Value, fnType.Parameters[i], false)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	t.returns = append(t.returns, []ast.TypeExpression{})
+	bodyType, err := t.checkBlockStatement(fn.Body, fnScope)
+	returns := t.returns[len(t.returns)-1]
+	t.returns = t.returns[:len(t.returns)-1]
+
+	if err != nil {
+		return err
+	}
+
+	// the last statement is returned implicitly unless the body never completes, ending in a return or throw
+	if !isNeverType(bodyType) {
+		returns = append(returns, bodyType)
+	}
+
+	// an annotated return type is checked against every value returned
+	if fn.ReturnType != nil {
+		for _, r := range returns {
+			if !t.matchTypes(fnType.Return, r) {
+				return fmt.Errorf("cannot return `%s` from function declared to return `%s`", t.describe(r).Type(), t.describe(fnType.Return).Type())
+			}
+		}
+
+		return nil
+	}
+
+	// a function that only throws leaves its return type free
+	if len(returns) == 0 {
+		return nil
+	}
+
+	returnType := returns[0]
+	for _, r := range returns[1:] {
+		returnType = t.join(returnType, r)
+	}
+
+	// recursive calls in the body may already have constrained the return type
+	if err := t.unify(fnType.Return, returnType); err != nil {
+		return fmt.Errorf("inconsistent return type: %s", err.Error())
+	}
+
+	return nil
+}
+
+func (t *TypeChecker) visitCallExpression(e *ast.CallExpression) (ast.TypeExpression, error) {
+	if ident, ok := e.Function.(*ast.IdentifierExpression); ok {
+		if _, defined := t.scope.lookup(ident.Value); !defined {
+			if _, ok := builtins.BuiltInFunctions[ident.Value]; ok {
+				args, err := t.visitArguments(e.Arguments, nil)
+
+				if err != nil {
+					return nil, err
+				}
+
+				if err := t.checkBuiltinArguments(ident.Value, args); err != nil {
+					return nil, err
+				}
+
+				return t.builtinReturnType(ident.Value, args), nil
+			}
+		}
+	}
+
+	// the callee is checked first so function literal arguments can take their parameter types from it
+	callee, err := t.visitExpression(e.Function)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var expected []ast.TypeExpression
+	if fn, ok := callee.(*ast.FunctionType); ok && len(fn.Parameters) == len(e.Arguments) {
+		expected = fn.Parameters
+	}
+
+	args, err := t.visitArguments(e.Arguments, expected)
+
+	if err != nil {
+		return nil, err
+	}
+
+	switch callee := callee.(type) {
+	case *ast.TypeVariable:
+		// calling an unknown value makes it a function of the arguments
+		ret := t.fresh()
+		err := t.unify(callee, &ast.FunctionType{Parameters: args, Return: ret})
+
+		if err != nil {
+			return nil, err
+		}
+
+		return ret, nil
+	case *ast.FunctionType:
+		if len(args) != len(callee.Parameters) {
+			return nil, fmt.Errorf("function requires %d arguments, received %d", len(callee.Parameters), len(args))
+		}
+
+		for i, arg := range args {
+			ok, err := t.matchValue(callee.Parameters[i], arg, e.Arguments[i])
+
+			if err != nil {
+				return nil, err
+			}
+
+			if !ok {
+				return nil, fmt.Errorf("cannot use `%s` as argument %d of type `%s`", t.describe(arg).Type(), i+1, t.describe(callee.Parameters[i]).Type())
+			}
+		}
+
+		return t.prune(callee.Return), nil
+	}
+
+	if isAnyType(callee) {
+		return anyType(), nil
+	}
+
+	return nil, fmt.Errorf("`%s` is not a function", t.describe(callee).Type())
+}
+
+/*
+Checks call arguments, expected holds the parameter types when the callee is a known function.
+Function literals are checked last so the other arguments can fix the type parameters they share.
+*/
+func (t *TypeChecker) visitArguments(arguments []ast.Expression, expected []ast.TypeExpression) ([]ast.TypeExpression, error) {
+	args := make([]ast.TypeExpression, len(arguments))
+
+	for i, arg := range arguments {
+		if _, ok := arg.(*ast.FunctionLiteral); ok && expected != nil {
+			continue
+		}
+
+		argType, err := t.visitExpression(arg)
+
+		if err != nil {
+			return nil, err
+		}
+
+		// binds type parameters early, a mismatch is reported with the other arguments
+		if expected != nil {
+			t.matchTypes(expected[i], argType)
+		}
+
+		args[i] = argType
+	}
+
+	for i, arg := range arguments {
+		if lit, ok := arg.(*ast.FunctionLiteral); ok && expected != nil {
+			argType, err := t.visitFunctionArgument(lit, expected[i])
+
+			if err != nil {
+				return nil, err
+			}
+
+			args[i] = argType
+		}
+	}
+
+	return args, nil
+}
+
+/*
+A function literal passed where a function type is expected takes its unannotated parameter types
+from it before its body is checked, so `apply(func(b) { !b }, true)` knows `b` is a bool.
+*/
+func (t *TypeChecker) visitFunctionArgument(lit *ast.FunctionLiteral, expected ast.TypeExpression) (ast.TypeExpression, error) {
+	fnType, err := t.functionSignature(lit)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if want, ok := t.prune(expected).(*ast.FunctionType); ok && len(want.Parameters) == len(fnType.Parameters) {
+		for i, param := range fnType.Parameters {
+			if isTypeVariable(t.prune(param)) {
+				if err := t.unify(param, want.Parameters[i]); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	if err := t.checkFunctionBody(lit, fnType); err != nil {
+		return nil, err
+	}
+
+	t.types[lit] = fnType
+	return fnType, nil
+}
+
+// builtins accept any arguments, only their results are typed
+func (t *TypeChecker) builtinReturnType(name string, args []ast.TypeExpression) ast.TypeExpression {
+	switch name {
+	case "ok", "err":
+		var inner ast.TypeExpression = anyType()
+
+		if len(args) == 1 {
+			inner = args[0]
+		}
+
+		if name == "ok" {
+			return &ast.ResultType{Value: inner, Error: anyType()}
+		}
+
+		return &ast.ResultType{Value: anyType(), Error: inner}
+	case "error":
+		return errorType()
+	case "message", "traceback":
+		return optionalOf(&ast.LiteralStringType{})
+	case "print", "typeOf":
+		return voidType()
+	case "freeze":
+		if len(args) == 1 {
+			return args[0]
+		}
+	case "union", "intersection", "difference":
+		if len(args) > 0 && isSetType(t.prune(args[0])) {
+			return args[0]
+		}
+	}
+
+	return anyType()
+}
+
+// set operations take two sets of the same element type
+func (t *TypeChecker) checkBuiltinArguments(name string, args []ast.TypeExpression) error {
+	switch name {
+	case "union", "intersection", "difference":
+		if len(args) != 2 {
+			return fmt.Errorf("`%s` requires 2 arguments, received %d", name, len(args))
+		}
+
+		for _, arg := range args {
+			if arg = t.prune(arg); !isSetType(arg) && !isAnyType(arg) && !isTypeVariable(arg) {
+				return fmt.Errorf("`%s` requires sets, got `%s`", name, t.describe(arg).Type())
+			}
+		}
+
+		if !t.matchTypes(args[0], args[1]) {
+			return fmt.Errorf("cannot %s `%s` and `%s`", name, t.describe(args[0]).Type(), t.describe(args[1]).Type())
+		}
+	}
+
+	return nil
+}
+
+func (t *TypeChecker) visitPrefixExpression(e *ast.PrefixExpression) (ast.TypeExpression, error) {
+	right, err := t.visitExpression(e.Right)
+
+	if err != nil {
+		return nil, err
+	}
+
+	switch e.Operator {
+	case "!":
+		return &ast.LiteralBooleanType{}, nil
+	case "-":
+		if v, ok := right.(*ast.TypeVariable); ok {
+			v.Numeric = true
+		}
+
+		if isNumericType(right) || isAnyType(right) || isTypeVariable(right) {
+			return right, nil
+		}
+	}
+
+	return nil, fmt.Errorf("unknown operator: %s%s", e.Operator, t.describe(right).Type())
+}
+
+func (t *TypeChecker) visitInfixExpression(e *ast.InfixExpression) (ast.TypeExpression, error) {
+	lhs, err := t.visitExpression(e.Left)
+
+	if err != nil {
+		return nil, err
+	}
+
+	rhs, err := t.visitExpression(e.Right)
+
+	if err != nil {
+		return nil, err
+	}
+
+	switch e.Operator {
+	case "??":
+		// the default must fit the wrapped type, the result is no longer optional
+		if optional, ok := lhs.(*ast.OptionalType); ok {
+			if !t.matchTypes(optional.Value, rhs) {
+				return nil, fmt.Errorf("cannot use `%s` as default for `%s`", t.describe(rhs).Type(), t.describe(lhs).Type())
+			}
+
+			return optional.Value, nil
+		}
+
+		return lhs, nil
+	case "==", "!=":
+		return &ast.LiteralBooleanType{}, nil
+	}
+
+	if result, ok, err := t.distinctOperation(e.Operator, lhs, rhs); ok {
+		return result, err
+	}
+
+	// operands still being inferred are constrained to numbers
+	for _, operand := range []ast.TypeExpression{lhs, rhs} {
+		if v, ok := operand.(*ast.TypeVariable); ok {
+			v.Numeric = true
+		}
+	}
+
+	numeric := func(ty ast.TypeExpression) bool {
+		return isNumericType(ty) || isAnyType(ty) || isTypeVariable(ty)
+	}
+
+	if !numeric(lhs) || !numeric(rhs) || !t.matchTypes(lhs, rhs) {
+		return nil, fmt.Errorf("unknown operator: %s %s %s", t.describe(lhs).Type(), e.Operator, t.describe(rhs).Type())
+	}
+
+	switch e.Operator {
+	case "<", ">", "<=", ">=":
+		return &ast.LiteralBooleanType{}, nil
+	case "+", "-", "*", "/":
+		if isAnyType(lhs) {
+			return t.prune(rhs), nil
+		}
+
+		return t.prune(lhs), nil
+	}
+
+	return nil, fmt.Errorf("unknown operator: %s %s %s", t.describe(lhs).Type(), e.Operator, t.describe(rhs).Type())
+}
+
+/*
+Checks both branches of an if expression, each in its own scope.
+Comparing an optional identifier against null narrows it to its wrapped type in the branch where it cannot be null:
+
+	if x != null { x is T }
+	if x == null { } else { x is T }
+
+The branches must agree on a type, unless one has no value and the if is a statement.
+*/
+func (t *TypeChecker) visitIfExpression(e *ast.IfExpression) (ast.TypeExpression, error) {
+	if _, err := t.visitExpression(e.Condition); err != nil {
+		return nil, err
+	}
+
+	name, narrowed, whenEqual := t.nullComparison(e.Condition)
+
+	actionScope, alternativeScope := newTypeScope(t.scope), newTypeScope(t.scope)
+
+	if narrowed != nil {
+		if whenEqual {
+			alternativeScope.define(name, narrowed, false)
+		} else {
+			actionScope.define(name, narrowed, false)
+		}
+	}
+
+	action, err := t.checkBlockStatement(e.Action, actionScope)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// without an alternative the expression evaluates to void when the condition fails
+	var alternative ast.TypeExpression = voidType()
+
+	if e.Alternative != nil {
+		alternative, err = t.checkBlockStatement(e.Alternative, alternativeScope)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	joined := t.join(action, alternative)
+	action, alternative = t.prune(action), t.prune(alternative)
+
+	// branches of different types only widen to `any` when one of them already is
+	if isAnyType(joined) && !isAnyType(action) && !isAnyType(alternative) {
+		// a branch without a value makes the if a statement
+		if isVoidType(action) || isVoidType(alternative) {
+			return voidType(), nil
+		}
+
+		return nil, fmt.Errorf("if branches have different types: `%s` and `%s`", t.describe(action).Type(), t.describe(alternative).Type())
+	}
+
+	return joined, nil
+}
+
+/*
+Matches `x == null`, `x != null` and their mirrored forms where x is an optional binding.
+Returns the identifier, its wrapped type and whether the comparison is `==`.
+*/
+func (t *TypeChecker) nullComparison(condition ast.Expression) (string, ast.TypeExpression, bool) {
+	infix, ok := condition.(*ast.InfixExpression)
+
+	if !ok || (infix.Operator != "==" && infix.Operator != "!=") {
+		return "", nil, false
+	}
+
+	ident, ok := infix.Left.(*ast.IdentifierExpression)
+	other := infix.Right
+
+	if !ok {
+		ident, ok = infix.Right.(*ast.IdentifierExpression)
+		other = infix.Left
+	}
+
+	if _, isNull := other.(*ast.NullLiteral); !ok || !isNull {
+		return "", nil, false
+	}
+
+	b, ok := t.scope.lookup(ident.Value)
+
+	if !ok {
+		return "", nil, false
+	}
+
+	optional, ok := b.Type.(*ast.OptionalType)
+
+	if !ok {
+		return "", nil, false
+	}
+
+	return ident.Value, optional.Value, infix.Operator == "=="
+}
+
+func (t *TypeChecker) visitIndexExpression(e *ast.IndexExpression) (ast.TypeExpression, error) {
+	left, err := t.visitExpression(e.Left)
+
+	if err != nil {
+		return nil, err
+	}
+
+	index, err := t.visitExpression(e.Index)
+
+	if err != nil {
+		return nil, err
+	}
+
+	left, optional, err := t.unwrapAccess(left, e.Optional, "index")
+
+	if err != nil {
+		return nil, err
+	}
+
+	var result ast.TypeExpression
+
+	switch {
+	case isAnyType(left) || isTypeVariable(left):
+		result = anyType()
+	case isArrayType(left):
+		if !t.matchTypes(&ast.LiteralIntegerType{}, index) {
+			return nil, fmt.Errorf("cannot index `%s` with `%s`", t.describe(left).Type(), t.describe(index).Type())
+		}
+
+		result = left.(*ast.ArrayType).Element
+	case isMapType(left):
+		m := left.(*ast.MapType)
+
+		if !t.matchTypes(m.Key, index) {
+			return nil, fmt.Errorf("cannot index `%s` with `%s`", t.describe(left).Type(), t.describe(index).Type())
+		}
+
+		// missing keys evaluate to null
+		result = optionalOf(m.Value)
+	case isTupleType(left):
+		elem, err := t.tupleElement(left.(*ast.TupleType), e.Index)
+
+		if err != nil {
+			return nil, err
+		}
+
+		result = elem
+	default:
+		return nil, fmt.Errorf("index operator not supported: %s", t.describe(left).Type())
+	}
+
+	if optional {
+		return optionalOf(result), nil
+	}
+
+	return result, nil
+}
+
+func (t *TypeChecker) visitMemberExpression(e *ast.MemberExpression) (ast.TypeExpression, error) {
+	object, err := t.visitExpression(e.Object)
+
+	if err != nil {
+		return nil, err
+	}
+
+	object, optional, err := t.unwrapAccess(object, e.Optional, "member")
+
+	if err != nil {
+		return nil, err
+	}
+
+	var result ast.TypeExpression
+
+	switch {
+	case isAnyType(object) || isTypeVariable(object):
+		result = anyType()
+	case isModuleType(object):
+		m := object.(*ast.ModuleType)
+		b, ok := t.modules[m.Path][e.Property.Value]
+
+		if !ok {
+			return nil, fmt.Errorf("module `%s` does not export `%s`", m.Name, e.Property.Value)
+		}
+
+		result = t.instantiate(b)
+	case isMapType(object):
+		m := object.(*ast.MapType)
+
+		if !t.matchTypes(m.Key, &ast.LiteralStringType{}) {
+			return nil, fmt.Errorf("`%s` has no member `%s`", t.describe(object).Type(), e.Property.Value)
+		}
+
+		result = optionalOf(m.Value)
+	default:
+		instance, ok := object.(*ast.ScopeDefinedType)
+
+		if !ok {
+			return nil, fmt.Errorf("member access not supported: %s", t.describe(object).Type())
+		}
+
+		field, isStruct, err := t.fieldType(instance, e.Property.Value)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if !isStruct {
+			return nil, fmt.Errorf("member access not supported: %s", t.describe(object).Type())
+		}
+
+		result = field
+	}
+
+	if optional {
+		return optionalOf(result), nil
+	}
+
+	return result, nil
+}
+
+// optionals may only be accessed with `?.` or `?[`, which unwrap them and make the result optional
+func (t *TypeChecker) unwrapAccess(target ast.TypeExpression, optionalAccess bool, kind string) (ast.TypeExpression, bool, error) {
+	switch target := target.(type) {
+	case *ast.OptionalType:
+		if !optionalAccess {
+			return nil, false, fmt.Errorf("cannot use %s access on `%s`, use the optional form", kind, t.describe(target).Type())
+		}
+
+		return target.Value, true, nil
+	case *ast.LiteralNullType:
+		if !optionalAccess {
+			return nil, false, fmt.Errorf("cannot use %s access on null", kind)
+		}
+
+		return anyType(), true, nil
+	}
+
+	return target, false, nil
+}
+
+func (t *TypeChecker) visitAssignmentExpression(e *ast.AssignmentExpression) (ast.TypeExpression, error) {
+	b, ok := t.scope.lookup(e.Target.Value)
+
+	if !ok {
+		return nil, fmt.Errorf("undefined variable %s", e.Target.Value)
+	}
+
+	if b.Constant {
+		return nil, fmt.Errorf("cannot reassign constant '%s'", e.Target.Value)
+	}
+
+	value, err := t.visitExpression(e.Value)
+
+	if err != nil {
+		return nil, err
+	}
+
+	ok, err = t.matchValue(b.Type, value, e.Value)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !ok {
+		return nil, fmt.Errorf("cannot assign `%s` to variable declared as a `%s`", t.describe(value).Type(), t.describe(b.Type).Type())
+	}
+
+	return voidType(), nil
+}
+
+func (t *TypeChecker) visitPropagateExpression(e *ast.PropagateExpression) (ast.TypeExpression, error) {
+	valueType, err := t.visitExpression(e.Value)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if isAnyType(valueType) {
+		return anyType(), nil
+	}
+
+	result, ok := valueType.(*ast.ResultType)
+
+	if !ok {
+		return nil, fmt.Errorf("`?` requires a result, got `%s`", t.describe(valueType).Type())
+	}
+
+	return result.Value, nil
+}
+
+func isArrayType(t ast.TypeExpression) bool {
+	_, ok := t.(*ast.ArrayType)
+	return ok
+}
+
+func isMapType(t ast.TypeExpression) bool {
+	_, ok := t.(*ast.MapType)
+	return ok
+}
+
+func isSetType(t ast.TypeExpression) bool {
+	_, ok := t.(*ast.SetType)
+	return ok
+}
@@ -0,0 +1,160 @@
+package typing
+
+import (
+	"fmt"
+
+	"github.com/mantton/anthe/internal/ast"
+)
+
+/*
+Constants and the results of `freeze` are deeply immutable. Writes the checker can trace back to
+such a binding are rejected here, any other write to a frozen value is refused by its container
+at runtime.
+*/
+
+// whether value is known to evaluate to a frozen value
+func (t *TypeChecker) isFrozenExpression(value ast.Expression) bool {
+	switch value := value.(type) {
+	case *ast.CallExpression:
+		fn, ok := value.Function.(*ast.IdentifierExpression)
+
+		if !ok || fn.Value != "freeze" {
+			return false
+		}
+
+		// unless shadowed by a declaration
+		_, declared := t.scope.lookup(fn.Value)
+		return !declared
+	case *ast.IdentifierExpression:
+		b, ok := t.scope.lookup(value.Value)
+		return ok && b.Frozen
+	case *ast.IndexExpression:
+		return t.isFrozenExpression(value.Left)
+	case *ast.MemberExpression:
+		return t.isFrozenExpression(value.Object)
+	}
+
+	return false
+}
+
+// e.g arr[0] = 1, p.name = "a"
+func (t *TypeChecker) visitElementAssignmentExpression(e *ast.ElementAssignmentExpression) (ast.TypeExpression, error) {
+	var element ast.TypeExpression
+	var err error
+
+	switch target := e.Target.(type) {
+	case *ast.IndexExpression:
+		if t.isFrozenExpression(target.Left) {
+			return nil, fmt.Errorf("cannot modify an element of a frozen value")
+		}
+
+		element, err = t.indexElementType(target)
+	case *ast.MemberExpression:
+		if t.isFrozenExpression(target.Object) {
+			return nil, fmt.Errorf("cannot modify member `%s` of a frozen value", target.Property.Value)
+		}
+
+		element, err = t.memberElementType(target)
+	default:
+		return nil, fmt.Errorf("cannot assign to %T", target)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := t.visitExpression(e.Value)
+
+	if err != nil {
+		return nil, err
+	}
+
+	ok, err := t.matchValue(element, value, e.Value)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !ok {
+		return nil, fmt.Errorf("cannot assign `%s` to an element of type `%s`", t.describe(value).Type(), t.describe(element).Type())
+	}
+
+	return voidType(), nil
+}
+
+// the type an index assignment stores, the value type of maps rather than the optional a read gives
+func (t *TypeChecker) indexElementType(target *ast.IndexExpression) (ast.TypeExpression, error) {
+	left, err := t.visitExpression(target.Left)
+
+	if err != nil {
+		return nil, err
+	}
+
+	index, err := t.visitExpression(target.Index)
+
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case isAnyType(left) || isTypeVariable(left):
+		return anyType(), nil
+	case isArrayType(left):
+		if !t.matchTypes(&ast.LiteralIntegerType{}, index) {
+			return nil, fmt.Errorf("cannot index `%s` with `%s`", t.describe(left).Type(), t.describe(index).Type())
+		}
+
+		return left.(*ast.ArrayType).Element, nil
+	case isMapType(left):
+		m := left.(*ast.MapType)
+
+		if !t.matchTypes(m.Key, index) {
+			return nil, fmt.Errorf("cannot index `%s` with `%s`", t.describe(left).Type(), t.describe(index).Type())
+		}
+
+		return m.Value, nil
+	case isTupleType(left):
+		return nil, fmt.Errorf("cannot modify an element of `%s`, tuples are immutable", t.describe(left).Type())
+	}
+
+	return nil, fmt.Errorf("index assignment not supported: %s", t.describe(left).Type())
+}
+
+func (t *TypeChecker) memberElementType(target *ast.MemberExpression) (ast.TypeExpression, error) {
+	object, err := t.visitExpression(target.Object)
+
+	if err != nil {
+		return nil, err
+	}
+
+	name := target.Property.Value
+
+	switch {
+	case isAnyType(object) || isTypeVariable(object):
+		return anyType(), nil
+	case isModuleType(object):
+		return nil, fmt.Errorf("cannot modify export `%s` of module `%s`", name, object.(*ast.ModuleType).Name)
+	case isMapType(object):
+		m := object.(*ast.MapType)
+
+		if !t.matchTypes(m.Key, &ast.LiteralStringType{}) {
+			return nil, fmt.Errorf("`%s` has no member `%s`", t.describe(object).Type(), name)
+		}
+
+		return m.Value, nil
+	}
+
+	if instance, ok := object.(*ast.ScopeDefinedType); ok {
+		field, isStruct, err := t.fieldType(instance, name)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if isStruct {
+			return field, nil
+		}
+	}
+
+	return nil, fmt.Errorf("member assignment not supported: %s", t.describe(object).Type())
+}
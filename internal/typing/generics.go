@@ -0,0 +1,293 @@
+package typing
+
+import (
+	"fmt"
+
+	"github.com/mantton/anthe/internal/ast"
+)
+
+/*
+Generic functions and structs.
+Type parameters are type variables that must stay abstract while the declaration is checked, a body
+that only works for some `T` is rejected. Once checked they are quantified like any inferred variable,
+so each use instantiates them afresh.
+*/
+
+// a fresh variable for each type parameter of a declaration
+func (t *TypeChecker) typeParameters(names []string) (map[string]ast.TypeExpression, error) {
+	params := make(map[string]ast.TypeExpression, len(names))
+
+	for _, name := range names {
+		if _, ok := params[name]; ok {
+			return nil, fmt.Errorf("duplicate type parameter `%s`", name)
+		}
+
+		params[name] = t.fresh()
+	}
+
+	return params, nil
+}
+
+// reports type parameters the declaration has narrowed to a concrete type or to each other
+func (t *TypeChecker) checkTypeParameters(names []string, params map[string]ast.TypeExpression) error {
+	seen := map[int]string{}
+
+	for _, name := range names {
+		v, ok := t.prune(params[name]).(*ast.TypeVariable)
+
+		if !ok {
+			return fmt.Errorf("type parameter `%s` cannot be used as `%s`", name, t.describe(params[name]).Type())
+		}
+
+		if v.Numeric {
+			return fmt.Errorf("type parameter `%s` cannot be used as a number", name)
+		}
+
+		if other, ok := seen[v.ID]; ok {
+			return fmt.Errorf("type parameters `%s` and `%s` cannot be used as the same type", other, name)
+		}
+
+		seen[v.ID] = name
+	}
+
+	return nil
+}
+
+// reports whether ty is a type parameter of the generic functions being checked, which only matches itself
+func (t *TypeChecker) isTypeParameter(ty ast.TypeExpression) bool {
+	v, ok := ty.(*ast.TypeVariable)
+
+	if !ok {
+		return false
+	}
+
+	for _, param := range t.typeParams {
+		if p, ok := t.prune(param).(*ast.TypeVariable); ok && p.ID == v.ID {
+			return true
+		}
+	}
+
+	return false
+}
+
+// replaces type parameter names in an annotation and validates the arity of generic types
+func (t *TypeChecker) resolveAnnotation(ann ast.TypeExpression, params map[string]ast.TypeExpression) (ast.TypeExpression, error) {
+	switch ann := ann.(type) {
+	case *ast.OptionalType:
+		value, err := t.resolveAnnotation(ann.Value, params)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return &ast.OptionalType{Value: value}, nil
+	case *ast.ResultType:
+		value, err := t.resolveAnnotation(ann.Value, params)
+
+		if err != nil {
+			return nil, err
+		}
+
+		e, err := t.resolveAnnotation(ann.Error, params)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return &ast.ResultType{Value: value, Error: e}, nil
+	case *ast.ArrayType:
+		elem, err := t.resolveAnnotation(ann.Element, params)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return arrayOf(elem), nil
+	case *ast.SetType:
+		elem, err := t.resolveAnnotation(ann.Element, params)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return setOf(elem), nil
+	case *ast.MapType:
+		key, err := t.resolveAnnotation(ann.Key, params)
+
+		if err != nil {
+			return nil, err
+		}
+
+		value, err := t.resolveAnnotation(ann.Value, params)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return mapOf(key, value), nil
+	case *ast.TupleType:
+		tuple := &ast.TupleType{}
+
+		for _, el := range ann.Elements {
+			elem, err := t.resolveAnnotation(el, params)
+
+			if err != nil {
+				return nil, err
+			}
+
+			tuple.Elements = append(tuple.Elements, elem)
+		}
+
+		return tuple, nil
+	case *ast.FunctionType:
+		fn := &ast.FunctionType{}
+
+		for _, p := range ann.Parameters {
+			param, err := t.resolveAnnotation(p, params)
+
+			if err != nil {
+				return nil, err
+			}
+
+			fn.Parameters = append(fn.Parameters, param)
+		}
+
+		ret, err := t.resolveAnnotation(ann.Return, params)
+
+		if err != nil {
+			return nil, err
+		}
+
+		fn.Return = ret
+		return fn, nil
+	case *ast.ScopeDefinedType:
+		if p, ok := params[ann.Name]; ok && ann.Values == nil {
+			return p, nil
+		}
+
+		// builtin names like any and void are declared by no module and keep an empty key
+		key, _ := t.declaredType(ann.Name)
+
+		if alias, ok := t.aliases[key]; ok && ann.Values == nil {
+			return alias, nil
+		}
+
+		if want, ok := t.typeArity(key); ok && want != len(ann.Values) {
+			return nil, fmt.Errorf("`%s` requires %d type arguments, received %d", ann.Name, want, len(ann.Values))
+		}
+
+		if ann.Values == nil {
+			return &ast.ScopeDefinedType{Name: ann.Name, Module: key.module}, nil
+		}
+
+		values := []ast.TypeExpression{}
+		for _, v := range ann.Values {
+			value, err := t.resolveAnnotation(v, params)
+
+			if err != nil {
+				return nil, err
+			}
+
+			values = append(values, value)
+		}
+
+		return &ast.ScopeDefinedType{Name: ann.Name, Module: key.module, Values: values}, nil
+	}
+
+	return ann, nil
+}
+
+// number of type arguments a named generic type takes
+func (t *TypeChecker) typeArity(key typeKey) (int, bool) {
+	if decl, ok := t.structs[key]; ok {
+		return len(decl.TypeParameters), true
+	}
+
+	return 0, false
+}
+
+/*
+Structs bind a constructor taking their fields in order.
+The constructor of `struct Box<T> { value: T }` has the type `(T) -> Box<T>`.
+*/
+func (t *TypeChecker) checkStructDeclaration(s *ast.StructDeclaration) error {
+	if t.isDeclaredType(s.Name) {
+		return fmt.Errorf("type `%s` is already defined", s.Name)
+	}
+
+	params, err := t.typeParameters(s.TypeParameters)
+
+	if err != nil {
+		return err
+	}
+
+	// registered first so fields may refer to the struct itself
+	key := typeKey{t.module, s.Name}
+	t.named[s.Name] = t.module
+	t.structs[key] = s
+
+	instance := &ast.ScopeDefinedType{Name: s.Name, Module: t.module}
+	for _, name := range s.TypeParameters {
+		instance.Values = append(instance.Values, params[name])
+	}
+
+	ctor := &ast.FunctionType{Return: instance}
+	seen := map[string]bool{}
+
+	for _, field := range s.Fields {
+		if seen[field.Name] {
+			t.undeclare(key)
+			return fmt.Errorf("duplicate field `%s` in struct `%s`", field.Name, s.Name)
+		}
+		seen[field.Name] = true
+
+		fieldType, err := t.resolveAnnotation(field.Type, params)
+
+		if err != nil {
+			t.undeclare(key)
+			return err
+		}
+
+		ctor.Parameters = append(ctor.Parameters, fieldType)
+	}
+
+	if err := t.scope.define(s.Name, ctor, true); err != nil {
+		t.undeclare(key)
+		return err
+	}
+
+	b, _ := t.scope.lookup(s.Name)
+	b.Quantified = t.generalize(b)
+	t.types[s] = ctor
+	return nil
+}
+
+func (t *TypeChecker) undeclare(key typeKey) {
+	delete(t.named, key.name)
+	delete(t.structs, key)
+}
+
+// the type of a field of a struct instance, with its type parameters replaced by the instance's arguments
+func (t *TypeChecker) fieldType(instance *ast.ScopeDefinedType, name string) (ast.TypeExpression, bool, error) {
+	decl, ok := t.structs[typeKey{instance.Module, instance.Name}]
+
+	if !ok {
+		return nil, false, nil
+	}
+
+	params := map[string]ast.TypeExpression{}
+	for i, p := range decl.TypeParameters {
+		if i < len(instance.Values) {
+			params[p] = instance.Values[i]
+		}
+	}
+
+	for _, field := range decl.Fields {
+		if field.Name == name {
+			fieldType, err := t.resolveAnnotation(field.Type, params)
+			return fieldType, true, err
+		}
+	}
+
+	return nil, true, fmt.Errorf("`%s` has no member `%s`", t.describe(instance).Type(), name)
+}
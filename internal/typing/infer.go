@@ -0,0 +1,404 @@
+package typing
+
+import (
+	"fmt"
+
+	"github.com/mantton/anthe/internal/ast"
+)
+
+/*
+Hindley–Milner style inference.
+Unannotated parameters and returns start as type variables, constraints between them are solved by
+unification as the program is checked. Functions are generalized when bound, so every use of
+`func id(x) { x }` gets its own copy of `(T) -> T`.
+*/
+
+func (t *TypeChecker) fresh() *ast.TypeVariable {
+	t.nextVar++
+	return &ast.TypeVariable{ID: t.nextVar}
+}
+
+// follows bound type variables until an unbound variable or another type is reached
+func (t *TypeChecker) prune(ty ast.TypeExpression) ast.TypeExpression {
+	for {
+		v, ok := ty.(*ast.TypeVariable)
+		if !ok {
+			return ty
+		}
+
+		bound, ok := t.subst[v.ID]
+		if !ok {
+			return v
+		}
+
+		ty = bound
+	}
+}
+
+// returns ty with every bound variable replaced by its binding
+func (t *TypeChecker) resolve(ty ast.TypeExpression) ast.TypeExpression {
+	return t.substitute(ty, nil)
+}
+
+// resolves ty and replaces the unbound variables found in mapping
+func (t *TypeChecker) substitute(ty ast.TypeExpression, mapping map[int]ast.TypeExpression) ast.TypeExpression {
+	ty = t.prune(ty)
+
+	switch ty := ty.(type) {
+	case *ast.TypeVariable:
+		if m, ok := mapping[ty.ID]; ok {
+			return m
+		}
+		return ty
+	case *ast.OptionalType:
+		return &ast.OptionalType{Value: t.substitute(ty.Value, mapping)}
+	case *ast.ResultType:
+		return &ast.ResultType{Value: t.substitute(ty.Value, mapping), Error: t.substitute(ty.Error, mapping)}
+	case *ast.ArrayType:
+		return &ast.ArrayType{Element: t.substitute(ty.Element, mapping)}
+	case *ast.SetType:
+		return &ast.SetType{Element: t.substitute(ty.Element, mapping)}
+	case *ast.MapType:
+		return &ast.MapType{Key: t.substitute(ty.Key, mapping), Value: t.substitute(ty.Value, mapping)}
+	case *ast.TupleType:
+		elems := []ast.TypeExpression{}
+		for _, el := range ty.Elements {
+			elems = append(elems, t.substitute(el, mapping))
+		}
+		return &ast.TupleType{Elements: elems}
+	case *ast.FunctionType:
+		params := []ast.TypeExpression{}
+		for _, p := range ty.Parameters {
+			params = append(params, t.substitute(p, mapping))
+		}
+		return &ast.FunctionType{Parameters: params, Return: t.substitute(ty.Return, mapping)}
+	case *ast.ScopeDefinedType:
+		if ty.Values == nil {
+			return ty
+		}
+
+		values := []ast.TypeExpression{}
+		for _, v := range ty.Values {
+			values = append(values, t.substitute(v, mapping))
+		}
+		return &ast.ScopeDefinedType{Name: ty.Name, Module: ty.Module, Values: values}
+	}
+
+	return ty
+}
+
+// unbound type variables appearing in ty
+func (t *TypeChecker) freeVariables(ty ast.TypeExpression, found map[int]*ast.TypeVariable) {
+	ty = t.prune(ty)
+
+	switch ty := ty.(type) {
+	case *ast.TypeVariable:
+		found[ty.ID] = ty
+	case *ast.OptionalType:
+		t.freeVariables(ty.Value, found)
+	case *ast.ResultType:
+		t.freeVariables(ty.Value, found)
+		t.freeVariables(ty.Error, found)
+	case *ast.ArrayType:
+		t.freeVariables(ty.Element, found)
+	case *ast.SetType:
+		t.freeVariables(ty.Element, found)
+	case *ast.MapType:
+		t.freeVariables(ty.Key, found)
+		t.freeVariables(ty.Value, found)
+	case *ast.TupleType:
+		for _, el := range ty.Elements {
+			t.freeVariables(el, found)
+		}
+	case *ast.FunctionType:
+		for _, p := range ty.Parameters {
+			t.freeVariables(p, found)
+		}
+		t.freeVariables(ty.Return, found)
+	case *ast.ScopeDefinedType:
+		for _, v := range ty.Values {
+			t.freeVariables(v, found)
+		}
+	}
+}
+
+func (t *TypeChecker) occurs(id int, ty ast.TypeExpression) bool {
+	found := map[int]*ast.TypeVariable{}
+	t.freeVariables(ty, found)
+	_, ok := found[id]
+	return ok
+}
+
+func (t *TypeChecker) bind(v *ast.TypeVariable, ty ast.TypeExpression) error {
+	if other, ok := ty.(*ast.TypeVariable); ok {
+		if other.ID == v.ID {
+			return nil
+		}
+
+		// the numeric constraint carries over to the variable it is bound to
+		other.Numeric = other.Numeric || v.Numeric
+		t.subst[v.ID] = other
+		return nil
+	}
+
+	// distinct types over numbers support arithmetic, so they satisfy the constraint too
+	underlying, distinct := t.distinctType(ty)
+	if v.Numeric && !isNumericType(ty) && !isAnyType(ty) && !(distinct && isNumericType(t.prune(underlying))) {
+		return fmt.Errorf("expected a numeric type, got `%s`", t.describe(ty).Type())
+	}
+
+	if t.occurs(v.ID, ty) {
+		return fmt.Errorf("recursive type `%s`", t.describe(ty).Type())
+	}
+
+	t.subst[v.ID] = ty
+	return nil
+}
+
+// makes a and b the same type, binding type variables as needed
+func (t *TypeChecker) unify(a, b ast.TypeExpression) error {
+	a, b = t.prune(a), t.prune(b)
+
+	if v, ok := a.(*ast.TypeVariable); ok {
+		return t.bind(v, b)
+	}
+
+	if v, ok := b.(*ast.TypeVariable); ok {
+		return t.bind(v, a)
+	}
+
+	if isAnyType(a) || isAnyType(b) {
+		return nil
+	}
+
+	mismatch := fmt.Errorf("type mismatch: `%s` and `%s`", t.describe(a).Type(), t.describe(b).Type())
+
+	switch a := a.(type) {
+	case *ast.FunctionType:
+		b, ok := b.(*ast.FunctionType)
+
+		if !ok || len(a.Parameters) != len(b.Parameters) {
+			return mismatch
+		}
+
+		for i := range a.Parameters {
+			if err := t.unify(a.Parameters[i], b.Parameters[i]); err != nil {
+				return err
+			}
+		}
+
+		return t.unify(a.Return, b.Return)
+	case *ast.OptionalType:
+		b, ok := b.(*ast.OptionalType)
+
+		if !ok {
+			return mismatch
+		}
+
+		return t.unify(a.Value, b.Value)
+	case *ast.ResultType:
+		b, ok := b.(*ast.ResultType)
+
+		if !ok {
+			return mismatch
+		}
+
+		if err := t.unify(a.Value, b.Value); err != nil {
+			return err
+		}
+
+		return t.unify(a.Error, b.Error)
+	case *ast.ArrayType:
+		b, ok := b.(*ast.ArrayType)
+
+		if !ok {
+			return mismatch
+		}
+
+		return t.unify(a.Element, b.Element)
+	case *ast.SetType:
+		b, ok := b.(*ast.SetType)
+
+		if !ok {
+			return mismatch
+		}
+
+		return t.unify(a.Element, b.Element)
+	case *ast.MapType:
+		b, ok := b.(*ast.MapType)
+
+		if !ok {
+			return mismatch
+		}
+
+		if err := t.unify(a.Key, b.Key); err != nil {
+			return err
+		}
+
+		return t.unify(a.Value, b.Value)
+	case *ast.TupleType:
+		b, ok := b.(*ast.TupleType)
+
+		if !ok || len(a.Elements) != len(b.Elements) {
+			return mismatch
+		}
+
+		for i := range a.Elements {
+			if err := t.unify(a.Elements[i], b.Elements[i]); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	case *ast.ScopeDefinedType:
+		b, ok := b.(*ast.ScopeDefinedType)
+
+		if !ok || a.Name != b.Name || a.Module != b.Module || len(a.Values) != len(b.Values) {
+			return mismatch
+		}
+
+		for i := range a.Values {
+			if err := t.unify(a.Values[i], b.Values[i]); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	if a.Type() != b.Type() {
+		return mismatch
+	}
+
+	return nil
+}
+
+// quantifies the variables of self's type that are not free in the other bindings of the enclosing scopes
+func (t *TypeChecker) generalize(self *binding) []int {
+	ty := self.Type
+
+	inType := map[int]*ast.TypeVariable{}
+	t.freeVariables(ty, inType)
+
+	inScope := map[int]*ast.TypeVariable{}
+	for s := t.scope; s != nil; s = s.parent {
+		for _, b := range s.bindings {
+			if b == self || len(b.Quantified) > 0 {
+				continue
+			}
+			t.freeVariables(b.Type, inScope)
+		}
+	}
+
+	quantified := []int{}
+	for id := range inType {
+		if _, ok := inScope[id]; !ok {
+			quantified = append(quantified, id)
+		}
+	}
+
+	return quantified
+}
+
+// a copy of the binding's type with fresh variables for each quantified one
+func (t *TypeChecker) instantiate(b *binding) ast.TypeExpression {
+	if len(b.Quantified) == 0 {
+		return t.prune(b.Type)
+	}
+
+	found := map[int]*ast.TypeVariable{}
+	t.freeVariables(b.Type, found)
+
+	mapping := map[int]ast.TypeExpression{}
+	for _, id := range b.Quantified {
+		v := t.fresh()
+		if original, ok := found[id]; ok {
+			v.Numeric = original.Numeric
+		}
+		mapping[id] = v
+	}
+
+	return t.substitute(b.Type, mapping)
+}
+
+// resolves ty and names its variables in order of appearance, T, U, V... and N, M... for numeric ones
+func (t *TypeChecker) describe(ty ast.TypeExpression) ast.TypeExpression {
+	resolved := t.resolve(ty)
+
+	order := []*ast.TypeVariable{}
+	seen := map[int]bool{}
+	var walk func(ast.TypeExpression)
+	walk = func(ty ast.TypeExpression) {
+		switch ty := ty.(type) {
+		case *ast.TypeVariable:
+			if !seen[ty.ID] {
+				seen[ty.ID] = true
+				order = append(order, ty)
+			}
+		case *ast.OptionalType:
+			walk(ty.Value)
+		case *ast.ResultType:
+			walk(ty.Value)
+			walk(ty.Error)
+		case *ast.ArrayType:
+			walk(ty.Element)
+		case *ast.SetType:
+			walk(ty.Element)
+		case *ast.MapType:
+			walk(ty.Key)
+			walk(ty.Value)
+		case *ast.TupleType:
+			for _, el := range ty.Elements {
+				walk(el)
+			}
+		case *ast.FunctionType:
+			for _, p := range ty.Parameters {
+				walk(p)
+			}
+			walk(ty.Return)
+		case *ast.ScopeDefinedType:
+			for _, v := range ty.Values {
+				walk(v)
+			}
+		}
+	}
+	walk(resolved)
+
+	plain, numeric := 0, 0
+	mapping := map[int]ast.TypeExpression{}
+
+	for _, v := range order {
+		var name string
+
+		if v.Numeric {
+			name = typeVariableName([]string{"N", "M", "P", "Q"}, numeric)
+			numeric++
+		} else {
+			name = typeVariableName([]string{"T", "U", "V", "W"}, plain)
+			plain++
+		}
+
+		mapping[v.ID] = &ast.TypeVariable{ID: v.ID, Numeric: v.Numeric, Name: name}
+	}
+
+	return t.substitute(resolved, mapping)
+}
+
+func typeVariableName(names []string, i int) string {
+	if i < len(names) {
+		return names[i]
+	}
+
+	return fmt.Sprintf("%s%d", names[0], i+1)
+}
+
+// the inferred type of an expression or function declaration that has been checked
+func (t *TypeChecker) TypeOf(node ast.Node) (ast.TypeExpression, bool) {
+	ty, ok := t.types[node]
+
+	if !ok {
+		return nil, false
+	}
+
+	return t.describe(ty), true
+}
@@ -0,0 +1,117 @@
+package typing
+
+import (
+	"fmt"
+
+	"github.com/mantton/anthe/internal/ast"
+	"github.com/mantton/anthe/internal/module"
+)
+
+// checks modules in dependency order, each with its own global scope and its own named types
+func (t *TypeChecker) CheckModules(modules []*module.Module) (bool, []string) {
+	global, named := t.scope, t.named
+	defer func() { t.scope, t.named, t.module, t.exports = global, named, "", nil }()
+
+	for _, m := range modules {
+		t.scope, t.named, t.module = newTypeScope(nil), make(map[string]string), m.Path
+		t.exports = make(map[string]*binding)
+
+		if ok, errs := t.CheckProgram(m.Program); !ok {
+			return false, errs
+		}
+
+		t.modules[m.Path] = t.exports
+	}
+
+	return true, nil
+}
+
+/*
+Imported bindings keep their quantified variables, generic exports stay generic.
+Named imports of types make them visible by name, the types of a module imported whole stay
+usable through its values but cannot be named.
+*/
+func (t *TypeChecker) checkImportStatement(s *ast.ImportStatement) error {
+	exports, ok := t.modules[s.Resolved]
+
+	if !ok {
+		return fmt.Errorf("module `%s` is not loaded", s.Path)
+	}
+
+	if s.Names == nil {
+		return t.scope.define(s.Name, &ast.ModuleType{Name: s.Name, Path: s.Resolved}, true)
+	}
+
+	for _, name := range s.Names {
+		b, ok := exports[name.Value]
+
+		if !ok {
+			return fmt.Errorf("module `%s` does not export `%s`", s.Name, name.Value)
+		}
+
+		if err := t.scope.define(name.Value, b.Type, true); err != nil {
+			return err
+		}
+
+		imported, _ := t.scope.lookup(name.Value)
+		imported.Quantified = b.Quantified
+
+		if err := t.importType(s.Resolved, name.Value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (t *TypeChecker) importType(path, name string) error {
+	key := typeKey{path, name}
+
+	_, isStruct := t.structs[key]
+	_, isAlias := t.aliases[key]
+	_, isDistinct := t.distinct[key]
+
+	if !isStruct && !isAlias && !isDistinct {
+		return nil
+	}
+
+	if t.isDeclaredType(name) {
+		return fmt.Errorf("type `%s` is already defined", name)
+	}
+
+	t.named[name] = path
+	return nil
+}
+
+func (t *TypeChecker) checkExportStatement(s *ast.ExportStatement) (ast.TypeExpression, error) {
+	if t.scope.parent != nil && t.scope.parent.parent != nil {
+		return nil, fmt.Errorf("exports must be at the top level of a module")
+	}
+
+	stmtType, err := t.check(s.Statement)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if t.exports == nil {
+		return stmtType, nil
+	}
+
+	for _, name := range s.Names() {
+		// aliases only bind a type, made visible to importers by importType, the binding is unusable
+		b, ok := t.scope.lookup(name)
+		if !ok {
+			b = &binding{Type: voidType(), Constant: true}
+		}
+
+		t.exports[name] = b
+	}
+
+	return stmtType, nil
+}
+
+func isModuleType(t ast.TypeExpression) bool {
+	_, ok := t.(*ast.ModuleType)
+	return ok
+}
@@ -0,0 +1,48 @@
+package typing
+
+import (
+	"fmt"
+
+	"github.com/mantton/anthe/internal/ast"
+)
+
+type binding struct {
+	Type       ast.TypeExpression
+	Constant   bool
+	Frozen     bool  // holds a deeply immutable value
+	Quantified []int // type variables instantiated afresh on every use
+}
+
+// nested scope of declared types, follows the same redeclaration and shadowing rules as scope.Scope
+type typeScope struct {
+	parent   *typeScope
+	bindings map[string]*binding
+	joins    bool // the declarations of a program, merged into the global parent once checked
+}
+
+func newTypeScope(parent *typeScope) *typeScope {
+	return &typeScope{parent: parent, bindings: make(map[string]*binding)}
+}
+
+func (s *typeScope) define(name string, t ast.TypeExpression, constant bool) error {
+	if _, ok := s.bindings[name]; ok {
+		return fmt.Errorf("`%s` is already defined", name)
+	}
+
+	if _, ok := s.parent.bindings[name]; ok && s.joins {
+		return fmt.Errorf("`%s` is already defined", name)
+	}
+
+	s.bindings[name] = &binding{Type: t, Constant: constant}
+	return nil
+}
+
+func (s *typeScope) lookup(name string) (*binding, bool) {
+	b, ok := s.bindings[name]
+
+	if !ok && s.parent != nil {
+		return s.parent.lookup(name)
+	}
+
+	return b, ok
+}
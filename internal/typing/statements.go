@@ -6,35 +6,222 @@ import (
 	"github.com/mantton/anthe/internal/ast"
 )
 
-func (t *TypeChecker) checkLetStatement(s *ast.LetStatement) error {
+// let and const
+func (t *TypeChecker) checkDeclaration(node ast.Statement, name string, declType ast.TypeExpression, value ast.Expression, constant bool) error {
 
-	// check if already defined
-	if _, ok := t.scope[s.Name.Value]; ok {
-		return fmt.Errorf("`%s` is already defined", s.Name.Value)
-	}
-
-	declType := s.Type                          // declaration type
-	initType, err := t.visitExpression(s.Value) // initialization type
+	initType, err := t.visitExpression(value) // initialization type
 
 	// type error in init type
 	if err != nil {
 		return err
 	}
 
+	if declType != nil {
+		declType, err = t.resolveAnnotation(declType, t.typeParams)
+
+		if err != nil {
+			return err
+		}
+	}
+
 	if declType == nil {
-		// infer type
-		s.Type = initType
-	} else {
+		// infer type, a binding initialised with null may later hold anything
+		declType = initType
 
+		if isNullType(initType) {
+			declType = anyType()
+		}
+	} else if ok, err := t.matchValue(declType, initType, value); err != nil {
+		return err
+	} else if !ok {
 		// declaration exists, type check
-		ok := t.matchTypes(declType, initType)
-		if !ok {
-			return fmt.Errorf("cannot assign `%s` to variable declared as a `%s`", initType.Type(), declType.Type())
+		return fmt.Errorf("cannot assign `%s` to variable declared as a `%s`", t.describe(initType).Type(), t.describe(declType).Type())
+	}
+
+	frozen := constant || t.isFrozenExpression(value)
+	err = t.scope.define(name, declType, constant)
+
+	if err != nil {
+		return err
+	}
+
+	t.types[node] = declType
+
+	b, _ := t.scope.lookup(name)
+	b.Frozen = frozen
+
+	// only function literals are generalized, other values keep a single type
+	if _, ok := value.(*ast.FunctionLiteral); ok {
+		b.Quantified = t.generalize(b)
+	}
+
+	return nil
+}
+
+func (t *TypeChecker) checkExpressionStatement(s *ast.ExpressionStatement) (ast.TypeExpression, error) {
+	return t.visitExpression(s.Expression)
+}
+
+/*
+Results must be handled, either bound, returned or unwrapped with `?`.
+Only the last statement of a block may leave a result as is, it is the value of the block.
+*/
+func (t *TypeChecker) unhandledResult(s ast.Statement, stmtType ast.TypeExpression) error {
+	if _, ok := s.(*ast.ExpressionStatement); !ok {
+		return nil
+	}
+
+	if _, ok := stmtType.(*ast.ResultType); ok {
+		return fmt.Errorf("unhandled `%s`, bind it, return it or unwrap it with `?`", t.describe(stmtType).Type())
+	}
+
+	return nil
+}
+
+// the returned type is collected for the enclosing function, the statement itself never completes
+func (t *TypeChecker) checkReturnStatement(s *ast.ReturnStatement) error {
+	returnType, err := t.visitExpression(s.ReturnValue)
+
+	if err != nil {
+		return err
+	}
+
+	if n := len(t.returns); n > 0 {
+		t.returns[n-1] = append(t.returns[n-1], returnType)
+	}
+
+	return nil
+}
+
+// checks the statements of a block within scope, the block evaluates to its last statement
+func (t *TypeChecker) checkBlockStatement(block *ast.BlockStatement, scope *typeScope) (ast.TypeExpression, error) {
+	previous := t.scope
+	t.scope = scope
+	defer func() { t.scope = previous }()
+
+	var result ast.TypeExpression = voidType()
+	t.declareFunctions(block.Statements)
+
+	for i, s := range block.Statements {
+		stmtType, err := t.check(s)
+
+		if err == nil && i != len(block.Statements)-1 {
+			err = t.unhandledResult(s, stmtType)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		result = stmtType
+	}
+
+	return result, nil
+}
+
+/*
+Defines the named functions of a block before any statement is checked, so they can call each other
+regardless of order. Each starts as a type variable, bound to its signature once the declaration is reached.
+Names that clash are left for the declaration itself to report.
+*/
+func (t *TypeChecker) declareFunctions(statements []ast.Statement) {
+	for _, s := range statements {
+		if export, ok := s.(*ast.ExportStatement); ok {
+			s = export.Statement
 		}
+
+		fn, ok := s.(*ast.NamedFunctionDeclaration)
+
+		if !ok || t.scope.define(fn.Name, t.fresh(), false) != nil {
+			continue
+		}
+
+		t.pending[fn] = t.scope.bindings[fn.Name]
+	}
+}
+
+// the function is defined before its body is checked so it can call itself
+func (t *TypeChecker) checkNamedFunctionDeclaration(s *ast.NamedFunctionDeclaration) error {
+	params, err := t.typeParameters(s.TypeParameters)
+
+	if err != nil {
+		return err
+	}
+
+	// type parameters are visible in the signature and the body, including nested functions
+	outer := t.typeParams
+	t.typeParams = make(map[string]ast.TypeExpression, len(outer)+len(params))
+	for name, v := range outer {
+		t.typeParams[name] = v
+	}
+	for name, v := range params {
+		t.typeParams[name] = v
+	}
+	defer func() { t.typeParams = outer }()
+
+	fnType, err := t.functionSignature(s.Fn)
+
+	if err != nil {
+		return err
+	}
+
+	if b, ok := t.pending[s]; ok {
+		// calls checked ahead of the declaration constrain the signature
+		delete(t.pending, s)
+
+		if err := t.unify(b.Type, fnType); err != nil {
+			return err
+		}
+
+		b.Type = fnType
+	} else if err := t.scope.define(s.Name, fnType, false); err != nil {
+		return err
 	}
 
-	fmt.Printf("\n%T\n", s.Type)
+	// monomorphic within its own body, generalized once the body is checked
+	err = t.checkFunctionBody(s.Fn, fnType)
 
-	t.scope[s.Name.Value] = s.Type
+	if err != nil {
+		return err
+	}
+
+	err = t.checkTypeParameters(s.TypeParameters, params)
+
+	if err != nil {
+		return err
+	}
+
+	b, _ := t.scope.lookup(s.Name)
+	b.Quantified = t.generalize(b)
+	t.types[s] = fnType
 	return nil
 }
+
+func (t *TypeChecker) checkTryStatement(s *ast.TryStatement) (ast.TypeExpression, error) {
+	_, err := t.checkBlockStatement(s.Body, newTypeScope(t.scope))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if s.Catch != nil {
+		catchScope := newTypeScope(t.scope)
+
+		if s.CatchParam != nil {
+			// catch binds whatever was thrown, which need not be an error
+			catchScope.define(s.CatchParam.Value, anyType(), false)
+		}
+
+		if _, err := t.checkBlockStatement(s.Catch, catchScope); err != nil {
+			return nil, err
+		}
+	}
+
+	if s.Finally != nil {
+		if _, err := t.checkBlockStatement(s.Finally, newTypeScope(t.scope)); err != nil {
+			return nil, err
+		}
+	}
+
+	return voidType(), nil
+}
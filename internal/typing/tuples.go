@@ -0,0 +1,174 @@
+package typing
+
+import (
+	"fmt"
+
+	"github.com/mantton/anthe/internal/ast"
+)
+
+func (t *TypeChecker) visitTupleLiteral(e *ast.TupleLiteral) (ast.TypeExpression, error) {
+	tuple := &ast.TupleType{}
+
+	for _, element := range e.Elements {
+		elemType, err := t.visitExpression(element)
+
+		if err != nil {
+			return nil, err
+		}
+
+		tuple.Elements = append(tuple.Elements, elemType)
+	}
+
+	return tuple, nil
+}
+
+// tuples are indexed with integer literals so the element type is known
+func (t *TypeChecker) tupleElement(tuple *ast.TupleType, index ast.Expression) (ast.TypeExpression, error) {
+	lit, ok := index.(*ast.IntegerLiteral)
+
+	if !ok {
+		return nil, fmt.Errorf("tuples must be indexed with an integer literal")
+	}
+
+	if lit.Value < 0 || int(lit.Value) >= len(tuple.Elements) {
+		return nil, fmt.Errorf("index %d out of range for `%s`", lit.Value, t.describe(tuple).Type())
+	}
+
+	return tuple.Elements[lit.Value], nil
+}
+
+/*
+let (a, b) = pair
+let [x, y, ...rest] = xs
+let {name, age} = person
+*/
+func (t *TypeChecker) checkDestructuring(s *ast.DestructuringStatement) error {
+	value, err := t.visitExpression(s.Value)
+
+	if err != nil {
+		return err
+	}
+
+	types := []ast.TypeExpression{}
+
+	switch s.Kind {
+	case ast.TuplePattern:
+		// still being inferred, e.g a parameter, it must be a tuple of this arity
+		if isTypeVariable(value) {
+			tuple := &ast.TupleType{}
+			for range s.Names {
+				tuple.Elements = append(tuple.Elements, t.fresh())
+			}
+
+			if err := t.unify(value, tuple); err != nil {
+				return err
+			}
+
+			value = tuple
+		}
+
+		switch {
+		case isAnyType(value):
+			for range s.Names {
+				types = append(types, anyType())
+			}
+		case isTupleType(value):
+			tuple := value.(*ast.TupleType)
+
+			if len(tuple.Elements) != len(s.Names) {
+				return fmt.Errorf("cannot destructure `%s` into %d names", t.describe(tuple).Type(), len(s.Names))
+			}
+
+			types = tuple.Elements
+		default:
+			return fmt.Errorf("cannot destructure `%s` as a tuple", t.describe(value).Type())
+		}
+	case ast.ArrayPattern:
+		if isTypeVariable(value) {
+			if err := t.unify(value, arrayOf(t.fresh())); err != nil {
+				return err
+			}
+
+			value = t.prune(value)
+		}
+
+		var elem ast.TypeExpression
+
+		switch {
+		case isAnyType(value):
+			elem = anyType()
+		case isArrayType(value):
+			elem = value.(*ast.ArrayType).Element
+		default:
+			return fmt.Errorf("cannot destructure `%s` as an array", t.describe(value).Type())
+		}
+
+		for range s.Names {
+			types = append(types, elem)
+		}
+	case ast.ObjectPattern:
+		for _, name := range s.Names {
+			var member ast.TypeExpression
+
+			switch {
+			case isAnyType(value) || isTypeVariable(value):
+				member = anyType()
+			case isMapType(value):
+				m := value.(*ast.MapType)
+
+				if !t.matchTypes(m.Key, &ast.LiteralStringType{}) {
+					return fmt.Errorf("`%s` has no member `%s`", t.describe(value).Type(), name.Value)
+				}
+
+				member = optionalOf(m.Value)
+			default:
+				instance, ok := value.(*ast.ScopeDefinedType)
+
+				if !ok {
+					return fmt.Errorf("cannot destructure `%s` as an object", t.describe(value).Type())
+				}
+
+				field, isStruct, err := t.fieldType(instance, name.Value)
+
+				if err != nil {
+					return err
+				}
+
+				if !isStruct {
+					return fmt.Errorf("cannot destructure `%s` as an object", t.describe(value).Type())
+				}
+
+				member = field
+			}
+
+			types = append(types, member)
+		}
+	}
+
+	// the parts of a frozen value are frozen too
+	frozen := s.Constant || t.isFrozenExpression(s.Value)
+
+	for i, name := range s.Names {
+		if err := t.scope.define(name.Value, types[i], s.Constant); err != nil {
+			return err
+		}
+
+		t.scope.bindings[name.Value].Frozen = frozen
+	}
+
+	if s.Rest != nil {
+		if err := t.scope.define(s.Rest.Value, value, s.Constant); err != nil {
+			return err
+		}
+
+		t.scope.bindings[s.Rest.Value].Frozen = frozen
+	}
+
+	t.types[s] = value
+	return nil
+}
+
+func isTupleType(t ast.TypeExpression) bool {
+	_, ok := t.(*ast.TupleType)
+	return ok
+}
@@ -7,40 +7,131 @@ import (
 )
 
 type TypeChecker struct {
-	Statements []ast.Statement
-	scope      map[string]ast.TypeExpression
+	scope *typeScope
+
+	returns [][]ast.TypeExpression // return types collected for each enclosing function, innermost last
+
+	named      map[string]string // type names visible in the module being checked, to the module declaring them
+	structs    map[typeKey]*ast.StructDeclaration
+	aliases    map[typeKey]ast.TypeExpression // alias to the type it stands for
+	distinct   map[typeKey]ast.TypeExpression // distinct type to its underlying type
+	typeParams map[string]ast.TypeExpression  // type parameters of the enclosing generic functions
+
+	module  string                         // resolved path of the module being checked, empty outside modules
+	modules map[string]map[string]*binding // exported bindings of checked modules by resolved path
+	exports map[string]*binding            // exports of the module being checked, nil outside modules
+
+	pending map[*ast.NamedFunctionDeclaration]*binding // functions declared ahead of their block, not yet checked
+
+	nextVar int
+	subst   map[int]ast.TypeExpression // bindings of type variables
+	types   map[ast.Node]ast.TypeExpression
 }
 
-func New(s []ast.Statement) *TypeChecker {
-	return &TypeChecker{Statements: s, scope: make(map[string]ast.TypeExpression)}
+// a declared type, modules may each declare a type of the same name
+type typeKey struct {
+	module string
+	name   string
 }
 
-func (t *TypeChecker) CheckAll() (bool, []string) {
+func New() *TypeChecker {
+	return &TypeChecker{
+		scope:    newTypeScope(nil),
+		named:    make(map[string]string),
+		structs:  make(map[typeKey]*ast.StructDeclaration),
+		aliases:  make(map[typeKey]ast.TypeExpression),
+		distinct: make(map[typeKey]ast.TypeExpression),
+		modules:  make(map[string]map[string]*binding),
+		pending:  make(map[*ast.NamedFunctionDeclaration]*binding),
+		subst:    make(map[int]ast.TypeExpression),
+		types:    make(map[ast.Node]ast.TypeExpression),
+	}
+}
 
+/*
+Checks every statement of the program, returning false and the error messages if any failed.
+Declarations are only kept when the whole program passes, so a rejected REPL line leaves no trace.
+*/
+func (t *TypeChecker) CheckProgram(program *ast.Program) (bool, []string) {
 	errors := []string{}
 
-	for _, statement := range t.Statements {
-		err := t.check(statement)
+	global := t.scope
+	t.scope = newTypeScope(global)
+	t.scope.joins = true
+	t.declareFunctions(program.Statements)
+
+	for i, statement := range program.Statements {
+		stmtType, err := t.check(statement)
+
+		if err == nil && i != len(program.Statements)-1 {
+			err = t.unhandledResult(statement, stmtType)
+		}
 
 		if err != nil {
-			errors = append(errors, err.Error())
+			errors = append(errors, fmt.Sprintf("%s: %s", statement.Pos(), err.Error()))
+		}
+	}
+
+	if len(errors) == 0 {
+		for name, b := range t.scope.bindings {
+			global.bindings[name] = b
 		}
 	}
 
+	t.scope = global
 	return len(errors) == 0, errors
 }
 
-func (t *TypeChecker) check(statement ast.Statement) error {
+// checks a statement, returning the type of the value it evaluates to
+func (t *TypeChecker) check(statement ast.Statement) (ast.TypeExpression, error) {
 
 	switch statement := statement.(type) {
 	case *ast.LetStatement:
-		return t.checkLetStatement(statement)
+		return voidType(), t.checkDeclaration(statement, statement.Name.Value, statement.Type, statement.Value, false)
+	case *ast.ConstStatement:
+		return voidType(), t.checkDeclaration(statement, statement.Name.Value, statement.Type, statement.Value, true)
+	case *ast.ExpressionStatement:
+		return t.checkExpressionStatement(statement)
+	case *ast.ReturnStatement:
+		return neverType(), t.checkReturnStatement(statement)
+	case *ast.BlockStatement:
+		return t.checkBlockStatement(statement, newTypeScope(t.scope))
+	case *ast.NamedFunctionDeclaration:
+		return voidType(), t.checkNamedFunctionDeclaration(statement)
+	case *ast.StructDeclaration:
+		return voidType(), t.checkStructDeclaration(statement)
+	case *ast.TypeDeclaration:
+		return voidType(), t.checkTypeDeclaration(statement)
+	case *ast.DestructuringStatement:
+		return voidType(), t.checkDestructuring(statement)
+	case *ast.ImportStatement:
+		return voidType(), t.checkImportStatement(statement)
+	case *ast.ExportStatement:
+		return t.checkExportStatement(statement)
+	case *ast.ThrowStatement:
+		_, err := t.visitExpression(statement.Value)
+		return neverType(), err
+	case *ast.TryStatement:
+		return t.checkTryStatement(statement)
 	}
 
-	return nil
+	return nil, fmt.Errorf("unknown statement %s", statement.TokenLiteral())
 }
 
+// infers the type of an expression, recording it for TypeOf
 func (t *TypeChecker) visitExpression(expression ast.Expression) (ast.TypeExpression, error) {
+	exprType, err := t.inferExpression(expression)
+
+	if err != nil {
+		return nil, err
+	}
+
+	exprType = t.prune(exprType)
+	t.types[expression] = exprType
+	return exprType, nil
+}
+
+func (t *TypeChecker) inferExpression(expression ast.Expression) (ast.TypeExpression, error) {
 
 	switch expression := expression.(type) {
 
@@ -52,13 +143,233 @@ func (t *TypeChecker) visitExpression(expression ast.Expression) (ast.TypeExpres
 		return &ast.LiteralBooleanType{}, nil
 	case *ast.StringLiteral:
 		return &ast.LiteralStringType{}, nil
-
-	default:
-		return nil, fmt.Errorf("unable to infer type from expression %s", expression.TokenLiteral())
+	case *ast.NullLiteral:
+		return &ast.LiteralNullType{}, nil
+	case *ast.ArrayLiteral:
+		return t.visitArrayLiteral(expression)
+	case *ast.HashLiteral:
+		return t.visitHashLiteral(expression)
+	case *ast.FunctionLiteral:
+		return t.visitFunctionLiteral(expression)
+	case *ast.IdentifierExpression:
+		return t.visitIdentifierExpression(expression)
+	case *ast.PrefixExpression:
+		return t.visitPrefixExpression(expression)
+	case *ast.InfixExpression:
+		return t.visitInfixExpression(expression)
+	case *ast.IfExpression:
+		return t.visitIfExpression(expression)
+	case *ast.CallExpression:
+		return t.visitCallExpression(expression)
+	case *ast.IndexExpression:
+		return t.visitIndexExpression(expression)
+	case *ast.MemberExpression:
+		return t.visitMemberExpression(expression)
+	case *ast.AssignmentExpression:
+		return t.visitAssignmentExpression(expression)
+	case *ast.ElementAssignmentExpression:
+		return t.visitElementAssignmentExpression(expression)
+	case *ast.PropagateExpression:
+		return t.visitPropagateExpression(expression)
+	case *ast.SetLiteral:
+		return t.visitSetLiteral(expression)
+	case *ast.TupleLiteral:
+		return t.visitTupleLiteral(expression)
 	}
+
+	return nil, fmt.Errorf("unable to infer type from expression %s", expression.TokenLiteral())
 }
 
+// reports whether a value of type rhs can be stored where lhs is expected, `any` matches everything
 func (t *TypeChecker) matchTypes(lhs, rhs ast.TypeExpression) bool {
+	lhs, rhs = t.prune(lhs), t.prune(rhs)
+
+	if isAnyType(lhs) || isAnyType(rhs) {
+		return true
+	}
+
+	// still being inferred, the variable takes on the other type, type parameters are rigid and left to the cases below
+	if (isTypeVariable(lhs) && !t.isTypeParameter(lhs)) || (isTypeVariable(rhs) && !t.isTypeParameter(rhs)) {
+		return t.unify(lhs, rhs) == nil
+	}
+
+	switch lhs := lhs.(type) {
+	case *ast.OptionalType:
+		// optionals accept null, their wrapped type and other optionals of it
+		switch rhs := rhs.(type) {
+		case *ast.LiteralNullType:
+			return true
+		case *ast.OptionalType:
+			return t.matchTypes(lhs.Value, rhs.Value)
+		}
+		return t.matchTypes(lhs.Value, rhs)
+	case *ast.ResultType:
+		rhs, ok := rhs.(*ast.ResultType)
+		return ok && t.matchTypes(lhs.Value, rhs.Value) && t.matchTypes(lhs.Error, rhs.Error)
+	case *ast.ArrayType:
+		rhs, ok := rhs.(*ast.ArrayType)
+		return ok && t.matchElement(lhs.Element, rhs.Element)
+	case *ast.SetType:
+		rhs, ok := rhs.(*ast.SetType)
+		return ok && t.matchElement(lhs.Element, rhs.Element)
+	case *ast.MapType:
+		rhs, ok := rhs.(*ast.MapType)
+		return ok && t.matchElement(lhs.Key, rhs.Key) && t.matchElement(lhs.Value, rhs.Value)
+	case *ast.TupleType:
+		rhs, ok := rhs.(*ast.TupleType)
+
+		if !ok || len(lhs.Elements) != len(rhs.Elements) {
+			return false
+		}
+
+		for i := range lhs.Elements {
+			if !t.matchTypes(lhs.Elements[i], rhs.Elements[i]) {
+				return false
+			}
+		}
+
+		return true
+	case *ast.FunctionType:
+		rhs, ok := rhs.(*ast.FunctionType)
+
+		if !ok || len(lhs.Parameters) != len(rhs.Parameters) {
+			return false
+		}
+
+		for i := range lhs.Parameters {
+			if !t.matchTypes(rhs.Parameters[i], lhs.Parameters[i]) {
+				return false
+			}
+		}
+
+		return t.matchTypes(lhs.Return, rhs.Return)
+	case *ast.ScopeDefinedType:
+		rhs, ok := rhs.(*ast.ScopeDefinedType)
+
+		if !ok || lhs.Name != rhs.Name || lhs.Module != rhs.Module || len(lhs.Values) != len(rhs.Values) {
+			return false
+		}
+
+		for i := range lhs.Values {
+			if !t.matchTypes(lhs.Values[i], rhs.Values[i]) {
+				return false
+			}
+		}
+
+		return true
+	}
+
 	return lhs.Type() == rhs.Type()
+}
+
+// elements of `any` may hold values of mixed types, which only a container of `any` or one still being inferred accepts
+func (t *TypeChecker) matchElement(lhs, rhs ast.TypeExpression) bool {
+	lhs, rhs = t.prune(lhs), t.prune(rhs)
+
+	if isAnyType(rhs) && !isAnyType(lhs) && !isTypeVariable(lhs) {
+		return false
+	}
+
+	return t.matchTypes(lhs, rhs)
+}
+
+// the narrowest type both a and b can be stored as, a branch that never completes takes the type of the other
+func (t *TypeChecker) join(a, b ast.TypeExpression) ast.TypeExpression {
+	a, b = t.prune(a), t.prune(b)
+
+	switch {
+	case isNeverType(a):
+		return b
+	case isNeverType(b):
+		return a
+	case (isVoidType(a) || isVoidType(b)) && (isTypeVariable(a) || isTypeVariable(b)):
+		// a variable is not inferred from a branch without a value, like the missing else of an if
+		return voidType()
+	}
+
+	if isTypeVariable(a) || isTypeVariable(b) {
+		if t.unify(a, b) == nil {
+			return t.prune(a)
+		}
+
+		return anyType()
+	}
+
+	switch {
+	case a.Type() == b.Type():
+		return a
+	case isNullType(a):
+		return optionalOf(b)
+	case isNullType(b):
+		return optionalOf(a)
+	case t.matchTypes(a, b) && !isAnyType(a) && !isAnyType(b):
+		return a
+	case t.matchTypes(b, a) && !isAnyType(a) && !isAnyType(b):
+		return b
+	}
+
+	return anyType()
+}
+
+func anyType() ast.TypeExpression   { return &ast.ScopeDefinedType{Name: "any"} }
+func voidType() ast.TypeExpression  { return &ast.ScopeDefinedType{Name: "void"} }
+func errorType() ast.TypeExpression { return &ast.ScopeDefinedType{Name: "error"} }
+
+// the type of statements that never complete, return and throw
+func neverType() ast.TypeExpression { return &ast.ScopeDefinedType{Name: "never"} }
+
+func arrayOf(elem ast.TypeExpression) ast.TypeExpression {
+	return &ast.ArrayType{Element: elem}
+}
+
+func mapOf(key, value ast.TypeExpression) ast.TypeExpression {
+	return &ast.MapType{Key: key, Value: value}
+}
+
+func setOf(elem ast.TypeExpression) ast.TypeExpression {
+	return &ast.SetType{Element: elem}
+}
+
+func optionalOf(v ast.TypeExpression) ast.TypeExpression {
+	if _, ok := v.(*ast.OptionalType); ok || isAnyType(v) {
+		return v
+	}
+
+	return &ast.OptionalType{Value: v}
+}
+
+func isAnyType(t ast.TypeExpression) bool {
+	return isNamedType(t, "any")
+}
+
+func isVoidType(t ast.TypeExpression) bool {
+	return isNamedType(t, "void")
+}
+
+func isNeverType(t ast.TypeExpression) bool {
+	return isNamedType(t, "never")
+}
+
+func isTypeVariable(t ast.TypeExpression) bool {
+	_, ok := t.(*ast.TypeVariable)
+	return ok
+}
+
+func isNullType(t ast.TypeExpression) bool {
+	_, ok := t.(*ast.LiteralNullType)
+	return ok
+}
+
+func isNamedType(t ast.TypeExpression, name string) bool {
+	s, ok := t.(*ast.ScopeDefinedType)
+	return ok && s.Name == name && s.Values == nil
+}
+
+func isNumericType(t ast.TypeExpression) bool {
+	switch t.(type) {
+	case *ast.LiteralIntegerType, *ast.LiteralFloatType:
+		return true
+	}
 
+	return false
 }
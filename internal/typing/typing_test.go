@@ -0,0 +1,441 @@
+package typing
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mantton/anthe/internal/lexer"
+	"github.com/mantton/anthe/internal/module"
+	"github.com/mantton/anthe/internal/parser"
+)
+
+func TestCheckProgram(t *testing.T) {
+	tests := []struct {
+		input string
+		ok    bool
+	}{
+		{`let x = 1; let y: int = x + 2;`, true},
+		{`let x = 1; let y: string = x;`, false},
+		{`const x = 1; x = 2;`, false},
+		{`let x = 1; x = "a";`, false},
+		{`y + 1;`, false},
+		{`if true { let a = 1; }; a;`, false},
+		{`func add(a, b) { a + b }; let r: int = add(1, 2);`, true},
+		{`func add(a, b) { a + b }; add(1);`, false},
+		{`let f = func(x) { return "a"; }; let s: string = f(1);`, true},
+		{`let arr = [1, 2]; let v: int = arr[0];`, true},
+		{`let arr = [1, 2]; arr["a"];`, false},
+		{`let m = {"a": 1}; let v: int? = m["a"];`, true},
+		{`let m = {"a": 1}; let v: int = m.a;`, false},
+		{`"a" + 1;`, false},
+		{`-"a";`, false},
+		{`let x: int? = null; let y: int = x;`, false},
+		{`let x: int? = null; if x != null { let y: int = x; }`, true},
+		{`let x: int? = null; if x == null { 1 } else { let y: int = x; }`, true},
+		{`let x: int? = 5; let y: int = x ?? 2;`, true},
+		{`let x: int? = if true { 1 } else { null };`, true},
+		{`func f(c) { if c { 1 } else { return 2; } }; let x: int = f(true);`, true},
+		{`func f(c) { if c { 1 } }; f(true);`, true},
+		{`ok(1); 2;`, false},
+		{`func f() { let x = ok(2)?; ok(x + 1) }; let r = f();`, true},
+		{`let r = ok(1);`, true},
+		{`try { throw "a" } catch (e) { message(e) }`, true},
+		{`let x = 1; const x = 2;`, false},
+		{`let f = 1; func f() { 2 };`, false},
+		{`let x = 1; func f() { let x = "a"; x }; let s: string = f();`, true},
+		{`let x = 1; if true { let x = "a"; let s: string = x; }`, true},
+		{`let a = [1, 2]; a[0] = 3;`, true},
+		{`let a = [1, 2]; a[0] = "a";`, false},
+		{`let m = {"a": 1}; m["b"] = 2; m.c = 3;`, true},
+		{`struct P { x: int }; let p = P(1); p.x = 2;`, true},
+		{`struct P { x: int }; let p = P(1); p.y = 2;`, false},
+		{`let t = (1, 2); t[0] = 3;`, false},
+		{`const a = [1, 2]; a[0] = 3;`, false},
+		{`let m = [[1]]; let inner = m[0]; inner[0] = 2;`, true},
+		{`const m = [[1]]; let inner = m[0]; inner[0] = 2;`, false},
+		{`let a = freeze([1]); a[0] = 2;`, false},
+		{`let (x, y) = freeze(([1], 2)); x[0] = 2;`, false},
+		{`const a = [1]; func f(b) { b[0] = 2 };`, true},
+	}
+
+	for _, tt := range tests {
+		prog := parser.New(lexer.New(tt.input, "test.an")).ParseProgram()
+
+		if len(prog.Errors) > 0 {
+			t.Fatalf("%s: failed to parse: %v", tt.input, prog.Errors)
+		}
+
+		ok, errs := New().CheckProgram(prog)
+
+		if ok != tt.ok {
+			t.Errorf("%s: expected ok=%t, got %t %v", tt.input, tt.ok, ok, errs)
+		}
+	}
+}
+
+// an optional compared against null is its wrapped type only in the branch where it cannot be null
+func TestNullNarrowing(t *testing.T) {
+	tests := []struct {
+		input string
+		ok    bool
+	}{
+		{`let x: int? = null; if x != null { let y: int = x + 1; }`, true},
+		{`let x: int? = null; if null != x { let y: int = x; }`, true},
+		{`func f(x: int?) -> int { if x != null { x } else { 0 } };`, true},
+		{`let x: int? = null; if x != null { 1 } else { let y: int = x; }`, false},
+		{`let x: int? = null; if x != null { 1 }; let y: int = x;`, false},
+		{`let x: int? = null; let z: int? = 1; if x != null { let y: int = z; }`, false},
+		{`let x: int? = null; if x != null { if true { let y: int = x; } }`, true},
+	}
+
+	for _, tt := range tests {
+		prog := parser.New(lexer.New(tt.input, "test.an")).ParseProgram()
+
+		if len(prog.Errors) > 0 {
+			t.Fatalf("%s: failed to parse: %v", tt.input, prog.Errors)
+		}
+
+		ok, errs := New().CheckProgram(prog)
+
+		if ok != tt.ok {
+			t.Errorf("%s: expected ok=%t, got %t %v", tt.input, tt.ok, ok, errs)
+		}
+	}
+}
+
+func TestInference(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`func id(x) { x }`, "(T) -> T"},
+		{`func add(a, b) { a + b }`, "(N, N) -> N"},
+		{`func k(x, y) { x }`, "(T, U) -> T"},
+		{`func apply(f, x) { f(x) }`, "((T) -> U, T) -> U"},
+		{`func inc(x) { x + 1 }`, "(int) -> int"},
+		{`func fib(n) { if n < 2 { return n; }; fib(n - 1) + fib(n - 2) }`, "(int) -> int"},
+		{`let id = func(x) { x };`, "(T) -> T"},
+		{`func loop(n, acc) { if n == 0 { return acc; }; return loop(n - 1, acc + 1); }`, "(int, int) -> int"},
+		{`func f(x) { if true { return x; }; x }; f(1);`, "(T) -> T"},
+		{`func fail(x) { throw "a" }`, "(T) -> U"},
+	}
+
+	for _, tt := range tests {
+		prog := parser.New(lexer.New(tt.input, "test.an")).ParseProgram()
+		checker := New()
+
+		if ok, errs := checker.CheckProgram(prog); !ok {
+			t.Fatalf("%s: %v", tt.input, errs)
+		}
+
+		inferred, ok := checker.TypeOf(prog.Statements[0])
+
+		if !ok || inferred.Type() != tt.expected {
+			t.Errorf("%s: expected %s, got %v", tt.input, tt.expected, inferred)
+		}
+	}
+}
+
+func TestLetPolymorphism(t *testing.T) {
+	tests := []struct {
+		input string
+		ok    bool
+	}{
+		{`func id(x) { x }; let a: int = id(1); let b: string = id("s");`, true},
+		{`let id = func(x) { x }; let a: int = id(1); let b: string = id("s");`, true},
+		{`func add(a, b) { a + b }; add(1, 2); add(1.5, 2.5);`, true},
+		{`func add(a, b) { a + b }; add("a", "b");`, false},
+		{`func add(a, b) { a + b }; add(1, 2.5);`, false},
+		{`func f(x) { x(x) };`, false},
+		{`func even(n) { if n == 0 { true } else { odd(n - 1) } }; func odd(n) { if n == 0 { false } else { even(n - 1) } }; let b: bool = even(4);`, true},
+		{`func even(n) { if n == 0 { true } else { odd(n - 1) } }; func odd(n) { if n == 0 { false } else { even(n - 1) } }; even("a");`, false},
+		{`func f() { 1 }; func f() { 2 };`, false},
+	}
+
+	for _, tt := range tests {
+		prog := parser.New(lexer.New(tt.input, "test.an")).ParseProgram()
+
+		ok, errs := New().CheckProgram(prog)
+
+		if ok != tt.ok {
+			t.Errorf("%s: expected ok=%t, got %t %v", tt.input, tt.ok, ok, errs)
+		}
+	}
+}
+
+func TestInferenceErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`func f(x) { x + 1 }; f("a");`, "cannot use `string` as argument 1 of type `int`"},
+		{`func f(x, y) { x - y; y(1) };`, "expected a numeric type, got `(int) -> T`"},
+		{`let x: int = if true { 1 } else { "a" };`, "if branches have different types: `int` and `string`"},
+		{`let x: int = if true { 1 };`, "cannot assign `void` to variable declared as a `int`"},
+	}
+
+	for _, tt := range tests {
+		prog := parser.New(lexer.New(tt.input, "test.an")).ParseProgram()
+
+		ok, errs := New().CheckProgram(prog)
+
+		if ok || len(errs) != 1 || !strings.Contains(errs[0], tt.expected) {
+			t.Errorf("%s: expected error %q, got %v", tt.input, tt.expected, errs)
+		}
+	}
+}
+
+func TestGenerics(t *testing.T) {
+	tests := []struct {
+		input string
+		ok    bool
+	}{
+		{`func first<T>(xs: array<T>) -> T { return xs[0]; }; let a: int = first([1]); let b: string = first(["a"]);`, true},
+		{`func first<T>(xs: array<T>) -> T { return xs[0]; }; let a: string = first([1]);`, false},
+		{`func bad<T>(x: T) -> T { x + 1 };`, false},
+		{`func bad<T>(x: T) -> int { x };`, false},
+		{`func swap<A, B>(a: A, b: B) -> A { b };`, false},
+		{`func dup<T>(x: T, y: T) -> T { x }; dup(1, "a");`, false},
+		{`func len(x: int) -> int? { if x > 0 { return x; }; null };`, true},
+		{`func first<T>(xs: array<T>) -> T? { xs[0] }; let a: int? = first([1]);`, true},
+		{`func first<T>(xs: array<T>) -> T? { null };`, true},
+		{`func first<T>(xs: array<T>) -> T? { 1 };`, false},
+		{`struct Box<T> { value: T }; let b = Box(1); let v: int = b.value;`, true},
+		{`struct Box<T> { value: T }; let b = Box(1); let v: string = b.value;`, false},
+		{`struct Box<T> { value: T }; let b = Box(1); b.missing;`, false},
+		{`struct Box<T> { value: T }; let b: Box<string> = Box("a");`, true},
+		{`struct Box<T> { value: T }; let b: Box<string> = Box(1);`, false},
+		{`struct Box<T> { value: T }; let b: Box = Box(1);`, false},
+		{`struct Pair<A, B> { left: A, right: B }; func left<A, B>(p: Pair<A, B>) -> A { p.left }; let l: int = left(Pair(1, "a"));`, true},
+		{`struct Node { value: int, next: Node? }; let n = Node(1, null); let v: int = n.value;`, true},
+	}
+
+	for _, tt := range tests {
+		prog := parser.New(lexer.New(tt.input, "test.an")).ParseProgram()
+
+		if len(prog.Errors) > 0 {
+			t.Fatalf("%s: failed to parse: %v", tt.input, prog.Errors)
+		}
+
+		ok, errs := New().CheckProgram(prog)
+
+		if ok != tt.ok {
+			t.Errorf("%s: expected ok=%t, got %t %v", tt.input, tt.ok, ok, errs)
+		}
+	}
+}
+
+func TestContainers(t *testing.T) {
+	tests := []struct {
+		input string
+		ok    bool
+	}{
+		{`let xs: array<int> = [1, 2];`, true},
+		{`let xs: array<int> = [1, "a"];`, false},
+		{`let xs: array<array<int>> = [[1], ["a"]];`, false},
+		{`let xs: array<any> = [1, "a"];`, true},
+		{`let xs = [1, 2]; xs = ["a"];`, false},
+		{`let m: map<string, int> = {"a": 1, "b": true};`, false},
+		{`let m: map<string, int> = {"a": 1}; let v: int? = m["a"];`, true},
+		{`let m: map<string, int> = {"a": 1}; m[1];`, false},
+		{`let s: set<int> = {1, 2};`, true},
+		{`let s: set<int> = {1, "a"};`, false},
+		{`let s = {1, 2}; s[0];`, false},
+		{`let s = union({1}, {2}); let t: set<int> = s;`, true},
+		{`union({1}, {"a"});`, false},
+		{`difference({1}, [1]);`, false},
+		{`let a: array = [1];`, false},
+		{`func sum(xs: array<int>) -> int { xs[0] }; sum([1, "a"]);`, false},
+		{`let xs = [1, "a"]; let ys: array<int> = xs;`, false},
+		{`let xs = [1, "a"]; let ys: array<any> = xs;`, true},
+		{`let m = {"a": 1, "b": "c"}; let n: map<string, int> = m;`, false},
+		{`func sum(xs: array<int>) -> int { xs[0] }; let xs = [1, "a"]; sum(xs);`, false},
+		{`let xs: array<int?> = [1, null];`, true},
+		{`let xs: array<array<int>> = [[1], [2, 3]];`, true},
+		{`let xs: array<int> = []; let ys: array<int> = xs;`, true},
+	}
+
+	for _, tt := range tests {
+		prog := parser.New(lexer.New(tt.input, "test.an")).ParseProgram()
+
+		if len(prog.Errors) > 0 {
+			if tt.ok {
+				t.Errorf("%s: failed to parse: %v", tt.input, prog.Errors)
+			}
+			continue
+		}
+
+		ok, errs := New().CheckProgram(prog)
+
+		if ok != tt.ok {
+			t.Errorf("%s: expected ok=%t, got %t %v", tt.input, tt.ok, ok, errs)
+		}
+	}
+}
+
+func TestFunctionTypes(t *testing.T) {
+	tests := []struct {
+		input string
+		ok    bool
+	}{
+		{`let f: (int) -> int = func(x) { x + 1 };`, true},
+		{`let f: (int) -> string = func(x) { x + 1 };`, false},
+		{`let f: (int, int) -> int = func(x) { x };`, false},
+		{`let log: (string) -> void = func(s) { print(s) };`, true},
+		{`func apply(f: (int) -> int, x: int) -> int { f(x) }; apply(func(x) { x * 2 }, 1);`, true},
+		{`func apply(f: (int) -> int, x: int) -> int { f(x) }; apply(func(x) { "a" }, 1);`, false},
+		{`func apply(f: (int) -> int, x: int) -> int { f("a") };`, false},
+		{`func twice<T>(f: (T) -> T, x: T) -> T { f(f(x)) }; let b: bool = twice(func(b) { !b }, true);`, true},
+		{`func twice<T>(f: (T) -> T, x: T) -> T { f(f(x)) }; twice(func(b) { b + 1 }, true);`, false},
+		{`func adder(n: int) -> (int) -> int { func(x) { x + n } }; let r: int = adder(1)(2);`, true},
+		{`let fs: array<(int) -> bool> = [func(x) { x > 0 }];`, true},
+		{`func inc(x: int) -> int { x + 1 }; let f: (string) -> int = inc;`, false},
+	}
+
+	for _, tt := range tests {
+		prog := parser.New(lexer.New(tt.input, "test.an")).ParseProgram()
+
+		if len(prog.Errors) > 0 {
+			t.Fatalf("%s: failed to parse: %v", tt.input, prog.Errors)
+		}
+
+		ok, errs := New().CheckProgram(prog)
+
+		if ok != tt.ok {
+			t.Errorf("%s: expected ok=%t, got %t %v", tt.input, tt.ok, ok, errs)
+		}
+	}
+}
+
+func TestTypeDeclarations(t *testing.T) {
+	tests := []struct {
+		input string
+		ok    bool
+	}{
+		{`type UserID = int; let id: UserID = 1; let n: int = id;`, true},
+		{`type Name = string; let n: Name = 1;`, false},
+		{`type Grid = array<array<int>>; let g: Grid = [[1]];`, true},
+		{`type Meters distinct float; let m: Meters = Meters(1.5);`, true},
+		{`type Meters distinct float; let m: Meters = 1.5;`, false},
+		{`type Meters distinct float; let f: float = Meters(1.5);`, false},
+		{`type Meters distinct float; let m: Meters = Meters(1.0) + Meters(2.0);`, true},
+		{`type Meters distinct float; Meters(1.0) + 2.0;`, false},
+		{`type Meters distinct float; type Feet distinct float; Meters(1.0) + Feet(1.0);`, false},
+		{`type Meters distinct float; let b: bool = Meters(1.0) < Meters(2.0);`, true},
+		{`type Meters distinct float; func double(m) { m + m }; let d: Meters = double(Meters(1.0));`, true},
+		{`type Label distinct string; Label("a") + Label("b");`, false},
+		{`type Meters distinct float; Meters("a");`, false},
+	}
+
+	for _, tt := range tests {
+		prog := parser.New(lexer.New(tt.input, "test.an")).ParseProgram()
+
+		if len(prog.Errors) > 0 {
+			t.Fatalf("%s: failed to parse: %v", tt.input, prog.Errors)
+		}
+
+		ok, errs := New().CheckProgram(prog)
+
+		if ok != tt.ok {
+			t.Errorf("%s: expected ok=%t, got %t %v", tt.input, tt.ok, ok, errs)
+		}
+	}
+}
+
+func TestTuples(t *testing.T) {
+	tests := []struct {
+		input string
+		ok    bool
+	}{
+		{`let p: (int, string) = (1, "a"); let s: string = p[1];`, true},
+		{`let p: (int, string) = ("a", 1);`, false},
+		{`let p = (1, "a"); p[2];`, false},
+		{`func divmod(a: int, b: int) -> (int, int) { return a / b, a - b; }; let (q, r) = divmod(7, 2); let n: int = q + r;`, true},
+		{`let (a, b) = (1, 2, 3);`, false},
+		{`const (a, b) = (1, 2); a = 3;`, false},
+		{`let [x, y, ...rest] = [1, 2, 3]; let r: array<int> = rest; let n: int = x;`, true},
+		{`let [x] = (1, 2);`, false},
+		{`let {name, age} = {"name": "a"}; let n: string? = name;`, true},
+		{`struct P { name: string, age: int }; let {age} = P("a", 1); let n: int = age;`, true},
+		{`struct P { name: string }; let {age} = P("a");`, false},
+		{`func swap(p) { let (a, b) = p; return (b, a); }; let s: (string, int) = swap((1, "a"));`, true},
+	}
+
+	for _, tt := range tests {
+		prog := parser.New(lexer.New(tt.input, "test.an")).ParseProgram()
+
+		if len(prog.Errors) > 0 {
+			t.Fatalf("%s: failed to parse: %v", tt.input, prog.Errors)
+		}
+
+		ok, errs := New().CheckProgram(prog)
+
+		if ok != tt.ok {
+			t.Errorf("%s: expected ok=%t, got %t %v", tt.input, tt.ok, ok, errs)
+		}
+	}
+}
+
+func TestModules(t *testing.T) {
+	tests := []struct {
+		main string
+		ok   bool
+	}{
+		{`import { area } from "./geometry"; let a: int = area(2, 3);`, true},
+		{`import "./geometry"; let a: int = geometry.area(2, 3);`, true},
+		{`import { area } from "./geometry"; let a: string = area(2, 3);`, false},
+		{`import { mul } from "./geometry";`, false},
+		{`import "./geometry"; geometry.mul(1, 2);`, false},
+		{`import { id } from "./geometry"; let s: string = id("a"); let n: int = id(1);`, true},
+		{`import { Point } from "./geometry"; let p: Point = Point(1, 2); let x: int = p.x;`, true},
+		{`import { origin } from "./geometry"; struct P { name: string }; let p = P("a"); let s: string = p.name;`, true},
+		{`import { origin } from "./geometry"; let x: int = origin().x;`, true},
+		{`import "./geometry"; let x: int = geometry.origin().x;`, true},
+		{`import { Size } from "./geometry"; let s: Size = 1;`, true},
+		{`import { Point } from "./geometry"; struct Point { x: int };`, false},
+	}
+
+	dir := t.TempDir()
+	geometry := `func mul(a, b) { a * b }; export func area(w: int, h: int) -> int { mul(w, h) }; export func id(x) { x }; export struct Point { x: int, y: int }; ` +
+		`struct P { value: int }; export func origin() -> Point { Point(P(0).value, 0) }; export type Size = int`
+
+	if err := os.WriteFile(filepath.Join(dir, "geometry.an"), []byte(geometry), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range tests {
+		if err := os.WriteFile(filepath.Join(dir, "main.an"), []byte(tt.main), 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		modules, err := module.NewResolver(nil).Load(filepath.Join(dir, "main.an"))
+
+		if err != nil {
+			t.Fatalf("%s: %v", tt.main, err)
+		}
+
+		ok, errs := New().CheckModules(modules)
+
+		if ok != tt.ok {
+			t.Errorf("%s: expected ok=%t, got %t %v", tt.main, tt.ok, ok, errs)
+		}
+	}
+}
+
+func TestRedeclarationAcrossPrograms(t *testing.T) {
+	checker := New()
+
+	for _, input := range []string{`let x = 1;`, `func f() { x };`} {
+		if ok, errs := checker.CheckProgram(parser.New(lexer.New(input, "test.an")).ParseProgram()); !ok {
+			t.Fatalf("%s: %v", input, errs)
+		}
+	}
+
+	for _, input := range []string{`const x = 2;`, `let f = 3;`} {
+		if ok, _ := checker.CheckProgram(parser.New(lexer.New(input, "test.an")).ParseProgram()); ok {
+			t.Errorf("%s: expected a redeclaration error", input)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package vm
+
+import (
+	"github.com/mantton/anthe/internal/bytecode"
+	"github.com/mantton/anthe/internal/object"
+	"github.com/mantton/anthe/internal/token"
+)
+
+// the constants and globals of a compiled program, shared by the functions created from it
+type unit struct {
+	constants []object.Object
+	globals   []object.Object
+	names     []string // of the globals, for errors
+}
+
+// slots of a function call or catch clause, parent is the frame the function was created in
+type env struct {
+	slots  []object.Object
+	parent *env
+}
+
+// a function closed over the frame it was created in
+type Closure struct {
+	Fn   *bytecode.Function
+	env  *env
+	unit *unit
+}
+
+func (c *Closure) Type() object.ObjectType { return object.FUNCTION }
+func (c *Closure) Inspect() string         { return "FUNCTION" }
+
+const (
+	inBody = iota
+	inCatch
+	inFinally
+)
+
+const (
+	completed = iota
+	returning
+	raising
+)
+
+// what a finally clause interrupted, resumed once it completes
+type completion struct {
+	kind  int
+	value object.Object
+	err   *object.Error
+}
+
+// an active try statement
+type handler struct {
+	catch, finally int // addresses, bytecode.NoAddress when absent
+	sp             int // stack height when the try statement was entered
+	env            *env
+	state          int
+	pending        completion
+}
+
+type frame struct {
+	cl       *Closure
+	ip       int // next instruction
+	last     int // instruction being executed
+	base     int // stack height before the call
+	env      *env
+	handlers []*handler
+
+	called *Closure       // the function called, when cl replaced it by a tail call
+	site   token.Position // of the tail call to cl
+}
+
+// the source position of the instruction being executed
+func (f *frame) position() token.Position {
+	return f.cl.Fn.PositionAt(f.last)
+}
+
+func (f *frame) read8() int {
+	v := f.cl.Fn.Instructions[f.ip]
+	f.ip++
+	return int(v)
+}
+
+func (f *frame) read16() int {
+	v := bytecode.ReadUint16(f.cl.Fn.Instructions[f.ip:])
+	f.ip += 2
+	return int(v)
+}
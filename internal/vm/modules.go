@@ -0,0 +1,75 @@
+package vm
+
+import (
+	"github.com/mantton/anthe/internal/bytecode"
+	"github.com/mantton/anthe/internal/module"
+	"github.com/mantton/anthe/internal/object"
+)
+
+// runs modules in dependency order, each with its own globals, returning the result of the last
+func (vm *VM) RunModules(modules []*module.Module) (object.Object, error) {
+	compiled, err := bytecode.CompileModules(modules)
+
+	if err != nil {
+		return nil, err
+	}
+
+	result, _, err := vm.runModules(compiled)
+	return result, err
+}
+
+// runs the modules, then the main function of the last one when it declares one
+func (vm *VM) RunMain(modules []*module.Module) (object.Object, error) {
+	compiled, err := bytecode.CompileModules(modules)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return vm.RunCompiled(compiled)
+}
+
+// runs modules compiled ahead of time, then the main function of the last one when it declares one
+func (vm *VM) RunCompiled(modules []*bytecode.Module) (object.Object, error) {
+	result, entry, err := vm.runModules(modules)
+
+	if err != nil || entry == nil {
+		return result, err
+	}
+
+	for i, name := range entry.names {
+		if name != "main" {
+			continue
+		}
+
+		if main, ok := entry.globals[i].(*Closure); ok {
+			return vm.callValue(main, nil)
+		}
+	}
+
+	return result, nil
+}
+
+// returns the result and unit of the last module
+func (vm *VM) runModules(modules []*bytecode.Module) (object.Object, *unit, error) {
+	global := vm.unit
+	defer func() { vm.unit, vm.exports = global, nil }()
+
+	var result object.Object
+
+	for _, m := range modules {
+		vm.unit = &unit{}
+		vm.exports = &object.Module{Name: m.Name, Exports: make(map[string]object.Object)}
+
+		r, err := vm.Run(m.Program)
+
+		if err != nil {
+			return nil, nil, err
+		}
+
+		vm.modules[m.Path] = vm.exports
+		result = r
+	}
+
+	return result, vm.unit, nil
+}
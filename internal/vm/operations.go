@@ -0,0 +1,331 @@
+package vm
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/mantton/anthe/internal/ast"
+	"github.com/mantton/anthe/internal/builtins"
+	"github.com/mantton/anthe/internal/bytecode"
+	"github.com/mantton/anthe/internal/object"
+)
+
+var operators = map[bytecode.Opcode]string{
+	bytecode.OpAdd:          "+",
+	bytecode.OpSub:          "-",
+	bytecode.OpMul:          "*",
+	bytecode.OpDiv:          "/",
+	bytecode.OpEqual:        "==",
+	bytecode.OpNotEqual:     "!=",
+	bytecode.OpLess:         "<",
+	bytecode.OpGreater:      ">",
+	bytecode.OpLessEqual:    "<=",
+	bytecode.OpGreaterEqual: ">=",
+}
+
+func nativeBool(b bool) *object.Boolean {
+	if b {
+		return builtins.TRUE
+	}
+	return builtins.FALSE
+}
+
+func (vm *VM) binary(op bytecode.Opcode, left, right object.Object) (object.Object, error) {
+	operator := operators[op]
+
+	switch {
+	case left.Type() == object.INTEGER && right.Type() == object.INTEGER:
+		return integerBinary(operator, left.(*object.Integer).Value, right.(*object.Integer).Value)
+	case operator == "==" || operator == "!=":
+		equal, err := vm.equals(left, right)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return nativeBool(equal == (operator == "==")), nil
+	// past equality, which keeps to values of the same type, integers mix with floats
+	case isNumeric(left) && isNumeric(right) && (left.Type() == object.FLOAT || right.Type() == object.FLOAT):
+		return floatBinary(operator, floatValue(left), floatValue(right))
+	case left.Type() != right.Type():
+		return nil, fmt.Errorf("type mismatch: %s %s %s", left.Type(), operator, right.Type())
+	}
+
+	return nil, fmt.Errorf("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+}
+
+func integerBinary(operator string, left, right int64) (object.Object, error) {
+	switch operator {
+	case "+":
+		return &object.Integer{Value: left + right}, nil
+	case "-":
+		return &object.Integer{Value: left - right}, nil
+	case "*":
+		return &object.Integer{Value: left * right}, nil
+	case "/":
+		if right == 0 {
+			return nil, errors.New("division by zero")
+		}
+		return &object.Integer{Value: left / right}, nil
+	case "<":
+		return nativeBool(left < right), nil
+	case ">":
+		return nativeBool(left > right), nil
+	case ">=":
+		return nativeBool(left >= right), nil
+	case "<=":
+		return nativeBool(left <= right), nil
+	case "==":
+		return nativeBool(left == right), nil
+	case "!=":
+		return nativeBool(left != right), nil
+	}
+
+	return nil, fmt.Errorf("unknown operator: %s %s %s", object.INTEGER, operator, object.INTEGER)
+}
+
+// arithmetic and comparisons, integers mixed with floats are promoted
+func floatBinary(operator string, left, right float64) (object.Object, error) {
+	switch operator {
+	case "+":
+		return &object.Float{Value: left + right}, nil
+	case "-":
+		return &object.Float{Value: left - right}, nil
+	case "*":
+		return &object.Float{Value: left * right}, nil
+	case "/":
+		return &object.Float{Value: left / right}, nil
+	case "<":
+		return nativeBool(left < right), nil
+	case ">":
+		return nativeBool(left > right), nil
+	case ">=":
+		return nativeBool(left >= right), nil
+	case "<=":
+		return nativeBool(left <= right), nil
+	}
+
+	return nil, fmt.Errorf("unknown operator: %s %s %s", object.FLOAT, operator, object.FLOAT)
+}
+
+func isNumeric(obj object.Object) bool {
+	return obj.Type() == object.INTEGER || obj.Type() == object.FLOAT
+}
+
+func floatValue(obj object.Object) float64 {
+	if i, ok := obj.(*object.Integer); ok {
+		return float64(i.Value)
+	}
+
+	return obj.(*object.Float).Value
+}
+
+func negate(right object.Object) (object.Object, error) {
+	switch right := right.(type) {
+	case *object.Integer:
+		return &object.Integer{Value: -right.Value}, nil
+	case *object.Float:
+		return &object.Float{Value: -right.Value}, nil
+	}
+
+	return nil, errors.New("object most conform to `numeric` protocol")
+}
+
+// a struct's `truthy` member takes precedence over the default table
+func (vm *VM) isTruthy(obj object.Object) (bool, error) {
+	if s, ok := obj.(*object.Structure); ok {
+		if fn, ok := s.Members["truthy"]; ok {
+			result, err := vm.callValue(fn, []object.Object{obj})
+
+			if err != nil {
+				return false, err
+			}
+
+			b, ok := result.(*object.Boolean)
+			if !ok {
+				return false, fmt.Errorf("`truthy` must return a boolean, got %s", result.Type())
+			}
+
+			return b.Value, nil
+		}
+	}
+
+	return object.IsTruthy(obj), nil
+}
+
+// a struct's `equals` member on either side takes precedence over value equality
+func (vm *VM) equals(left, right object.Object) (bool, error) {
+	fn, self, other, ok := object.EqualsMember(left, right)
+
+	if !ok {
+		return object.Equals(left, right), nil
+	}
+
+	result, err := vm.callValue(fn, []object.Object{self, other})
+
+	if err != nil {
+		return false, err
+	}
+
+	b, ok := result.(*object.Boolean)
+	if !ok {
+		return false, fmt.Errorf("`equals` must return a boolean, got %s", result.Type())
+	}
+
+	return b.Value, nil
+}
+
+func index(left, idx object.Object) (object.Object, error) {
+	switch left := left.(type) {
+	case *object.Array:
+		if i, ok := idx.(*object.Integer); ok {
+			return element(left.Elements, i.Value)
+		}
+	case *object.Tuple:
+		if i, ok := idx.(*object.Integer); ok {
+			return element(left.Elements, i.Value)
+		}
+	case *object.Hash:
+		return hashValue(left, idx)
+	}
+
+	return nil, fmt.Errorf("index operator not supported: %s", left.Type())
+}
+
+func element(elements []object.Object, idx int64) (object.Object, error) {
+	if idx < 0 || idx >= int64(len(elements)) {
+		return nil, fmt.Errorf("index out of range")
+	}
+
+	return elements[idx], nil
+}
+
+// missing keys are null
+func hashValue(hash *object.Hash, key object.Object) (object.Object, error) {
+	value, ok, err := hash.Get(key)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !ok {
+		return builtins.NULL, nil
+	}
+
+	return value, nil
+}
+
+func member(obj object.Object, name string) (object.Object, error) {
+	switch obj := obj.(type) {
+	case *object.Null:
+		return nil, fmt.Errorf("cannot access member `%s` of null, use `?.`", name)
+	case *object.Hash:
+		return hashValue(obj, &object.String{Value: name})
+	case *object.Structure:
+		val, ok := obj.Members[name]
+
+		if !ok {
+			return nil, fmt.Errorf("`%s` has no member `%s`", obj.Name, name)
+		}
+
+		return val, nil
+	case *object.Module:
+		val, ok := obj.Exports[name]
+
+		if !ok {
+			return nil, fmt.Errorf("module `%s` does not export `%s`", obj.Name, name)
+		}
+
+		return val, nil
+	}
+
+	return nil, fmt.Errorf("member access not supported: %s", obj.Type())
+}
+
+// refused by frozen containers
+func setIndex(left, idx, value object.Object) error {
+	switch left := left.(type) {
+	case *object.Array:
+		i, ok := idx.(*object.Integer)
+		if !ok {
+			return fmt.Errorf("cannot index array with %s", idx.Type())
+		}
+
+		return left.SetElement(i.Value, value)
+	case *object.Hash:
+		return left.Set(idx, value)
+	}
+
+	return fmt.Errorf("index assignment not supported: %s", left.Type())
+}
+
+func setMember(obj object.Object, name string, value object.Object) error {
+	switch obj := obj.(type) {
+	case *object.Structure:
+		return obj.SetMember(name, value)
+	case *object.Hash:
+		return obj.Set(&object.String{Value: name}, value)
+	}
+
+	return fmt.Errorf("member assignment not supported: %s", obj.Type())
+}
+
+/*
+The parts of a value bound by a tuple or array pattern of count names, followed by the rest.
+Tuples must have exactly as many elements as names, arrays need at least as many.
+*/
+func destructure(kind ast.PatternKind, val object.Object, count int, rest bool) ([]object.Object, error) {
+	switch kind {
+	case ast.TuplePattern:
+		tuple, ok := val.(*object.Tuple)
+
+		if !ok {
+			return nil, fmt.Errorf("cannot destructure %s as a tuple", val.Type())
+		}
+
+		if len(tuple.Elements) != count {
+			return nil, fmt.Errorf("cannot destructure a tuple of %d elements into %d names", len(tuple.Elements), count)
+		}
+
+		return tuple.Elements, nil
+	case ast.ArrayPattern:
+		arr, ok := val.(*object.Array)
+
+		if !ok {
+			return nil, fmt.Errorf("cannot destructure %s as an array", val.Type())
+		}
+
+		if len(arr.Elements) < count || (!rest && len(arr.Elements) != count) {
+			return nil, fmt.Errorf("cannot destructure an array of %d elements into %d names", len(arr.Elements), count)
+		}
+
+		parts := append([]object.Object{}, arr.Elements[:count]...)
+
+		if rest {
+			remaining := append([]object.Object{}, arr.Elements[count:]...)
+			parts = append(parts, &object.Array{Elements: remaining})
+		}
+
+		return parts, nil
+	}
+
+	return nil, fmt.Errorf("unknown pattern %d", kind)
+}
+
+// structs must have the member, missing hash keys are null
+func destructureMember(val object.Object, name string) (object.Object, error) {
+	switch obj := val.(type) {
+	case *object.Structure:
+		m, ok := obj.Members[name]
+
+		if !ok {
+			return nil, fmt.Errorf("`%s` has no member `%s`", obj.Name, name)
+		}
+
+		return m, nil
+	case *object.Hash:
+		return hashValue(obj, &object.String{Value: name})
+	}
+
+	return nil, fmt.Errorf("cannot destructure %s as an object", val.Type())
+}
@@ -0,0 +1,541 @@
+package vm
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/mantton/anthe/internal/ast"
+	"github.com/mantton/anthe/internal/builtins"
+	"github.com/mantton/anthe/internal/bytecode"
+	"github.com/mantton/anthe/internal/object"
+	"github.com/mantton/anthe/internal/resolver"
+	"github.com/mantton/anthe/internal/token"
+)
+
+/*
+Runs programs compiled to bytecode on a value stack, an alternative to the evaluator with the same
+semantics. Each call pushes a frame holding its instruction pointer and the slots of its locals,
+calls return to the loop rather than recursing on the Go stack.
+*/
+type VM struct {
+	stack  []object.Object
+	frames []*frame
+
+	unit     *unit // of the programs run so far, kept across REPL lines
+	compiler *bytecode.Compiler
+	resolver *resolver.Resolver
+
+	modules map[string]*object.Module // run modules by resolved path
+	exports *object.Module            // exports of the module being run, nil outside modules
+
+	maxDepth int // of the function frames on the stack
+	top      int // frames running the top level of programs, which do not count towards the depth
+}
+
+func New() *VM {
+	return &VM{
+		unit:     &unit{},
+		compiler: bytecode.NewCompiler(),
+		resolver: resolver.New(),
+		modules:  make(map[string]*object.Module),
+		maxDepth: object.MAX_CALL_DEPTH,
+	}
+}
+
+// calls nested deeper than max raise a runtime error
+func (vm *VM) SetMaxCallDepth(max int) {
+	vm.maxDepth = max
+}
+
+func (vm *VM) RunProgram(program *ast.Program) (object.Object, error) {
+	if errs := vm.resolver.Resolve(program); len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	code, err := vm.compiler.Compile(program)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return vm.Run(code)
+}
+
+// runs a compiled program, whose globals extend those of the programs run before it
+func (vm *VM) Run(code *bytecode.Program) (object.Object, error) {
+	u := vm.unit
+	u.constants = code.Constants
+	u.names = code.Globals
+
+	for len(u.globals) < len(u.names) {
+		u.globals = append(u.globals, nil)
+	}
+
+	vm.top++
+	result, err := vm.callValue(&Closure{Fn: code.Main, unit: u}, nil)
+	vm.top--
+
+	if err != nil {
+		vm.stack, vm.frames = vm.stack[:0], vm.frames[:0]
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (vm *VM) push(obj object.Object) {
+	vm.stack = append(vm.stack, obj)
+}
+
+func (vm *VM) pop() object.Object {
+	obj := vm.stack[len(vm.stack)-1]
+	vm.stack = vm.stack[:len(vm.stack)-1]
+	return obj
+}
+
+func (vm *VM) peek() object.Object {
+	return vm.stack[len(vm.stack)-1]
+}
+
+// pops the n values on top of the stack, in the order they were pushed
+func (vm *VM) popN(n int) []object.Object {
+	values := append([]object.Object{}, vm.stack[len(vm.stack)-n:]...)
+	vm.stack = vm.stack[:len(vm.stack)-n]
+	return values
+}
+
+// calls fn from Go, running the loop until it returns
+func (vm *VM) callValue(fn object.Object, args []object.Object) (object.Object, error) {
+	stop := len(vm.frames)
+
+	if err := vm.call(fn, args); err != nil {
+		return nil, err
+	}
+
+	// builtins and constructors have pushed their result
+	if len(vm.frames) == stop {
+		return vm.pop(), nil
+	}
+
+	return vm.run(stop)
+}
+
+// pushes a frame for a closure, the result of anything else that can be called
+func (vm *VM) call(fn object.Object, args []object.Object) error {
+	switch fn := fn.(type) {
+	case *Closure:
+		if len(args) != fn.Fn.NumParams {
+			return fmt.Errorf("`%s` requires %d arguments, received %d", fn.Inspect(), fn.Fn.NumParams, len(args))
+		}
+
+		if len(vm.frames)-vm.top >= vm.maxDepth {
+			return object.CallDepthError(vm.maxDepth)
+		}
+
+		e := &env{slots: make([]object.Object, fn.Fn.FrameSize), parent: fn.env}
+		copy(e.slots, args)
+
+		vm.frames = append(vm.frames, &frame{cl: fn, base: len(vm.stack), env: e})
+		return nil
+	case *object.Builtin:
+		vm.push(fn.Fn(args...))
+		return nil
+	case *object.StructDefinition:
+		if len(args) != len(fn.Fields) {
+			return fmt.Errorf("`%s` requires %d fields, received %d", fn.Name, len(fn.Fields), len(args))
+		}
+
+		members := make(map[string]object.Object, len(args))
+		for i, field := range fn.Fields {
+			members[field] = args[i]
+		}
+
+		vm.push(&object.Structure{Name: fn.Name, Members: members})
+		return nil
+	}
+
+	return fmt.Errorf("%s is not a function", fn.Type())
+}
+
+/*
+Returns val from the current frame, running the finally clauses of its try statements first.
+Reports true once the frame returned to is below stop, the result of the run.
+*/
+func (vm *VM) doReturn(val object.Object, stop int) bool {
+	f := vm.frames[len(vm.frames)-1]
+
+	for len(f.handlers) > 0 {
+		h := f.handlers[len(f.handlers)-1]
+
+		if h.state != inFinally && h.finally != bytecode.NoAddress {
+			vm.stack = vm.stack[:h.sp]
+			f.env, f.ip = h.env, h.finally
+			h.state, h.pending = inFinally, completion{kind: returning, value: val}
+			return false
+		}
+
+		f.handlers = f.handlers[:len(f.handlers)-1]
+	}
+
+	vm.frames = vm.frames[:len(vm.frames)-1]
+	vm.stack = vm.stack[:f.base]
+	vm.push(val)
+
+	return len(vm.frames) == stop
+}
+
+/*
+Raises err in the current frame, entering the catch or finally clause of the innermost try statement
+that can handle it, or unwinding the frame into its caller. Reports true, with the error, once the
+error unwinds below stop.
+*/
+func (vm *VM) raise(err error, stop int) (bool, *object.Error) {
+	f := vm.frames[len(vm.frames)-1]
+
+	rErr, ok := err.(*object.Error)
+	if !ok {
+		rErr = object.NewError(f.position(), err.Error())
+	}
+
+	for {
+		for len(f.handlers) > 0 {
+			h := f.handlers[len(f.handlers)-1]
+
+			switch {
+			case h.state == inBody && h.catch != bytecode.NoAddress:
+				vm.stack = vm.stack[:h.sp]
+				vm.push(rErr.Caught())
+				f.env, f.ip, h.state = h.env, h.catch, inCatch
+				return false, nil
+			case h.state != inFinally && h.finally != bytecode.NoAddress:
+				vm.stack = vm.stack[:h.sp]
+				f.env, f.ip = h.env, h.finally
+				h.state, h.pending = inFinally, completion{kind: raising, err: rErr}
+				return false, nil
+			}
+
+			f.handlers = f.handlers[:len(f.handlers)-1]
+		}
+
+		vm.frames = vm.frames[:len(vm.frames)-1]
+		vm.stack = vm.stack[:f.base]
+
+		callSite := token.Position{}
+		if len(vm.frames) > 0 {
+			callSite = vm.frames[len(vm.frames)-1].position()
+		}
+
+		// the functions a chain of tail calls passed through have no frames left
+		if f.called != nil {
+			rErr.Unwind(f.cl.Fn.Name, f.site)
+			rErr.Unwind(f.called.Fn.Name, callSite)
+		} else {
+			rErr.Unwind(f.cl.Fn.Name, callSite)
+		}
+
+		if len(vm.frames) == stop {
+			return true, rErr
+		}
+
+		f = vm.frames[len(vm.frames)-1]
+	}
+}
+
+// executes instructions until the frame at stop returns, with its result, or an error unwinds past it
+func (vm *VM) run(stop int) (object.Object, error) {
+	for {
+		f := vm.frames[len(vm.frames)-1]
+		u := f.cl.unit
+
+		f.last = f.ip
+		op := bytecode.Opcode(f.cl.Fn.Instructions[f.ip])
+		f.ip++
+
+		var err error
+
+		switch op {
+		case bytecode.OpConstant:
+			vm.push(u.constants[f.read16()])
+		case bytecode.OpTrue:
+			vm.push(builtins.TRUE)
+		case bytecode.OpFalse:
+			vm.push(builtins.FALSE)
+		case bytecode.OpNull:
+			vm.push(builtins.NULL)
+		case bytecode.OpVoid:
+			vm.push(builtins.VOID)
+		case bytecode.OpPop:
+			vm.pop()
+		case bytecode.OpDup:
+			vm.push(vm.peek())
+
+		case bytecode.OpAdd, bytecode.OpSub, bytecode.OpMul, bytecode.OpDiv, bytecode.OpEqual, bytecode.OpNotEqual,
+			bytecode.OpLess, bytecode.OpGreater, bytecode.OpLessEqual, bytecode.OpGreaterEqual:
+			right := vm.pop()
+			left := vm.pop()
+
+			var result object.Object
+			if result, err = vm.binary(op, left, right); err == nil {
+				vm.push(result)
+			}
+		case bytecode.OpNot:
+			var truthy bool
+			if truthy, err = vm.isTruthy(vm.pop()); err == nil {
+				vm.push(nativeBool(!truthy))
+			}
+		case bytecode.OpNegate:
+			var result object.Object
+			if result, err = negate(vm.pop()); err == nil {
+				vm.push(result)
+			}
+
+		case bytecode.OpJump:
+			f.ip = f.read16()
+		case bytecode.OpJumpNotTruthy:
+			target := f.read16()
+
+			var truthy bool
+			if truthy, err = vm.isTruthy(vm.pop()); err == nil && !truthy {
+				f.ip = target
+			}
+		case bytecode.OpJumpNull:
+			target := f.read16()
+
+			if vm.peek() == builtins.NULL {
+				f.ip = target
+			}
+		case bytecode.OpCoalesce:
+			target := f.read16()
+
+			if vm.peek() != builtins.NULL {
+				f.ip = target
+			} else {
+				vm.pop()
+			}
+
+		case bytecode.OpGetGlobal:
+			idx := f.read16()
+
+			if val := u.globals[idx]; val != nil {
+				vm.push(val)
+			} else {
+				err = fmt.Errorf("undefined identifier %s", u.names[idx])
+			}
+		case bytecode.OpDefineGlobal:
+			u.globals[f.read16()] = vm.pop()
+		case bytecode.OpSetGlobal:
+			idx := f.read16()
+
+			if u.globals[idx] != nil {
+				u.globals[idx] = vm.pop()
+			} else {
+				err = fmt.Errorf("undefined variable %s", u.names[idx])
+			}
+		case bytecode.OpGetLocal:
+			e := f.env.up(f.read8())
+
+			if val := e.slots[f.read16()]; val != nil {
+				vm.push(val)
+			} else {
+				err = errors.New("variable is used before its declaration")
+			}
+		case bytecode.OpSetLocal:
+			e := f.env.up(f.read8())
+			e.slots[f.read16()] = vm.pop()
+		case bytecode.OpGetBuiltin:
+			name := u.constants[f.read16()].(*object.String).Value
+			vm.push(builtins.BuiltInFunctions[name])
+
+		case bytecode.OpArray:
+			vm.push(&object.Array{Elements: vm.popN(f.read16())})
+		case bytecode.OpTuple:
+			vm.push(&object.Tuple{Elements: vm.popN(f.read16())})
+		case bytecode.OpHash:
+			values := vm.popN(2 * f.read16())
+			hash := object.NewHash()
+
+			for i := 0; i < len(values) && err == nil; i += 2 {
+				err = hash.Set(values[i], values[i+1])
+			}
+
+			vm.push(hash)
+		case bytecode.OpSet:
+			values := vm.popN(f.read16())
+			set := object.NewSet()
+
+			for i := 0; i < len(values) && err == nil; i++ {
+				err = set.Add(values[i])
+			}
+
+			vm.push(set)
+
+		case bytecode.OpIndex:
+			idx := vm.pop()
+
+			var result object.Object
+			if result, err = index(vm.pop(), idx); err == nil {
+				vm.push(result)
+			}
+		case bytecode.OpSetIndex:
+			values := vm.popN(3)
+
+			if err = setIndex(values[1], values[2], values[0]); err == nil {
+				vm.push(builtins.VOID)
+			}
+		case bytecode.OpMember:
+			name := u.constants[f.read16()].(*object.String).Value
+
+			var result object.Object
+			if result, err = member(vm.pop(), name); err == nil {
+				vm.push(result)
+			}
+		case bytecode.OpSetMember:
+			name := u.constants[f.read16()].(*object.String).Value
+			values := vm.popN(2)
+
+			if err = setMember(values[1], name, values[0]); err == nil {
+				vm.push(builtins.VOID)
+			}
+
+		case bytecode.OpCall:
+			args := vm.popN(f.read8())
+			err = vm.call(vm.pop(), args)
+		case bytecode.OpTailCall:
+			args := vm.popN(f.read8())
+			fn := vm.pop()
+
+			// pending finally clauses keep the frame, anything but a closure is called as usual
+			cl, ok := fn.(*Closure)
+			if !ok || len(f.handlers) > 0 || len(args) != cl.Fn.NumParams {
+				err = vm.call(fn, args)
+				break
+			}
+
+			if f.called == nil {
+				f.called = f.cl
+			}
+
+			e := &env{slots: make([]object.Object, cl.Fn.FrameSize), parent: cl.env}
+			copy(e.slots, args)
+
+			f.site = f.position()
+			f.cl, f.ip, f.env = cl, 0, e
+			vm.stack = vm.stack[:f.base]
+		case bytecode.OpReturnValue:
+			if vm.doReturn(vm.pop(), stop) {
+				return vm.pop(), nil
+			}
+		case bytecode.OpReturn:
+			// only ends the top level of a program, which then has no result
+			vm.frames = vm.frames[:len(vm.frames)-1]
+			vm.stack = vm.stack[:f.base]
+			return nil, nil
+		case bytecode.OpClosure:
+			fn := u.constants[f.read16()].(*bytecode.Function)
+			vm.push(&Closure{Fn: fn, env: f.env, unit: u})
+		case bytecode.OpPropagate:
+			val := vm.pop()
+			result, ok := val.(*object.Result)
+
+			switch {
+			case !ok:
+				err = fmt.Errorf("`?` requires a result, got %s", val.Type())
+			case result.Ok:
+				vm.push(result.Value)
+			case vm.doReturn(result, stop):
+				return vm.pop(), nil
+			}
+
+		case bytecode.OpThrow:
+			// errors are raised as is, any other value is carried in an error, the trace restarts at the throw
+			err = object.Thrown(f.position(), vm.pop())
+		case bytecode.OpTry:
+			catch, finally := f.read16(), f.read16()
+			f.handlers = append(f.handlers, &handler{catch: catch, finally: finally, sp: len(vm.stack), env: f.env})
+		case bytecode.OpEndTry:
+			end := f.read16()
+			h := f.handlers[len(f.handlers)-1]
+
+			if h.finally != bytecode.NoAddress {
+				f.ip = h.finally
+				h.state, h.pending = inFinally, completion{kind: completed}
+			} else {
+				f.handlers = f.handlers[:len(f.handlers)-1]
+				f.ip = end
+			}
+		case bytecode.OpEndFinally:
+			h := f.handlers[len(f.handlers)-1]
+			f.handlers = f.handlers[:len(f.handlers)-1]
+
+			switch h.pending.kind {
+			case returning:
+				if vm.doReturn(h.pending.value, stop) {
+					return vm.pop(), nil
+				}
+			case raising:
+				err = h.pending.err
+			}
+		case bytecode.OpEnterFrame:
+			f.env = &env{slots: make([]object.Object, f.read16()), parent: f.env}
+		case bytecode.OpLeaveFrame:
+			f.env = f.env.parent
+
+		case bytecode.OpFreeze:
+			vm.push(object.FrozenCopy(vm.pop()))
+		case bytecode.OpNotNull:
+			msg := u.constants[f.read16()].(*object.String).Value
+
+			if vm.peek() == builtins.NULL {
+				err = errors.New(msg)
+			}
+		case bytecode.OpDestructure:
+			kind, count, rest := f.read8(), f.read8(), f.read8() == 1
+
+			var parts []object.Object
+			if parts, err = destructure(ast.PatternKind(kind), vm.pop(), count, rest); err == nil {
+				vm.stack = append(vm.stack, parts...)
+			}
+		case bytecode.OpDestructureMember:
+			name := u.constants[f.read16()].(*object.String).Value
+
+			var result object.Object
+			if result, err = destructureMember(vm.pop(), name); err == nil {
+				vm.push(result)
+			}
+
+		case bytecode.OpImport:
+			path := u.constants[f.read16()].(*object.String).Value
+
+			if mod, ok := vm.modules[path]; ok {
+				vm.push(mod)
+			} else {
+				err = fmt.Errorf("module `%s` is not loaded", path)
+			}
+		case bytecode.OpExport:
+			name := u.constants[f.read16()].(*object.String).Value
+			val := vm.pop()
+
+			if vm.exports != nil {
+				vm.exports.Exports[name] = val
+			}
+
+		default:
+			err = fmt.Errorf("unknown opcode %d", op)
+		}
+
+		if err != nil {
+			if done, rErr := vm.raise(err, stop); done {
+				return nil, rErr
+			}
+		}
+	}
+}
+
+// the frame depth frames up
+func (e *env) up(depth int) *env {
+	for ; depth > 0; depth-- {
+		e = e.parent
+	}
+
+	return e
+}
@@ -0,0 +1,97 @@
+package vm
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mantton/anthe/internal/ast"
+	"github.com/mantton/anthe/internal/bytecode"
+	"github.com/mantton/anthe/internal/lexer"
+	"github.com/mantton/anthe/internal/module"
+	"github.com/mantton/anthe/internal/object"
+	"github.com/mantton/anthe/internal/parser"
+)
+
+func run(vm *VM, input string) (object.Object, error) {
+	return vm.RunProgram(parser.New(lexer.New(input, "test.an")).ParseProgram())
+}
+
+func TestBlockValues(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`if true { 1 }`, "1"},
+		{`if false { 1 }`, "void"},
+		{`if true { let z = 1; }`, "void"},
+		{`func f() { if true { let z = 1; }; 5 }; f()`, "5"},
+		{`func f() { let z = 1; }; f()`, "void"},
+		{`func f() { }; f()`, "void"},
+	}
+
+	for _, tt := range tests {
+		result, err := run(New(), tt.input)
+
+		if err != nil {
+			t.Errorf("%s: %s", tt.input, err)
+		} else if result.Inspect() != tt.expected {
+			t.Errorf("%s: expected %s, got %s", tt.input, tt.expected, result.Inspect())
+		}
+	}
+}
+
+// tail calls replace the caller's frame, so only calls in other positions count towards the depth
+func TestTailCallFrames(t *testing.T) {
+	vm := New()
+	vm.SetMaxCallDepth(10)
+
+	result, err := run(vm, `func count(n) { if n == 0 { "done" } else { count(n - 1) } }; count(1000)`)
+
+	if err != nil || result.Inspect() != "done" {
+		t.Fatalf("expected done, got %v %v", result, err)
+	}
+
+	_, err = run(vm, `func sum(n) { if n == 0 { 0 } else { n + sum(n - 1) } }; sum(1000)`)
+
+	if err == nil || !strings.Contains(err.Error(), "maximum call depth of 10 exceeded") {
+		t.Errorf("expected the call depth to be exceeded, got %v", err)
+	}
+
+	// the vm is usable after the error unwound its frames
+	if result, err := run(vm, `count(5)`); err != nil || result.Inspect() != "done" {
+		t.Errorf("expected done after an error, got %v %v", result, err)
+	}
+}
+
+func TestRunCompiled(t *testing.T) {
+	lib := parser.New(lexer.New(`export func double(x) { x * 2 }`, "lib.an")).ParseProgram()
+	entry := parser.New(lexer.New(`import { double } from "./lib"; func main() { double(21) }`, "main.an")).ParseProgram()
+	entry.Statements[0].(*ast.ImportStatement).Resolved = "/lib.an"
+
+	compiled, err := bytecode.CompileModules([]*module.Module{
+		{Name: "lib", Path: "/lib.an", Program: lib},
+		{Name: "main", Path: "/main.an", Program: entry},
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := bytecode.Encode(compiled)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decoded, err := bytecode.Decode(data)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := New().RunCompiled(decoded)
+
+	if err != nil || result.Inspect() != "42" {
+		t.Errorf("expected main to return 42, got %v %v", result, err)
+	}
+}